@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// mimeText is the plain text representation. Backends map it onto the
+// native text target (UTF8_STRING on X11, text/plain;charset=utf-8 on Wayland).
+const mimeText = "text/plain"
+
+// ClipboardContent is one representation of the clipboard, e.g. text/plain or image/png
+type ClipboardContent struct {
+	MimeType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// ClipboardBackend is the single point of access to the system clipboard.
+// One implementation is selected at startup; tests install memoryBackend.
+type ClipboardBackend interface {
+	// Name identifies the backend in diagnostics
+	Name() string
+
+	// Targets lists the MIME types offered by the current clipboard owner
+	Targets() ([]string, error)
+
+	// Read returns the clipboard content converted to mimeType
+	Read(mimeType string) ([]byte, error)
+
+	// Write takes ownership of the clipboard and offers the given representations
+	Write(contents []ClipboardContent) error
+
+	// Watch delivers a signal each time the clipboard changes
+	Watch() (*clipboardChangeNotifier, error)
+}
+
+// primarySelectionBackend is implemented by backends that can also reach the
+// PRIMARY (middle-click) selection
+type primarySelectionBackend interface {
+	Primary() (ClipboardBackend, error)
+}
+
+var (
+	clipboardBackend   ClipboardBackend
+	clipboardBackendMu sync.Mutex
+)
+
+// getClipboardBackend returns the active backend, selecting one on first use
+func getClipboardBackend() (ClipboardBackend, error) {
+	clipboardBackendMu.Lock()
+	defer clipboardBackendMu.Unlock()
+
+	if clipboardBackend == nil {
+		b, err := selectClipboardBackend()
+		if err != nil {
+			return nil, err
+		}
+		clipboardBackend = b
+	}
+
+	return clipboardBackend, nil
+}
+
+// setClipboardBackend replaces the active backend
+func setClipboardBackend(b ClipboardBackend) {
+	clipboardBackendMu.Lock()
+	defer clipboardBackendMu.Unlock()
+	clipboardBackend = b
+}
+
+// getPrimaryBackend returns a backend for the PRIMARY selection of the active backend
+func getPrimaryBackend() (ClipboardBackend, error) {
+	b, err := getClipboardBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := b.(primarySelectionBackend)
+	if !ok {
+		return nil, fmt.Errorf("%s backend has no PRIMARY selection", b.Name())
+	}
+	return p.Primary()
+}
+
+// selectClipboardBackend picks a backend for the current session.
+// CLIPBOARD_MANAGER_BACKEND (x11, wayland or memory) overrides detection.
+func selectClipboardBackend() (ClipboardBackend, error) {
+	switch name := os.Getenv("CLIPBOARD_MANAGER_BACKEND"); name {
+	case "":
+	case "x11":
+		return newX11Backend()
+	case "wayland":
+		return newWaylandBackend()
+	case "memory":
+		return newMemoryBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown clipboard backend %q (expected x11, wayland or memory)", name)
+	}
+
+	var reasons []string
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		b, err := newWaylandBackend()
+		if err == nil {
+			return b, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("wayland: %v", err))
+	}
+
+	if os.Getenv("DISPLAY") != "" {
+		b, err := newX11Backend()
+		if err == nil {
+			return b, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("x11: %v", err))
+	}
+
+	if len(reasons) == 0 {
+		return nil, fmt.Errorf("no display server detected")
+	}
+	return nil, fmt.Errorf("no clipboard backend available (%s)", strings.Join(reasons, "; "))
+}
+
+// readClipboardText returns the clipboard as plain text
+func readClipboardText() (string, error) {
+	b, err := getClipboardBackend()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := b.Read(mimeText)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// writeClipboardText replaces the clipboard with plain text
+func writeClipboardText(text string) error {
+	b, err := getClipboardBackend()
+	if err != nil {
+		return err
+	}
+	return b.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte(text)}})
+}
+
+// parseTargets splits the newline-separated target list printed by xclip and wl-paste
+func parseTargets(output string) []string {
+	var targets []string
+	for _, line := range strings.Split(output, "\n") {
+		if target := strings.TrimSpace(line); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// runClipboardTool runs a clipboard utility and returns its output.
+// Tool errors include stderr, which carries messages like "No selection".
+func runClipboardTool(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %s", name, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %v", name, err)
+	}
+	return output, nil
+}
+
+// memoryBackend is an in-process clipboard used by tests and headless runs
+type memoryBackend struct {
+	mu       sync.Mutex
+	contents []ClipboardContent
+	watchers []*clipboardChangeNotifier
+	primary  *memoryBackend
+	owner    SourceApp // Application reported as owning the selection
+}
+
+// newMemoryBackend creates an empty in-memory clipboard
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{}
+}
+
+func (m *memoryBackend) Name() string {
+	return "memory"
+}
+
+// Primary returns the in-memory PRIMARY selection, creating it on first use
+func (m *memoryBackend) Primary() (ClipboardBackend, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.primary == nil {
+		m.primary = newMemoryBackend()
+	}
+	return m.primary, nil
+}
+
+// Owner returns the application set with setOwner
+func (m *memoryBackend) Owner() (SourceApp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.owner, nil
+}
+
+// setOwner sets the application reported as owning the selection
+func (m *memoryBackend) setOwner(app SourceApp) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owner = app
+}
+
+func (m *memoryBackend) Targets() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	targets := make([]string, 0, len(m.contents))
+	for _, c := range m.contents {
+		targets = append(targets, c.MimeType)
+	}
+	return targets, nil
+}
+
+func (m *memoryBackend) Read(mimeType string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.contents {
+		if c.MimeType == mimeType {
+			data := make([]byte, len(c.Data))
+			copy(data, c.Data)
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("clipboard has no %s content", mimeType)
+}
+
+func (m *memoryBackend) Write(contents []ClipboardContent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.contents = make([]ClipboardContent, len(contents))
+	for i, c := range contents {
+		m.contents[i] = ClipboardContent{MimeType: c.MimeType, Data: append([]byte(nil), c.Data...)}
+	}
+
+	for _, w := range m.watchers {
+		w.notify()
+	}
+	return nil
+}
+
+func (m *memoryBackend) Watch() (*clipboardChangeNotifier, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := &clipboardChangeNotifier{
+		name:    "memory",
+		changes: make(chan struct{}, 1),
+	}
+	n.stop = func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, w := range m.watchers {
+			if w == n {
+				m.watchers = append(m.watchers[:i], m.watchers[i+1:]...)
+				close(n.changes)
+				break
+			}
+		}
+		return nil
+	}
+
+	m.watchers = append(m.watchers, n)
+	return n, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseTargets(t *testing.T) {
+	got := parseTargets("TARGETS\n image/png \n\nUTF8_STRING\n")
+	want := []string{"TARGETS", "image/png", "UTF8_STRING"}
+
+	if len(got) != len(want) {
+		t.Fatalf("parseTargets() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseTargets()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPickImageTarget(t *testing.T) {
+	tests := []struct {
+		targets []string
+		mime    string
+		format  string
+		ok      bool
+	}{
+		{targets: []string{"TARGETS", "image/png", "image/jpeg"}, mime: "image/png", format: "png", ok: true},
+		{targets: []string{"image/bmp", "image/png"}, mime: "image/bmp", format: "bmp", ok: true},
+		{targets: []string{"UTF8_STRING", "text/html"}, ok: false},
+		{targets: nil, ok: false},
+	}
+
+	for _, tt := range tests {
+		mime, format, ok := pickImageTarget(tt.targets)
+		if mime != tt.mime || format != tt.format || ok != tt.ok {
+			t.Errorf("pickImageTarget(%v) = %q, %q, %v; want %q, %q, %v", tt.targets, mime, format, ok, tt.mime, tt.format, tt.ok)
+		}
+	}
+}
+
+func TestMemoryBackendReadWrite(t *testing.T) {
+	backend := newMemoryBackend()
+
+	if _, err := backend.Read(mimeText); err == nil {
+		t.Error("Expected reading an empty clipboard to fail")
+	}
+
+	contents := []ClipboardContent{
+		{MimeType: mimeText, Data: []byte("hello")},
+		{MimeType: "text/html", Data: []byte("<b>hello</b>")},
+	}
+	if err := backend.Write(contents); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	// The backend must keep its own copy of the data
+	contents[0].Data[0] = 'j'
+
+	targets, err := backend.Targets()
+	if err != nil {
+		t.Fatalf("Targets() failed: %v", err)
+	}
+	if len(targets) != 2 || targets[0] != mimeText || targets[1] != "text/html" {
+		t.Errorf("Targets() = %v, want [%s text/html]", targets, mimeText)
+	}
+
+	data, err := backend.Read(mimeText)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Read(%s) = %q, %v; want \"hello\"", mimeText, data, err)
+	}
+	if _, err := backend.Read("image/png"); err == nil {
+		t.Error("Expected reading a missing type to fail")
+	}
+}
+
+func TestMemoryBackendWatch(t *testing.T) {
+	backend := newMemoryBackend()
+
+	notifier, err := backend.Watch()
+	if err != nil {
+		t.Fatalf("Watch() failed: %v", err)
+	}
+
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("first")}})
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("second")}})
+
+	select {
+	case <-notifier.changes:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a change notification after Write()")
+	}
+
+	// Both writes coalesce into the single pending signal
+	select {
+	case <-notifier.changes:
+		t.Error("Expected pending notifications to be coalesced")
+	default:
+	}
+
+	notifier.Close()
+	if _, ok := <-notifier.changes; ok {
+		t.Error("Expected the changes channel to be closed after Close()")
+	}
+
+	// Writing after Close must not panic on the closed channel
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("third")}})
+}
+
+func TestClipboardTextHelpers(t *testing.T) {
+	backend := setupTestClipboard(t)
+
+	if err := writeClipboardText("copied text"); err != nil {
+		t.Fatalf("writeClipboardText() failed: %v", err)
+	}
+
+	text, err := readClipboardText()
+	if err != nil || text != "copied text" {
+		t.Errorf("readClipboardText() = %q, %v; want \"copied text\"", text, err)
+	}
+
+	if data, _ := backend.Read(mimeText); string(data) != "copied text" {
+		t.Errorf("Expected the text to be written as %s, got %q", mimeText, data)
+	}
+}
+
+func TestImageClipboardRoundTrip(t *testing.T) {
+	backend := setupTestClipboard(t)
+
+	imageData, err := createTestImage()
+	if err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+
+	if err := restoreImageToSystemClipboard(imageData, "png"); err != nil {
+		t.Fatalf("restoreImageToSystemClipboard() failed: %v", err)
+	}
+	if targets, _ := backend.Targets(); len(targets) != 1 || targets[0] != "image/png" {
+		t.Errorf("Expected the image to be offered as image/png, got %v", targets)
+	}
+
+	data, format, err := detectImageInClipboard()
+	if err != nil {
+		t.Fatalf("detectImageInClipboard() failed: %v", err)
+	}
+	if format != "png" || !bytes.Equal(data, imageData) {
+		t.Errorf("detectImageInClipboard() returned format %q and %d bytes, want png and %d bytes", format, len(data), len(imageData))
+	}
+}
+
+func TestSelectClipboardBackendOverride(t *testing.T) {
+	t.Setenv("CLIPBOARD_MANAGER_BACKEND", "memory")
+	backend, err := selectClipboardBackend()
+	if err != nil {
+		t.Fatalf("selectClipboardBackend() failed: %v", err)
+	}
+	if backend.Name() != "memory" {
+		t.Errorf("Expected the memory backend, got %s", backend.Name())
+	}
+
+	t.Setenv("CLIPBOARD_MANAGER_BACKEND", "carrier-pigeon")
+	if _, err := selectClipboardBackend(); err == nil {
+		t.Error("Expected an unknown backend name to be rejected")
+	}
+}
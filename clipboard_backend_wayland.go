@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// waylandBackend accesses the clipboard, or the primary selection when
+// primary is set, through wl-paste and wl-copy
+type waylandBackend struct {
+	primary bool
+}
+
+// newWaylandBackend checks that wl-clipboard is installed
+func newWaylandBackend() (*waylandBackend, error) {
+	for _, tool := range []string{"wl-paste", "wl-copy"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, fmt.Errorf("%s not available", tool)
+		}
+	}
+	return &waylandBackend{}, nil
+}
+
+func (w *waylandBackend) Name() string {
+	return "Wayland (wl-clipboard)"
+}
+
+// Primary returns a backend for the primary selection. The compositor must
+// support the primary-selection protocol; otherwise reads fail.
+func (w *waylandBackend) Primary() (ClipboardBackend, error) {
+	return &waylandBackend{primary: true}, nil
+}
+
+// toolArgs prepends --primary when this backend targets the primary selection
+func (w *waylandBackend) toolArgs(args ...string) []string {
+	if w.primary {
+		return append([]string{"--primary"}, args...)
+	}
+	return args
+}
+
+func (w *waylandBackend) Targets() ([]string, error) {
+	output, err := runClipboardTool("wl-paste", w.toolArgs("--list-types")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clipboard types: %v", err)
+	}
+	return parseTargets(string(output)), nil
+}
+
+func (w *waylandBackend) Read(mimeType string) ([]byte, error) {
+	if mimeType == mimeText {
+		// wl-paste picks the best text type and would otherwise append a newline
+		return runClipboardTool("wl-paste", w.toolArgs("--no-newline")...)
+	}
+	return runClipboardTool("wl-paste", w.toolArgs("--type", mimeType)...)
+}
+
+// Write hands the data to wl-copy, which forks and keeps serving the
+// clipboard. wl-copy serves a single type, so several representations are
+// served through XWayland when it is running, which the compositor mirrors
+// to Wayland clients. Otherwise only the first representation is offered.
+func (w *waylandBackend) Write(contents []ClipboardContent) error {
+	if len(contents) == 0 {
+		return fmt.Errorf("nothing to write")
+	}
+	if display := os.Getenv("DISPLAY"); len(contents) > 1 && display != "" {
+		selection := "clipboard"
+		if w.primary {
+			selection = "primary"
+		}
+		if err := serveX11Selection(display, selection, contents); err == nil {
+			return nil
+		}
+	}
+	content := contents[0]
+
+	cmd := exec.Command("wl-copy", w.toolArgs()...)
+	if content.MimeType != mimeText {
+		cmd = exec.Command("wl-copy", w.toolArgs("--type", content.MimeType)...)
+	}
+	cmd.Stdin = bytes.NewReader(content.Data)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("wl-copy failed to set clipboard: %v", err)
+	}
+	return nil
+}
+
+// Owner identifies the application owning the selection when it is an
+// XWayland client. Wayland doesn't reveal which client copied, and copies
+// from native clients are bridged into XWayland by windows without a
+// WM_CLASS, so those stay unknown.
+func (w *waylandBackend) Owner() (SourceApp, error) {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return SourceApp{}, fmt.Errorf("DISPLAY not set")
+	}
+	selection := "CLIPBOARD"
+	if w.primary {
+		selection = "PRIMARY"
+	}
+
+	app, err := lookupSelectionOwner(display, selection)
+	if err != nil {
+		return SourceApp{}, err
+	}
+	if app.Class == "" {
+		return SourceApp{}, fmt.Errorf("selection owner is not an X11 application")
+	}
+	return app, nil
+}
+
+// Watch prefers wl-paste --watch and falls back to XFIXES through XWayland
+// when wl-paste can't be started
+func (w *waylandBackend) Watch() (*clipboardChangeNotifier, error) {
+	n, err := startWaylandNotifier(w.primary)
+	if err == nil {
+		return n, nil
+	}
+
+	selection := "CLIPBOARD"
+	if w.primary {
+		selection = "PRIMARY"
+	}
+	if display := os.Getenv("DISPLAY"); display != "" {
+		if xn, xerr := startX11Notifier(display, selection); xerr == nil {
+			return xn, nil
+		}
+	}
+	return nil, err
+}
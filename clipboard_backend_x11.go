@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// x11Backend accesses a selection through xclip, or through xsel for text
+// when xclip isn't installed. Change notifications come from XFIXES.
+type x11Backend struct {
+	tool      string // "xclip" or "xsel"
+	display   string
+	selection string // "clipboard" or "primary"
+}
+
+// newX11Backend checks for a usable X11 clipboard utility
+func newX11Backend() (*x11Backend, error) {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return nil, fmt.Errorf("DISPLAY not set")
+	}
+
+	for _, tool := range []string{"xclip", "xsel"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return &x11Backend{tool: tool, display: display, selection: "clipboard"}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("neither xclip nor xsel is installed")
+}
+
+func (x *x11Backend) Name() string {
+	return "X11 (" + x.tool + ")"
+}
+
+// Primary returns a backend for the PRIMARY (middle-click) selection
+func (x *x11Backend) Primary() (ClipboardBackend, error) {
+	return &x11Backend{tool: x.tool, display: x.display, selection: "primary"}, nil
+}
+
+func (x *x11Backend) Targets() ([]string, error) {
+	if x.tool == "xsel" {
+		// xsel can't list targets; report text only when there is some
+		if _, err := x.Read(mimeText); err != nil {
+			return nil, err
+		}
+		return []string{mimeText}, nil
+	}
+
+	output, err := runClipboardTool("xclip", "-selection", x.selection, "-t", "TARGETS", "-o")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clipboard targets: %v", err)
+	}
+	return parseTargets(string(output)), nil
+}
+
+func (x *x11Backend) Read(mimeType string) ([]byte, error) {
+	if x.tool == "xsel" {
+		if mimeType != mimeText {
+			return nil, fmt.Errorf("xsel can only read text")
+		}
+		return runClipboardTool("xsel", "--"+x.selection, "--output")
+	}
+
+	args := []string{"-selection", x.selection, "-o"}
+	if mimeType != mimeText {
+		// Without -t xclip asks for UTF8_STRING, which is what we want for text
+		args = append(args, "-t", mimeType)
+	}
+	return runClipboardTool("xclip", args...)
+}
+
+// Write hands the data to xclip or xsel, which fork and keep serving the
+// selection. Both serve a single target, so several representations are
+// served by our own selection helper instead.
+func (x *x11Backend) Write(contents []ClipboardContent) error {
+	if len(contents) == 0 {
+		return fmt.Errorf("nothing to write")
+	}
+	if len(contents) > 1 {
+		err := serveX11Selection(x.display, x.selection, contents)
+		if err == nil {
+			return nil
+		}
+		fmt.Printf("Warning: %v; offering %s only\n", err, contents[0].MimeType)
+	}
+	content := contents[0]
+
+	var cmd *exec.Cmd
+	switch {
+	case x.tool == "xsel" && content.MimeType != mimeText:
+		return fmt.Errorf("xsel can only write text")
+	case x.tool == "xsel":
+		cmd = exec.Command("xsel", "--"+x.selection, "--input")
+	case content.MimeType == mimeText:
+		cmd = exec.Command("xclip", "-selection", x.selection)
+	default:
+		cmd = exec.Command("xclip", "-selection", x.selection, "-t", content.MimeType)
+	}
+	cmd.Stdin = bytes.NewReader(content.Data)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed to set clipboard: %v", x.tool, err)
+	}
+	return nil
+}
+
+func (x *x11Backend) Watch() (*clipboardChangeNotifier, error) {
+	return startX11Notifier(x.display, strings.ToUpper(x.selection))
+}
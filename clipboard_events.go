@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// changeSettleDelay lets the new selection owner finish publishing its
+// content and coalesces bursts of owner changes into a single read
+const changeSettleDelay = 50 * time.Millisecond
+
+// clipboardChangeNotifier delivers a signal each time the clipboard changes.
+// The changes channel is closed when notifications stop.
+type clipboardChangeNotifier struct {
+	name    string
+	changes chan struct{}
+	stop    func() error
+}
+
+// notify records a change without blocking; a pending signal already covers it
+func (n *clipboardChangeNotifier) notify() {
+	select {
+	case n.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the notifier
+func (n *clipboardChangeNotifier) Close() error {
+	return n.stop()
+}
+
+// startX11Notifier watches owner changes of a selection (CLIPBOARD or PRIMARY) via XFIXES
+func startX11Notifier(display, selection string) (*clipboardChangeNotifier, error) {
+	conn, err := openX11SelectionConn(display)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.watchSelection(selection); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	n := &clipboardChangeNotifier{
+		name:    "X11 XFIXES " + selection,
+		changes: make(chan struct{}, 1),
+		stop:    conn.Close,
+	}
+
+	go func() {
+		defer close(n.changes)
+		for {
+			if _, err := conn.nextSelectionChange(); err != nil {
+				return
+			}
+			n.notify()
+		}
+	}()
+
+	return n, nil
+}
+
+// startWaylandNotifier runs 'wl-paste --watch', which executes a command on
+// every clipboard (or, with primary, primary selection) change. The command
+// discards the content and prints a line. Compositors without the
+// data-control protocol (e.g. GNOME) make wl-paste exit straight away, which
+// closes the channel and triggers the fallback.
+func startWaylandNotifier(primary bool) (*clipboardChangeNotifier, error) {
+	if _, err := exec.LookPath("wl-paste"); err != nil {
+		return nil, fmt.Errorf("wl-paste not available")
+	}
+
+	args := []string{"--watch", "sh", "-c", "cat > /dev/null; echo"}
+	if primary {
+		args = append([]string{"--primary"}, args...)
+	}
+	cmd := exec.Command("wl-paste", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wl-paste pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start wl-paste --watch: %v", err)
+	}
+
+	n := &clipboardChangeNotifier{
+		name:    "wl-paste " + strings.Join(args[:len(args)-3], " "),
+		changes: make(chan struct{}, 1),
+		stop: func() error {
+			return cmd.Process.Kill()
+		},
+	}
+
+	go func() {
+		defer close(n.changes)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			n.notify()
+		}
+		cmd.Wait()
+	}()
+
+	return n, nil
+}
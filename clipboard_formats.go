@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+)
+
+// richFormats are the representations kept alongside an item's plain text,
+// in the order they are offered back. Aliases used by some applications are
+// stored under the type they were offered as.
+var richFormats = []string{
+	"text/html",
+	"text/rtf",
+	"application/rtf",
+	"text/uri-list",
+	"image/png",
+	passwordManagerHint,
+}
+
+// readRichFormats reads every rich representation the clipboard owner offers.
+// Formats that can't be read or are empty are skipped.
+func readRichFormats(b ClipboardBackend) []ClipboardContent {
+	targets, err := b.Targets()
+	if err != nil {
+		return nil
+	}
+
+	offered := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		offered[target] = true
+	}
+
+	var formats []ClipboardContent
+	for _, mimeType := range richFormats {
+		if !offered[mimeType] {
+			continue
+		}
+		data, err := b.Read(mimeType)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		formats = append(formats, ClipboardContent{MimeType: mimeType, Data: data})
+	}
+	return formats
+}
+
+// findFormat returns the data stored for mimeType, if any
+func findFormat(formats []ClipboardContent, mimeType string) ([]byte, bool) {
+	for _, f := range formats {
+		if f.MimeType == mimeType {
+			return f.Data, true
+		}
+	}
+	return nil, false
+}
+
+// saveClipboardFormats stores the extra representations of an item
+func saveClipboardFormats(itemID int64, formats []ClipboardContent) error {
+	for _, f := range formats {
+		_, err := db.Exec("INSERT OR REPLACE INTO clipboard_formats (item_id, mime_type, data) VALUES (?, ?, ?)",
+			itemID, f.MimeType, f.Data)
+		if err != nil {
+			return fmt.Errorf("failed to save %s representation: %v", f.MimeType, err)
+		}
+	}
+	return nil
+}
+
+// loadClipboardFormats loads the extra representations of an item in the order they were stored
+func loadClipboardFormats(itemID int64) ([]ClipboardContent, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := db.Query("SELECT mime_type, data FROM clipboard_formats WHERE item_id = ? ORDER BY rowid", itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query representations: %v", err)
+	}
+	defer rows.Close()
+
+	var formats []ClipboardContent
+	for rows.Next() {
+		var f ClipboardContent
+		if err := rows.Scan(&f.MimeType, &f.Data); err != nil {
+			return nil, fmt.Errorf("failed to scan representation: %v", err)
+		}
+		formats = append(formats, f)
+	}
+	return formats, rows.Err()
+}
+
+// textItemContents returns everything to offer when restoring a text item:
+// the plain text first, then any stored rich representations
+func textItemContents(item ClipboardItem) []ClipboardContent {
+	contents := []ClipboardContent{{MimeType: mimeText, Data: []byte(item.Content)}}
+
+	formats, err := loadClipboardFormats(item.ID)
+	if err != nil {
+		fmt.Printf("Warning: %v; restoring plain text only\n", err)
+		return contents
+	}
+	return append(contents, formats...)
+}
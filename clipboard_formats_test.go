@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// richCopy is what a browser offers when a formatted table is copied
+var richCopy = []ClipboardContent{
+	{MimeType: mimeText, Data: []byte("Name\tQty")},
+	{MimeType: "application/x-moz-nativehtml", Data: []byte("ignored")},
+	{MimeType: "text/html", Data: []byte("<table><tr><td>Name</td><td>Qty</td></tr></table>")},
+	{MimeType: "text/rtf", Data: []byte(`{\rtf1 Name\tab Qty}`)},
+}
+
+func TestReadRichFormats(t *testing.T) {
+	backend := newMemoryBackend()
+	backend.Write(append(richCopy, ClipboardContent{MimeType: "text/uri-list", Data: nil}))
+
+	formats := readRichFormats(backend)
+
+	// Unknown and empty representations are skipped; order follows richFormats
+	if len(formats) != 2 || formats[0].MimeType != "text/html" || formats[1].MimeType != "text/rtf" {
+		t.Fatalf("Unexpected formats %+v", formats)
+	}
+	if html, ok := findFormat(formats, "text/html"); !ok || !bytes.Equal(html, richCopy[2].Data) {
+		t.Errorf("Expected the HTML to be read, got %q", html)
+	}
+}
+
+func TestClipboardFormatsPersisted(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	item := ClipboardItem{
+		Type:      ItemTypeText,
+		Content:   "Name\tQty",
+		Timestamp: time.Now(),
+		Formats:   []ClipboardContent{richCopy[2], richCopy[3]},
+	}
+	if err := saveClipboardItem(item); err != nil {
+		t.Fatalf("saveClipboardItem() failed: %v", err)
+	}
+
+	items, err := loadClipboardHistory()
+	if err != nil || len(items) != 1 {
+		t.Fatalf("loadClipboardHistory() = %d items, %v", len(items), err)
+	}
+	id := items[0].ID
+
+	contents := textItemContents(items[0])
+	if len(contents) != 3 || contents[0].MimeType != mimeText || contents[1].MimeType != "text/html" || contents[2].MimeType != "text/rtf" {
+		t.Fatalf("Unexpected restore contents %+v", contents)
+	}
+
+	// Editing the text drops the representations that no longer match it
+	if err := updateClipboardItem(id, "edited"); err != nil {
+		t.Fatalf("updateClipboardItem() failed: %v", err)
+	}
+	if formats, _ := loadClipboardFormats(id); len(formats) != 0 {
+		t.Errorf("Expected formats to be dropped after an edit, got %+v", formats)
+	}
+
+	// Deleting an item deletes its representations
+	item.Content = "second"
+	if err := saveClipboardItem(item); err != nil {
+		t.Fatalf("saveClipboardItem() failed: %v", err)
+	}
+	var second int64
+	db.QueryRow("SELECT id FROM clipboard_history WHERE content = ?", "second").Scan(&second)
+	if err := deleteClipboardItem(second); err != nil {
+		t.Fatalf("deleteClipboardItem() failed: %v", err)
+	}
+	var remaining int
+	db.QueryRow("SELECT COUNT(*) FROM clipboard_formats").Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected no orphaned formats, got %d", remaining)
+	}
+}
+
+func TestRestoreTextOffersAllFormats(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	backend := setupTestClipboard(t)
+
+	addTextToHistory("Name\tQty", SourceClipboard, []ClipboardContent{richCopy[2]}, SourceApp{})
+	item := getTestHistoryItem(0)
+
+	if err := restoreHistoryItem(item.ID); err != nil {
+		t.Fatalf("restoreHistoryItem() failed: %v", err)
+	}
+
+	targets, _ := backend.Targets()
+	if len(targets) != 2 || targets[0] != mimeText || targets[1] != "text/html" {
+		t.Errorf("Expected text and HTML to be offered, got %v", targets)
+	}
+}
+
+func TestWatcherCapturesRichFormats(t *testing.T) {
+	backend := newMemoryBackend()
+	w := newWatcher(watcherPresets["default"])
+	w.backend = func() (ClipboardBackend, error) {
+		return backend, nil
+	}
+
+	var captured []ClipboardContent
+	var images int
+	w.onText = func(text string, formats []ClipboardContent, app SourceApp) {
+		captured = formats
+	}
+	w.onImage = func([]byte, string, SourceApp) {
+		images++
+	}
+
+	// Spreadsheet cells come with a rendered PNG; that is part of the text item
+	backend.Write([]ClipboardContent{
+		{MimeType: mimeText, Data: []byte("A1\tB1")},
+		{MimeType: "text/html", Data: []byte("<td>A1</td><td>B1</td>")},
+		{MimeType: "image/png", Data: []byte("rendered cells")},
+	})
+	w.check(true)
+
+	if len(captured) != 2 || captured[0].MimeType != "text/html" || captured[1].MimeType != "image/png" {
+		t.Errorf("Expected HTML and PNG to be captured with the text, got %+v", captured)
+	}
+	if images != 0 {
+		t.Errorf("Expected the PNG not to be recorded as a separate image, got %d", images)
+	}
+}
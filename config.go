@@ -0,0 +1,437 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds user settings loaded from ~/.config/clipboard-manager/config.toml.
+// Any setting missing from the file keeps its default value.
+type Config struct {
+	Retention RetentionPolicy   `toml:"retention"`
+	Watcher   WatcherSettings   `toml:"watcher"`
+	Primary   PrimarySettings   `toml:"primary"`
+	Startup   StartupSettings   `toml:"startup"`
+	Filter    FilterSettings    `toml:"filter"`
+	Hotkey    HotkeySettings    `toml:"hotkey"`
+	Theme     ThemeSettings     `toml:"theme"`
+	Sensitive SensitiveSettings `toml:"sensitive"`
+	Apps      AppSettings       `toml:"apps"`
+}
+
+// Duration is a time.Duration that can be written in TOML as "90s", "24h" or "30d"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration string, additionally accepting a "d" (days) suffix
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" || s == "0" {
+		d.Duration = 0
+		return nil
+	}
+
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		d.Duration = time.Duration(days * float64(24*time.Hour))
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalText formats the duration, using whole days where possible
+func (d Duration) MarshalText() ([]byte, error) {
+	if d.Duration != 0 && d.Duration%(24*time.Hour) == 0 {
+		return []byte(fmt.Sprintf("%dd", d.Duration/(24*time.Hour))), nil
+	}
+	return []byte(d.Duration.String()), nil
+}
+
+// HexColor is a color written in TOML as "#rrggbb" or "#rrggbbaa"
+type HexColor struct {
+	color.RGBA
+}
+
+// UnmarshalText parses a hex color
+func (c *HexColor) UnmarshalText(text []byte) error {
+	s := strings.TrimPrefix(strings.TrimSpace(string(text)), "#")
+	if len(s) == 6 {
+		s += "ff"
+	}
+	value, err := strconv.ParseUint(s, 16, 32)
+	if len(s) != 8 || err != nil {
+		return fmt.Errorf("invalid color %q (expected #rrggbb or #rrggbbaa)", string(text))
+	}
+	c.RGBA = color.RGBA{R: uint8(value >> 24), G: uint8(value >> 16), B: uint8(value >> 8), A: uint8(value)}
+	return nil
+}
+
+// MarshalText formats the color, leaving out the alpha channel when opaque
+func (c HexColor) MarshalText() ([]byte, error) {
+	if c.A == 0xff {
+		return []byte(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)), nil
+	}
+	return []byte(fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)), nil
+}
+
+// rgb returns an opaque HexColor
+func rgb(r, g, b uint8) HexColor {
+	return HexColor{color.RGBA{R: r, G: g, B: b, A: 0xff}}
+}
+
+var (
+	config   = defaultConfig()
+	configMu sync.RWMutex
+)
+
+// defaultConfig returns the settings used when no config file is present
+func defaultConfig() Config {
+	return Config{
+		Retention: defaultRetentionPolicy(),
+		Primary:   defaultPrimarySettings(),
+		Startup:   defaultStartupSettings(),
+		Filter:    defaultFilterSettings(),
+		Hotkey:    defaultHotkeySettings(),
+		Theme:     defaultThemeSettings(),
+		Sensitive: defaultSensitiveSettings(),
+	}
+}
+
+// getConfigPath returns the path to the user's config file
+func getConfigPath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "clipboard-manager", "config.toml")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "/tmp/clipboard-manager-config.toml"
+	}
+
+	return filepath.Join(home, ".config", "clipboard-manager", "config.toml")
+}
+
+// getConfig returns a copy of the current configuration
+func getConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config
+}
+
+// parseConfig decodes TOML on top of the defaults and validates the result
+func parseConfig(data string) (Config, error) {
+	cfg := defaultConfig()
+	md, err := toml.Decode(data, &cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return Config{}, fmt.Errorf("unknown setting %s", strings.Join(keys, ", "))
+	}
+	if _, err := cfg.Watcher.options(""); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Primary.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Startup.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Filter.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Hotkey.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Sensitive.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Apps.validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// readConfigFile reads and validates the config file at path. A missing file
+// gives the defaults.
+func readConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return defaultConfig(), nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	cfg, err := parseConfig(string(data))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// loadConfig reads the config file into the global configuration.
+// A missing file is not an error; the defaults are used instead.
+func loadConfig() error {
+	cfg, err := readConfigFile(getConfigPath())
+	if err != nil {
+		return err
+	}
+
+	configMu.Lock()
+	config = cfg
+	configMu.Unlock()
+
+	return nil
+}
+
+// configReloadedCh is closed and replaced each time a reload changes the config
+var configReloadedCh = make(chan struct{})
+
+// configReloaded returns a channel that is closed the next time the
+// configuration changes
+func configReloaded() <-chan struct{} {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return configReloadedCh
+}
+
+// reloadConfig re-reads the config file and reports whether anything changed.
+// An invalid file leaves the current configuration in place.
+func reloadConfig() (bool, error) {
+	cfg, err := readConfigFile(getConfigPath())
+	if err != nil {
+		return false, err
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	if reflect.DeepEqual(cfg, config) {
+		return false, nil
+	}
+	config = cfg
+	close(configReloadedCh)
+	configReloadedCh = make(chan struct{})
+	return true, nil
+}
+
+// configPollInterval is how often the daemon checks the config file for changes
+const configPollInterval = 2 * time.Second
+
+// watchConfigFile reloads the configuration on SIGHUP or when the config file
+// changes. It blocks forever.
+func watchConfigFile() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	path := getConfigPath()
+	last := configFileStamp(path)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hup:
+		case <-ticker.C:
+			stamp := configFileStamp(path)
+			if stamp == last {
+				continue
+			}
+			last = stamp
+		}
+
+		changed, err := reloadConfig()
+		switch {
+		case err != nil:
+			fmt.Printf("Warning: %v; keeping the current settings\n", err)
+		case changed:
+			fmt.Printf("Reloaded settings from %s\n", path)
+		}
+	}
+}
+
+// configFileStamp identifies a version of the config file by its modification
+// time and size; a missing file has a zero stamp
+func configFileStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// configUsage is shown when the config subcommand is missing or unknown
+const configUsage = "clipboard-manager config show|path|get <key>|set <key> <value>"
+
+// runConfigCommand handles 'config show|path|get|set'
+func runConfigCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected one of show, path, get or set")
+	}
+
+	switch {
+	case args[0] == "path" && len(args) == 1:
+		fmt.Println(getConfigPath())
+
+	case args[0] == "show" && len(args) == 1:
+		data, err := encodeTOML(getConfig())
+		if err != nil {
+			return err
+		}
+		fmt.Print(data)
+
+	case args[0] == "get" && len(args) == 2:
+		value, err := configValue(getConfig(), args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+
+	case args[0] == "set" && len(args) == 3:
+		path := getConfigPath()
+		if err := setConfigValue(path, args[1], args[2]); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Set %s in %s\n", args[1], path)
+		if _, running := readDaemonLock(getLockPath()); running {
+			fmt.Println("  The running daemon picks up the change within a few seconds")
+		}
+
+	default:
+		return fmt.Errorf("unknown config command %q", strings.Join(args, " "))
+	}
+	return nil
+}
+
+// encodeTOML renders a value as TOML
+func encodeTOML(v interface{}) (string, error) {
+	var b strings.Builder
+	if err := toml.NewEncoder(&b).Encode(v); err != nil {
+		return "", fmt.Errorf("failed to encode settings: %v", err)
+	}
+	return b.String(), nil
+}
+
+// configTree converts a configuration to nested maps keyed by TOML names
+func configTree(cfg Config) (map[string]interface{}, error) {
+	data, err := encodeTOML(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tree := map[string]interface{}{}
+	if _, err := toml.Decode(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode settings: %v", err)
+	}
+	return tree, nil
+}
+
+// configValue returns the setting at a dotted key such as
+// "retention.text.max_items". Strings are returned bare, tables and other
+// values as TOML.
+func configValue(cfg Config, key string) (string, error) {
+	tree, err := configTree(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	var value interface{} = tree
+	for _, part := range strings.Split(key, ".") {
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("unknown setting %q", key)
+		}
+		if value, ok = table[part]; !ok {
+			return "", fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case map[string]interface{}:
+		data, err := encodeTOML(v)
+		return strings.TrimSuffix(data, "\n"), err
+	default:
+		data, err := encodeTOML(map[string]interface{}{"value": v})
+		return strings.TrimSuffix(strings.TrimPrefix(data, "value = "), "\n"), err
+	}
+}
+
+// parseConfigValue interprets a value given on the command line as TOML,
+// treating anything that isn't valid TOML as a string
+func parseConfigValue(raw string) interface{} {
+	var parsed map[string]interface{}
+	if _, err := toml.Decode("value = "+raw, &parsed); err == nil {
+		return parsed["value"]
+	}
+	return raw
+}
+
+// setConfigValue sets a dotted key in the config file at path. The result is
+// validated before it is written, so the file is never left invalid. The file
+// is rewritten, so comments in it are not kept.
+func setConfigValue(path, key, raw string) error {
+	tree := map[string]interface{}{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	if _, err := toml.Decode(string(data), &tree); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	parts := strings.Split(key, ".")
+	table := tree
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part]
+		if !ok {
+			next = map[string]interface{}{}
+			table[part] = next
+		}
+		if table, ok = next.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s is not a table", part)
+		}
+	}
+	table[parts[len(parts)-1]] = parseConfigValue(raw)
+
+	updated, err := encodeTOML(tree)
+	if err != nil {
+		return err
+	}
+	if _, err := parseConfig(updated); err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// restoreConfigAfter puts the global configuration back when the test ends
+func restoreConfigAfter(t *testing.T) {
+	t.Helper()
+	saved := getConfig()
+	t.Cleanup(func() {
+		configMu.Lock()
+		config = saved
+		configMu.Unlock()
+	})
+}
+
+// writeTestConfig writes the config file in the test home
+func writeTestConfig(t *testing.T, data string) {
+	t.Helper()
+	path := getConfigPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "Misspelled key", data: "[watcher]\npol_interval = \"5s\"\n", err: "unknown setting watcher.pol_interval"},
+		{name: "Wrong type", data: "[filter]\nmin_length = \"three\"\n", err: "line 2"},
+		{name: "Bad color", data: "[theme.dark]\nhover = \"#12345\"\n", err: "invalid color"},
+		{name: "Bad hotkey", data: "[hotkey]\nbinding = \"Ctrl+Shift+Enter\"\n", err: "must be a letter, digit or F1-F24"},
+		{name: "Negative length", data: "[filter]\nmin_length = -1\n", err: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseConfigThemeAndHotkey(t *testing.T) {
+	cfg, err := parseConfig("[hotkey]\nbinding = \"super+f12\"\n\n[theme.dark]\nhover = \"#102030\"\n")
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+
+	if cfg.Theme.Dark.Hover.RGBA != (color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}) {
+		t.Errorf("Unexpected dark hover color %v", cfg.Theme.Dark.Hover)
+	}
+	if cfg.Theme.Light.Hover != defaultThemeSettings().Light.Hover {
+		t.Error("Expected colors that aren't set to keep their defaults")
+	}
+
+	hotkey, err := parseHotkey(cfg.Hotkey.Binding)
+	if err != nil {
+		t.Fatalf("parseHotkey() failed: %v", err)
+	}
+	if hotkey.String() != "Meta+F12" || hotkey.gnome() != "<Super>F12" {
+		t.Errorf("Unexpected hotkey %q / %q", hotkey, hotkey.gnome())
+	}
+	if hotkey, _ := parseHotkey("Ctrl+Shift+V"); hotkey.gnome() != "<Primary><Shift>v" {
+		t.Errorf("Expected the default GNOME binding, got %q", hotkey.gnome())
+	}
+}
+
+func TestNoisePatternsFromConfig(t *testing.T) {
+	restoreConfigAfter(t)
+
+	// noise_patterns from older config files still drop short text
+	cfg, err := parseConfig("[filter]\nmin_length = 1\nnoise_patterns = [\"import\"]\nnoise_max_length = 20\n")
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+	configMu.Lock()
+	config = cfg
+	configMu.Unlock()
+
+	if !isNoise(ItemTypeText, "import \"os\"") {
+		t.Error("Expected the old patterns to drop short Go fragments")
+	}
+	if decision := checkNoise(ItemTypeText, "import \"os\""); decision.Rule != `filter.noise_patterns "import"` {
+		t.Errorf("Expected test-filter to name the old setting, got %q", decision.Rule)
+	}
+	if isNoise(ItemTypeText, "import the photos before the trip") || isNoise(ItemTypeText, "ok") {
+		t.Error("Expected long text and text without a pattern to be kept")
+	}
+	if isNoise(ItemTypeFiles, "/home/user/import") {
+		t.Error("Expected the old patterns to apply to text only")
+	}
+
+	if _, err := parseConfig("[filter]\nnoise_max_length = -1\n"); err == nil {
+		t.Error("Expected a negative noise_max_length to be rejected")
+	}
+}
+
+func TestConfigSetAndGet(t *testing.T) {
+	setupTestHome(t)
+	path := getConfigPath()
+
+	if err := setConfigValue(path, "retention.text.max_age", "30d"); err != nil {
+		t.Fatalf("setConfigValue() failed: %v", err)
+	}
+	if err := setConfigValue(path, "watcher.monitor_images", "false"); err != nil {
+		t.Fatalf("setConfigValue() failed: %v", err)
+	}
+
+	// Invalid values and unknown keys leave the file alone
+	before, _ := os.ReadFile(path)
+	if err := setConfigValue(path, "retention.text.max_items", "lots"); err == nil {
+		t.Error("Expected a string for an integer setting to be rejected")
+	}
+	if err := setConfigValue(path, "watcher.pol_interval", "5s"); err == nil {
+		t.Error("Expected an unknown setting to be rejected")
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("Expected the file to be unchanged, got:\n%s", after)
+	}
+
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile() failed: %v", err)
+	}
+	if cfg.Retention.Text.MaxAge.Duration != 30*24*time.Hour || cfg.Watcher.MonitorImages == nil || *cfg.Watcher.MonitorImages {
+		t.Errorf("Expected both settings to be saved, got %+v", cfg)
+	}
+	if cfg.Retention.Text.MaxItems != defaultRetentionPolicy().Text.MaxItems {
+		t.Error("Expected unset keys to keep their defaults")
+	}
+
+	for key, want := range map[string]string{
+		"retention.text.max_age": "30d",
+		"hotkey.binding":         "Ctrl+Shift+V",
+		"filter.min_length":      "3",
+	} {
+		if got, err := configValue(cfg, key); err != nil || got != want {
+			t.Errorf("configValue(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	if _, err := configValue(cfg, "retention.text.max_items.nested"); err == nil {
+		t.Error("Expected an unknown key to be rejected")
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	setupTestHome(t)
+	restoreConfigAfter(t)
+	loadConfig()
+
+	reloaded := configReloaded()
+	watcherChanged := watcherSettingsChanged(getConfig(), reloaded)
+
+	// A retention change is picked up without restarting the watcher
+	writeTestConfig(t, "[retention.text]\nmax_items = 7\n")
+	if changed, err := reloadConfig(); !changed || err != nil {
+		t.Fatalf("reloadConfig() = %v, %v", changed, err)
+	}
+	select {
+	case <-reloaded:
+	default:
+		t.Error("Expected the reload to be signalled")
+	}
+	if getConfig().Retention.Text.MaxItems != 7 {
+		t.Errorf("Expected the new limit, got %d", getConfig().Retention.Text.MaxItems)
+	}
+
+	// An invalid file keeps the current settings
+	writeTestConfig(t, "[retention.text]\nmax_items = \"seven\"\n")
+	if changed, err := reloadConfig(); changed || err == nil {
+		t.Errorf("Expected the invalid file to be rejected, got %v, %v", changed, err)
+	}
+	if getConfig().Retention.Text.MaxItems != 7 {
+		t.Error("Expected the previous settings to stay in place")
+	}
+
+	select {
+	case <-watcherChanged:
+		t.Fatal("Expected the watcher to keep running after a retention change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeTestConfig(t, "[retention.text]\nmax_items = 7\n\n[watcher]\npreset = \"minimal\"\n")
+	reloadConfig()
+	select {
+	case <-watcherChanged:
+	case <-time.After(time.Second):
+		t.Error("Expected a watcher change to restart the watcher")
+	}
+}
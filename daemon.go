@@ -1,51 +1,34 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
-// Check if clipboard daemon is running
-func isDaemonRunning() bool {
-	// Check for running clipboard-manager processes (any daemon mode)
-	cmd := exec.Command("pgrep", "-f", "clipboard-manager")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-	
-	pids := strings.TrimSpace(string(output))
-	if pids == "" {
-		return false
-	}
-	
-	// Check if any of the processes are daemon processes
-	for _, pid := range strings.Split(pids, "\n") {
-		pid = strings.TrimSpace(pid)
-		if pid != "" {
-			// Check the command line of this process
-			cmdlineFile := fmt.Sprintf("/proc/%s/cmdline", pid)
-			if cmdline, err := os.ReadFile(cmdlineFile); err == nil {
-				cmdStr := string(cmdline)
-				if strings.Contains(cmdStr, "daemon") {
-					return true
-				}
-			}
+// daemonWaitTimeout bounds how long start and stop wait for the daemon
+const daemonWaitTimeout = 5 * time.Second
+
+// waitForDaemon polls the lock file until a daemon is running or not, as wanted
+func waitForDaemon(running bool, timeout time.Duration) (daemonLockInfo, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, ok := readDaemonLock(getLockPath())
+		if ok == running || time.Now().After(deadline) {
+			return info, ok == running
 		}
+		time.Sleep(50 * time.Millisecond)
 	}
-	
-	return false
 }
 
 // Start daemon if not running
 func ensureDaemonRunning() {
-	if isDaemonRunning() {
-		fmt.Println("✓ Clipboard daemon is already running")
+	if info, running := readDaemonLock(getLockPath()); running {
+		fmt.Printf("✓ Clipboard daemon is already running (PID: %d, mode: %s)\n", info.PID, info.Mode)
 		return
 	}
 	
@@ -78,58 +61,31 @@ func ensureDaemonRunning() {
 		cmd.Wait()
 	}()
 	
-	fmt.Printf("✓ Clipboard daemon started (PID: %d)\n", cmd.Process.Pid)
-	
-	// Save PID for later reference
-	saveDaemonPID(cmd.Process.Pid)
-}
-
-// Save daemon PID to file
-func saveDaemonPID(pid int) {
-	home, err := os.UserHomeDir()
-	if err != nil {
+	// The daemon takes the lock once it is up. If another one won the race
+	// to start, this one exits and the winner is reported instead.
+	info, ok := waitForDaemon(true, daemonWaitTimeout)
+	if !ok {
+		fmt.Println("Warning: Clipboard daemon did not start. Run 'clipboard-manager daemon' to see why.")
 		return
 	}
-	
-	pidDir := filepath.Join(home, ".local", "share", "clipboard-manager")
-	os.MkdirAll(pidDir, 0755)
-	
-	pidFile := filepath.Join(pidDir, "daemon.pid")
-	os.WriteFile(pidFile, []byte(strconv.Itoa(pid)), 0644)
-}
-
-// Get daemon PID from file
-func getDaemonPID() int {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return 0
-	}
-	
-	pidFile := filepath.Join(home, ".local", "share", "clipboard-manager", "daemon.pid")
-	data, err := os.ReadFile(pidFile)
-	if err != nil {
-		return 0
-	}
-	
-	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
-	if err != nil {
-		return 0
-	}
-	
-	return pid
+	fmt.Printf("✓ Clipboard daemon started (PID: %d)\n", info.PID)
 }
 
 // Stop daemon
 func stopDaemon() {
-	pid := getDaemonPID()
-	if pid == 0 {
-		fmt.Println("No daemon PID found")
+	info, running := readDaemonLock(getLockPath())
+	if !running {
+		fmt.Println("✗ Clipboard daemon is not running")
+		return
+	}
+	if info.PID == 0 {
+		fmt.Println("❌ Clipboard daemon is running but its lock file has no PID")
 		return
 	}
 	
-	process, err := os.FindProcess(pid)
+	process, err := os.FindProcess(info.PID)
 	if err != nil {
-		fmt.Printf("Could not find process %d: %v\n", pid, err)
+		fmt.Printf("Could not find process %d: %v\n", info.PID, err)
 		return
 	}
 	
@@ -138,21 +94,235 @@ func stopDaemon() {
 		return
 	}
 	
-	fmt.Printf("✓ Daemon stopped (PID: %d)\n", pid)
-	
-	// Remove PID file
-	home, _ := os.UserHomeDir()
-	pidFile := filepath.Join(home, ".local", "share", "clipboard-manager", "daemon.pid")
-	os.Remove(pidFile)
+	if _, stopped := waitForDaemon(false, daemonWaitTimeout); !stopped {
+		fmt.Printf("❌ Daemon (PID: %d) did not stop within %v\n", info.PID, daemonWaitTimeout)
+		return
+	}
+	fmt.Printf("✓ Daemon stopped (PID: %d)\n", info.PID)
 }
 
 // Show daemon status
 func showDaemonStatus() {
-	if isDaemonRunning() {
-		pid := getDaemonPID()
-		fmt.Printf("✓ Clipboard daemon is running (PID: %d)\n", pid)
-	} else {
+	info, running := readDaemonLock(getLockPath())
+	if !running {
 		fmt.Println("✗ Clipboard daemon is not running")
 		fmt.Println("Start it with: ./clipboard-manager daemon")
+		return
+	}
+	
+	// Only reached when the control socket didn't answer
+	fmt.Printf("✓ Clipboard daemon is running (PID: %d)\n", info.PID)
+	fmt.Printf("  Mode:    %s\n", info.Mode)
+	fmt.Printf("  Started: %s\n", info.Started.Format("2006-01-02 15:04:05"))
+	fmt.Println("  Control socket is not responding")
+}
+
+// controlCommandUsage lists the CLI commands that go through the daemon's
+// control socket when it is running and use the database directly otherwise
+var controlCommandUsage = map[string]string{
+	"list":    "clipboard-manager list",
+	"get":     "clipboard-manager get <id>  (IDs are shown by 'list' and 'search')",
+	"delete":  "clipboard-manager delete <id>  (IDs are shown by 'list' and 'search')",
+	"pin":     "clipboard-manager pin <id>  (IDs are shown by 'list' and 'search')",
+	"unpin":   "clipboard-manager unpin <id>  (IDs are shown by 'list' and 'search')",
+	"clear":   "clipboard-manager clear [--force]",
+	"capture": "clipboard-manager capture",
+	"pause":   "clipboard-manager pause",
+	"resume":  "clipboard-manager resume",
+	"status":  "clipboard-manager status",
+}
+
+// runControlCommand runs a control command through the daemon, falling back
+// to the database when no daemon is listening
+func runControlCommand(cmd string, args []string) error {
+	switch cmd {
+	case "list":
+		var items []ClipboardItem
+		err := callDaemon("list", nil, &items)
+		if err == errDaemonUnavailable {
+			loadHistory()
+			showTerminalHistory()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		printHistory(items)
+		
+	case "get":
+		id, err := parseItemID(args)
+		if err != nil {
+			return err
+		}
+		var item ipcItem
+		err = callDaemon("get", ipcIDParams{ID: id}, &item)
+		if err == errDaemonUnavailable {
+			loadHistory()
+			stored, err := getClipboardItem(id)
+			if err != nil {
+				return err
+			}
+			item = ipcItem{ClipboardItem: stored, ImageData: stored.ImageData}
+		} else if err != nil {
+			return err
+		}
+		return printItemContent(item)
+		
+	case "delete":
+		id, err := parseItemID(args)
+		if err != nil {
+			return err
+		}
+		err = callDaemon("delete", ipcIDParams{ID: id}, nil)
+		if err == errDaemonUnavailable {
+			loadHistory()
+			return removeHistoryItem(id)
+		}
+		if err == nil {
+			fmt.Printf("Removed history item %d\n", id)
+		}
+		return err
+		
+	case "pin", "unpin":
+		id, err := parseItemID(args)
+		if err != nil {
+			return err
+		}
+		pinned := cmd == "pin"
+		err = callDaemon("pin", ipcPinParams{ID: id, Pinned: pinned}, nil)
+		if err == errDaemonUnavailable {
+			loadHistory()
+			return setHistoryItemPinned(id, pinned)
+		}
+		if err == nil && pinned {
+			fmt.Printf("Pinned history item %d\n", id)
+		} else if err == nil {
+			fmt.Printf("Unpinned history item %d\n", id)
+		}
+		return err
+		
+	case "clear":
+		force, err := parseClearArgs(args)
+		if err != nil {
+			return err
+		}
+		err = callDaemon("clear", ipcClearParams{Force: force}, nil)
+		if err == errDaemonUnavailable {
+			loadHistory()
+			return runClearCommand(args)
+		}
+		if err == nil {
+			fmt.Println("Clipboard history cleared.")
+		}
+		return err
+		
+	case "capture":
+		text, err := readClipboardText()
+		if err != nil {
+			return fmt.Errorf("error reading clipboard: %v", err)
+		}
+		text = normalizeCaptured(ItemTypeText, text)
+		if isNoise(ItemTypeText, text) {
+			fmt.Println("No meaningful text found in clipboard")
+			return nil
+		}
+		
+		backend, _ := getClipboardBackend()
+		app, allowed := allowedOwner(backend)
+		if !allowed {
+			return nil
+		}
+		hint := secretHint(backend)
+		reason := detectSensitive(text, hint, getConfig().Sensitive)
+		if reason != "" && getConfig().Sensitive.Action == sensitiveSkip {
+			fmt.Printf("🔒 Not recording sensitive text (%s)\n", reason)
+			return nil
+		}
+		
+		err = callDaemon("add", ipcAddParams{Text: text, Sensitive: len(hint) > 0, App: app}, nil)
+		var rpcErr *ipcError
+		if err == errDaemonUnavailable {
+			loadHistory()
+			if _, ok := addTextToHistory(text, SourceClipboard, hint, app); !ok {
+				return fmt.Errorf("clipboard text was not saved")
+			}
+		} else if errors.As(err, &rpcErr) && rpcErr.Code == ipcCapturePaused {
+			fmt.Println("⏸️  Clipboard capture is paused; nothing was recorded. Resume with: clipboard-manager resume")
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if reason != "" {
+			fmt.Printf("📋 Captured: %s\n", maskedText(ClipboardItem{Sensitive: reason}))
+		} else {
+			fmt.Printf("📋 Captured: %s\n", text)
+		}
+		
+	case "pause", "resume":
+		var status DaemonStatus
+		if err := callDaemon(cmd, nil, &status); err != nil {
+			return err
+		}
+		if status.Paused {
+			fmt.Println("⏸️  Clipboard capture paused. Resume with: clipboard-manager resume")
+		} else {
+			fmt.Println("▶️  Clipboard capture resumed")
+		}
+		
+	case "status":
+		var status DaemonStatus
+		err := callDaemon("status", nil, &status)
+		var rpcErr *ipcError
+		if err != nil && !errors.As(err, &rpcErr) {
+			// No daemon, or one that isn't answering; the lock file still knows
+			showDaemonStatus()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		printDaemonStatus(status)
+		
+	default:
+		return fmt.Errorf("unknown command %s", cmd)
+	}
+	
+	return nil
+}
+
+// printDaemonStatus shows the status reported over the control socket
+func printDaemonStatus(status DaemonStatus) {
+	fmt.Printf("✓ Clipboard daemon is running (PID: %d)\n", status.PID)
+	fmt.Printf("  Mode:    %s\n", status.Mode)
+	fmt.Printf("  Started: %s\n", status.Started.Format("2006-01-02 15:04:05"))
+	if status.Paused {
+		fmt.Println("  Capture: paused")
+	} else {
+		fmt.Println("  Capture: active")
 	}
-}
\ No newline at end of file
+	fmt.Printf("  Items:   %d\n", status.Items)
+	fmt.Printf("  Socket:  %s\n", status.Socket)
+}
+
+// printItemContent writes an item's content to stdout so it can be used in
+// scripts. Image bytes are written as-is when stdout is redirected.
+func printItemContent(item ipcItem) error {
+	if item.Type != ItemTypeImage {
+		fmt.Println(item.Content)
+		return nil
+	}
+	
+	if stat, err := os.Stdout.Stat(); err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		_, err := os.Stdout.Write(item.ImageData)
+		return err
+	}
+	
+	if item.ImageMeta != nil {
+		fmt.Printf("[IMAGE] %s %dx%d (%d KB)\n", strings.ToUpper(item.ImageMeta.Format),
+			item.ImageMeta.Width, item.ImageMeta.Height, len(item.ImageData)/1024)
+	} else {
+		fmt.Printf("[IMAGE] %d KB\n", len(item.ImageData)/1024)
+	}
+	fmt.Println("Redirect the output to a file to save the image.")
+	return nil
+}
@@ -14,8 +14,22 @@ import (
 
 var db *sql.DB
 
-// initDatabase initializes the SQLite database and creates tables if they don't exist
+// initDatabase initializes the SQLite database and brings its schema up to date
 func initDatabase() error {
+	if err := openDatabase(); err != nil {
+		return err
+	}
+	
+	// Create or upgrade tables
+	if err := createTables(); err != nil {
+		return fmt.Errorf("failed to create tables: %v", err)
+	}
+	
+	return nil
+}
+
+// openDatabase opens the SQLite database without touching its schema
+func openDatabase() error {
 	dbPath := getDatabasePath()
 	
 	// Ensure directory exists
@@ -34,11 +48,6 @@ func initDatabase() error {
 		return fmt.Errorf("failed to ping database: %v", err)
 	}
 	
-	// Create tables
-	if err = createTables(); err != nil {
-		return fmt.Errorf("failed to create tables: %v", err)
-	}
-	
 	return nil
 }
 
@@ -54,27 +63,13 @@ func getDatabasePath() string {
 	return filepath.Join(dir, "history.db")
 }
 
-// createTables creates the necessary database tables
+// createTables applies any pending schema migrations and sets up the search index
 func createTables() error {
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS clipboard_history (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		type TEXT NOT NULL CHECK(type IN ('text', 'image')),
-		content TEXT NOT NULL,
-		timestamp DATETIME NOT NULL,
-		image_format TEXT,
-		image_width INTEGER,
-		image_height INTEGER,
-		image_size INTEGER,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_timestamp ON clipboard_history(timestamp DESC);
-	CREATE INDEX IF NOT EXISTS idx_type ON clipboard_history(type);
-	`
+	if err := applyMigrations(schemaMigrations); err != nil {
+		return err
+	}
 	
-	_, err := db.Exec(createTableSQL)
-	return err
+	return ensureSearchIndex()
 }
 
 // closeDatabase closes the database connection
@@ -87,31 +82,39 @@ func closeDatabase() error {
 
 // saveClipboardItem saves a clipboard item to the database
 func saveClipboardItem(item ClipboardItem) error {
+	_, _, err := storeClipboardItem(item)
+	return err
+}
+
+// storeClipboardItem saves a clipboard item and applies the retention policy.
+// It returns the item as stored and the IDs retention removed, so in-memory
+// history can be updated without reloading the table.
+func storeClipboardItem(item ClipboardItem) (ClipboardItem, []int64, error) {
 	if db == nil {
-		return fmt.Errorf("database not initialized")
-	}
-	
-	// Check for duplicates (same content and type)
-	var count int
-	checkSQL := "SELECT COUNT(*) FROM clipboard_history WHERE content = ? AND type = ?"
-	err := db.QueryRow(checkSQL, item.Content, string(item.Type)).Scan(&count)
-	if err != nil {
-		return fmt.Errorf("failed to check for duplicates: %v", err)
-	}
-	
-	// If duplicate exists, delete it first (we'll add the new one at the end)
-	if count > 0 {
-		deleteSQL := "DELETE FROM clipboard_history WHERE content = ? AND type = ?"
-		_, err = db.Exec(deleteSQL, item.Content, string(item.Type))
-		if err != nil {
-			return fmt.Errorf("failed to delete duplicate: %v", err)
-		}
-	}
-	
-	// Insert new item
-	insertSQL := `
-	INSERT INTO clipboard_history (type, content, timestamp, image_format, image_width, image_height, image_size)
-	VALUES (?, ?, ?, ?, ?, ?, ?)
+		return ClipboardItem{}, nil, fmt.Errorf("database not initialized")
+	}
+	
+	if item.ContentHash == "" {
+		item.ContentHash = itemContentHash(item)
+	}
+	
+	// Insert new item. Content copied again is matched by its hash and moved to
+	// the end with its use count bumped; a re-copied pinned item stays pinned,
+	// and a secret stays marked as one.
+	upsertSQL := `
+	INSERT INTO clipboard_history (type, content, timestamp, image_format, image_width, image_height, image_size, image_data, content_hash, use_count, pinned, source, sensitive, source_app, source_class, source_title)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(type, content_hash) DO UPDATE SET
+		content = excluded.content,
+		timestamp = excluded.timestamp,
+		use_count = use_count + 1,
+		pinned = MAX(pinned, excluded.pinned),
+		source = excluded.source,
+		source_app = excluded.source_app,
+		source_class = excluded.source_class,
+		source_title = excluded.source_title,
+		sensitive = CASE WHEN excluded.sensitive != '' THEN excluded.sensitive ELSE sensitive END
+	RETURNING id, use_count, pinned, sensitive
 	`
 	
 	var imageFormat sql.NullString
@@ -124,14 +127,69 @@ func saveClipboardItem(item ClipboardItem) error {
 		imageSize = sql.NullInt64{Int64: int64(item.ImageMeta.Size), Valid: true}
 	}
 	
-	_, err = db.Exec(insertSQL, string(item.Type), item.Content, item.Timestamp,
-		imageFormat, imageWidth, imageHeight, imageSize)
+	source := item.Source
+	if source == "" {
+		source = SourceClipboard
+	}
+	
+	err := db.QueryRow(upsertSQL, string(item.Type), item.Content, item.Timestamp,
+		imageFormat, imageWidth, imageHeight, imageSize, item.ImageData, item.ContentHash, item.Pinned, string(source), item.Sensitive, item.App, item.AppClass, item.WindowTitle).
+		Scan(&item.ID, &item.UseCount, &item.Pinned, &item.Sensitive)
 	if err != nil {
-		return fmt.Errorf("failed to insert clipboard item: %v", err)
+		return ClipboardItem{}, nil, fmt.Errorf("failed to insert clipboard item: %v", err)
+	}
+	item.Source = source
+	
+	// The representations of the latest copy replace any stored earlier
+	if _, err := db.Exec("DELETE FROM clipboard_formats WHERE item_id = ?", item.ID); err != nil {
+		return ClipboardItem{}, nil, fmt.Errorf("failed to replace representations: %v", err)
+	}
+	if err := saveClipboardFormats(item.ID, item.Formats); err != nil {
+		return ClipboardItem{}, nil, err
 	}
 	
-	// Maintain max history size
-	return maintainHistoryLimit()
+	// Apply the retention policy (count, size and age limits)
+	expired, err := applyRetention(getConfig().Retention)
+	return item, expired, err
+}
+
+// clipboardItemColumns is the column list read by scanClipboardItem. Image
+// bytes are left out so listing history stays cheap; see loadImageData.
+const clipboardItemColumns = "id, type, content, timestamp, image_format, image_width, image_height, image_size, content_hash, use_count, pinned, source, sensitive, source_app, source_class, source_title"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanClipboardItem reads a row selected with clipboardItemColumns
+func scanClipboardItem(row rowScanner) (ClipboardItem, error) {
+	var item ClipboardItem
+	var itemType, source string
+	var imageFormat, hash sql.NullString
+	var imageWidth, imageHeight, imageSize sql.NullInt64
+	
+	err := row.Scan(&item.ID, &itemType, &item.Content, &item.Timestamp,
+		&imageFormat, &imageWidth, &imageHeight, &imageSize, &hash, &item.UseCount, &item.Pinned, &source, &item.Sensitive, &item.App, &item.AppClass, &item.WindowTitle)
+	if err != nil {
+		return ClipboardItem{}, err
+	}
+	
+	item.Type = ClipboardItemType(itemType)
+	item.Source = ClipboardSource(source)
+	item.ContentHash = hash.String
+	
+	// Set image metadata if available
+	if imageFormat.Valid {
+		item.ImageMeta = &ImageMetadata{
+			Format: imageFormat.String,
+			Width:  int(imageWidth.Int64),
+			Height: int(imageHeight.Int64),
+			Size:   int(imageSize.Int64),
+		}
+	}
+	
+	return item, nil
 }
 
 // loadClipboardHistory loads clipboard history from the database
@@ -140,11 +198,7 @@ func loadClipboardHistory() ([]ClipboardItem, error) {
 		return nil, fmt.Errorf("database not initialized")
 	}
 	
-	query := `
-	SELECT type, content, timestamp, image_format, image_width, image_height, image_size
-	FROM clipboard_history
-	ORDER BY timestamp ASC
-	`
+	query := "SELECT " + clipboardItemColumns + " FROM clipboard_history ORDER BY timestamp ASC, id ASC"
 	
 	rows, err := db.Query(query)
 	if err != nil {
@@ -155,29 +209,11 @@ func loadClipboardHistory() ([]ClipboardItem, error) {
 	var items []ClipboardItem
 	
 	for rows.Next() {
-		var item ClipboardItem
-		var itemType string
-		var imageFormat sql.NullString
-		var imageWidth, imageHeight, imageSize sql.NullInt64
-		
-		err := rows.Scan(&itemType, &item.Content, &item.Timestamp,
-			&imageFormat, &imageWidth, &imageHeight, &imageSize)
+		item, err := scanClipboardItem(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %v", err)
 		}
 		
-		item.Type = ClipboardItemType(itemType)
-		
-		// Set image metadata if available
-		if imageFormat.Valid {
-			item.ImageMeta = &ImageMetadata{
-				Format: imageFormat.String,
-				Width:  int(imageWidth.Int64),
-				Height: int(imageHeight.Int64),
-				Size:   int(imageSize.Int64),
-			}
-		}
-		
 		items = append(items, item)
 	}
 	
@@ -188,15 +224,81 @@ func loadClipboardHistory() ([]ClipboardItem, error) {
 	return items, nil
 }
 
-// updateClipboardItem updates the content of an existing clipboard item
-func updateClipboardItem(oldContent string, newContent string, itemType ClipboardItemType) error {
+// getClipboardItem loads a single clipboard item by its database ID
+func getClipboardItem(id int64) (ClipboardItem, error) {
+	if db == nil {
+		return ClipboardItem{}, fmt.Errorf("database not initialized")
+	}
+	
+	query := "SELECT " + clipboardItemColumns + " FROM clipboard_history WHERE id = ?"
+	
+	item, err := scanClipboardItem(db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return ClipboardItem{}, fmt.Errorf("no item found with id %d", id)
+	}
+	if err != nil {
+		return ClipboardItem{}, fmt.Errorf("failed to load clipboard item: %v", err)
+	}
+	
+	if item.Type == ItemTypeImage {
+		if item.ImageData, err = loadImageData(id); err != nil {
+			return ClipboardItem{}, err
+		}
+	}
+	
+	return item, nil
+}
+
+// loadImageData loads the bytes of an image item by its database ID
+func loadImageData(id int64) ([]byte, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	
+	var data []byte
+	err := db.QueryRow("SELECT image_data FROM clipboard_history WHERE id = ?", id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no item found with id %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image data: %v", err)
+	}
+	
+	return data, nil
+}
+
+// updateClipboardItem updates the content of an existing text item by its ID
+func updateClipboardItem(id int64, newContent string) error {
 	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 	
-	// Update the item's content and timestamp
-	updateSQL := "UPDATE clipboard_history SET content = ?, timestamp = ? WHERE content = ? AND type = ?"
-	result, err := db.Exec(updateSQL, newContent, time.Now(), oldContent, string(itemType))
+	hash := itemContentHash(ClipboardItem{Type: ItemTypeText, Content: newContent})
+	
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin update: %v", err)
+	}
+	defer tx.Rollback()
+	
+	// Editing an item into the text of another merges the two
+	var otherUses int
+	var otherPinned bool
+	mergeSQL := "SELECT COALESCE(SUM(use_count), 0), COALESCE(MAX(pinned), 0) FROM clipboard_history WHERE type = ? AND content_hash = ? AND id != ?"
+	if err := tx.QueryRow(mergeSQL, string(ItemTypeText), hash, id).Scan(&otherUses, &otherPinned); err != nil {
+		return fmt.Errorf("failed to check for duplicates: %v", err)
+	}
+	if _, err := tx.Exec("DELETE FROM clipboard_history WHERE type = ? AND content_hash = ? AND id != ?", string(ItemTypeText), hash, id); err != nil {
+		return fmt.Errorf("failed to merge duplicate: %v", err)
+	}
+	
+	// Update the item's content and timestamp (only text items are editable)
+	updateSQL := `
+	UPDATE clipboard_history
+	SET content = ?, content_hash = ?, timestamp = ?, use_count = use_count + ?, pinned = MAX(pinned, ?)
+	WHERE id = ? AND type = ?
+	`
+	result, err := tx.Exec(updateSQL, newContent, hash, time.Now(), otherUses, otherPinned, id, string(ItemTypeText))
 	if err != nil {
 		return fmt.Errorf("failed to update clipboard item: %v", err)
 	}
@@ -207,20 +309,24 @@ func updateClipboardItem(oldContent string, newContent string, itemType Clipboar
 	}
 	
 	if rowsAffected == 0 {
-		return fmt.Errorf("no item found to update")
+		return fmt.Errorf("no text item found with id %d", id)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit update: %v", err)
 	}
 	
 	return nil
 }
 
-// deleteClipboardItem deletes a clipboard item by its content and type
-func deleteClipboardItem(content string, itemType ClipboardItemType) error {
+// deleteClipboardItem deletes a clipboard item by its ID
+func deleteClipboardItem(id int64) error {
 	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 	
-	deleteSQL := "DELETE FROM clipboard_history WHERE content = ? AND type = ?"
-	result, err := db.Exec(deleteSQL, content, string(itemType))
+	deleteSQL := "DELETE FROM clipboard_history WHERE id = ?"
+	result, err := db.Exec(deleteSQL, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete clipboard item: %v", err)
 	}
@@ -231,19 +337,48 @@ func deleteClipboardItem(content string, itemType ClipboardItemType) error {
 	}
 	
 	if rowsAffected == 0 {
-		return fmt.Errorf("no item found to delete")
+		return fmt.Errorf("no item found with id %d", id)
 	}
 	
 	return nil
 }
 
-// clearClipboardHistory deletes all clipboard history
-func clearClipboardHistory() error {
+// setClipboardItemPinned sets or clears the pinned flag of an item by its ID
+func setClipboardItemPinned(id int64, pinned bool) error {
 	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 	
-	_, err := db.Exec("DELETE FROM clipboard_history")
+	result, err := db.Exec("UPDATE clipboard_history SET pinned = ? WHERE id = ?", pinned, id)
+	if err != nil {
+		return fmt.Errorf("failed to update pinned flag: %v", err)
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	
+	if rowsAffected == 0 {
+		return fmt.Errorf("no item found with id %d", id)
+	}
+	
+	return nil
+}
+
+// clearClipboardHistory deletes clipboard history. Pinned items are kept
+// unless force is set.
+func clearClipboardHistory(force bool) error {
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	
+	deleteSQL := "DELETE FROM clipboard_history WHERE pinned = 0"
+	if force {
+		deleteSQL = "DELETE FROM clipboard_history"
+	}
+	
+	_, err := db.Exec(deleteSQL)
 	if err != nil {
 		return fmt.Errorf("failed to clear clipboard history: %v", err)
 	}
@@ -266,30 +401,192 @@ func getClipboardHistoryCount() (int, error) {
 	return count, nil
 }
 
-// maintainHistoryLimit ensures the history doesn't exceed maxHistory items
-func maintainHistoryLimit() error {
-	count, err := getClipboardHistoryCount()
-	if err != nil {
-		return err
+// enforceRetention deletes items that fall outside the retention policy.
+// Pinned items are never deleted. Returns the number of rows removed.
+func enforceRetention(policy RetentionPolicy) (int, error) {
+	expired, err := applyRetention(policy)
+	return len(expired), err
+}
+
+// applyRetention deletes items that fall outside the retention policy and
+// returns their IDs. Expired items are always the oldest of their group, so
+// the oldest are read up to the first one kept for the age limit, and the
+// count and size limits delete everything past the newest items they keep.
+// The cost per capture therefore depends on the limits, not on how much
+// history has built up.
+func applyRetention(policy RetentionPolicy) ([]int64, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
 	}
 	
-	if count > maxHistory {
-		// Delete oldest items
-		deleteSQL := `
-		DELETE FROM clipboard_history 
-		WHERE id IN (
-			SELECT id FROM clipboard_history 
-			ORDER BY timestamp ASC 
-			LIMIT ?
-		)
-		`
-		_, err = db.Exec(deleteSQL, count-maxHistory)
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin retention cleanup: %v", err)
+	}
+	
+	// File lists are a few paths each, so they share the text limits
+	var expired []int64
+	now := time.Now()
+	for _, group := range []struct {
+		types  []ClipboardItemType
+		limits RetentionLimits
+	}{
+		{[]ClipboardItemType{ItemTypeText, ItemTypeFiles}, policy.Text},
+		{[]ClipboardItemType{ItemTypeImage}, policy.Image},
+	} {
+		aged, err := deleteAgedItems(tx, group.limits.MaxAge.Duration, now, group.types...)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		expired = append(expired, aged...)
+		
+		keep, err := countKeptItems(tx, group.limits, now, group.types...)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if keep < 0 {
+			continue
+		}
+		older, err := deleteOlderItems(tx, keep, group.types...)
 		if err != nil {
-			return fmt.Errorf("failed to maintain history limit: %v", err)
+			tx.Rollback()
+			return nil, err
 		}
+		expired = append(expired, older...)
 	}
 	
-	return nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit retention cleanup: %v", err)
+	}
+	
+	return expired, nil
+}
+
+// retentionTypeFilter returns the WHERE clause and arguments selecting the
+// unpinned items of the given types
+func retentionTypeFilter(itemTypes ...ClipboardItemType) (string, []interface{}) {
+	placeholders := make([]string, len(itemTypes))
+	args := make([]interface{}, len(itemTypes))
+	for i, itemType := range itemTypes {
+		placeholders[i] = "?"
+		args[i] = string(itemType)
+	}
+	return "type IN (" + strings.Join(placeholders, ", ") + ") AND pinned = 0", args
+}
+
+// deleteAgedItems deletes the unpinned items of the given types older than
+// maxAge, reading from the oldest up to the first one that is kept
+func deleteAgedItems(tx *sql.Tx, maxAge time.Duration, now time.Time, itemTypes ...ClipboardItemType) ([]int64, error) {
+	if maxAge <= 0 {
+		return nil, nil
+	}
+	
+	where, args := retentionTypeFilter(itemTypes...)
+	rows, err := tx.Query("SELECT id, timestamp FROM clipboard_history WHERE "+where+" ORDER BY timestamp, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention candidates: %v", err)
+	}
+	
+	var ids []int64
+	for rows.Next() {
+		var c retentionCandidate
+		if err := rows.Scan(&c.id, &c.timestamp); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan retention candidate: %v", err)
+		}
+		if now.Sub(c.timestamp) <= maxAge {
+			break
+		}
+		ids = append(ids, c.id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query retention candidates: %v", err)
+	}
+	
+	for _, id := range ids {
+		if _, err := tx.Exec("DELETE FROM clipboard_history WHERE id = ?", id); err != nil {
+			return nil, fmt.Errorf("failed to apply retention policy: %v", err)
+		}
+	}
+	
+	return ids, nil
+}
+
+// countKeptItems returns how many of the newest unpinned items of the given
+// types the count and size limits keep, or -1 if they keep them all. Only
+// the size limit needs rows read, and it stops at the first expired item
+// since every older one is expired too.
+func countKeptItems(tx *sql.Tx, limits RetentionLimits, now time.Time, itemTypes ...ClipboardItemType) (int, error) {
+	if limits.MaxBytes == 0 {
+		if limits.MaxItems > 0 {
+			return limits.MaxItems, nil
+		}
+		return -1, nil
+	}
+	
+	where, args := retentionTypeFilter(itemTypes...)
+	query := `
+	SELECT id, timestamp, COALESCE(image_size, LENGTH(content))
+	FROM clipboard_history
+	WHERE ` + where + `
+	ORDER BY timestamp DESC, id DESC
+	`
+	
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query retention candidates: %v", err)
+	}
+	defer rows.Close()
+	
+	var totalBytes int64
+	for i := 0; rows.Next(); i++ {
+		var c retentionCandidate
+		if err := rows.Scan(&c.id, &c.timestamp, &c.size); err != nil {
+			return 0, fmt.Errorf("failed to scan retention candidate: %v", err)
+		}
+		totalBytes += c.size
+		if limits.expires(i, totalBytes, c, now) {
+			return i, nil
+		}
+	}
+	
+	return -1, rows.Err()
+}
+
+// deleteOlderItems deletes the unpinned items of the given types after the
+// newest keep and returns their IDs
+func deleteOlderItems(tx *sql.Tx, keep int, itemTypes ...ClipboardItemType) ([]int64, error) {
+	where, args := retentionTypeFilter(itemTypes...)
+	query := `
+	DELETE FROM clipboard_history
+	WHERE id IN (
+		SELECT id FROM clipboard_history
+		WHERE ` + where + `
+		ORDER BY timestamp DESC, id DESC
+		LIMIT -1 OFFSET ?
+	)
+	RETURNING id
+	`
+	
+	rows, err := tx.Query(query, append(args, keep)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply retention policy: %v", err)
+	}
+	defer rows.Close()
+	
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to apply retention policy: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	
+	return ids, rows.Err()
 }
 
 // migrateFromJSON migrates existing JSON history to SQLite database
@@ -335,6 +632,10 @@ func migrateFromJSON() error {
 	
 	// Save each item to SQLite
 	for _, item := range jsonHistory {
+		if err := decodeLegacyImage(&item); err != nil {
+			fmt.Printf("Warning: failed to migrate item: %v\n", err)
+			continue
+		}
 		if err := saveClipboardItem(item); err != nil {
 			fmt.Printf("Warning: failed to migrate item: %v\n", err)
 		}
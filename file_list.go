@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MIME types file managers use for copied files. Nautilus and other GTK file
+// managers read x-special/gnome-copied-files; Dolphin and most other
+// applications read text/uri-list.
+const (
+	mimeURIList     = "text/uri-list"
+	mimeGnomeCopied = "x-special/gnome-copied-files"
+)
+
+// fileSizeWalkLimit caps how many entries are visited when sizing copied
+// folders, so a copied home directory doesn't stall the popup
+const fileSizeWalkLimit = 10000
+
+// parseURIList returns the local paths in a text/uri-list. It fails if the
+// list is empty or names anything other than local files, such as a web link.
+func parseURIList(data string) ([]string, bool) {
+	var paths []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") || u.Path == "" {
+			return nil, false
+		}
+		paths = append(paths, u.Path)
+	}
+	return paths, len(paths) > 0
+}
+
+// parseGnomeCopiedFiles returns the local paths in x-special/gnome-copied-files,
+// which is "copy" or "cut" followed by one URI per line
+func parseGnomeCopiedFiles(data string) ([]string, bool) {
+	action, uris, found := strings.Cut(data, "\n")
+	action = strings.TrimSpace(action)
+	if !found || (action != "copy" && action != "cut") {
+		return nil, false
+	}
+	return parseURIList(uris)
+}
+
+// readFileList returns the copied files if the clipboard holds a file list
+func readFileList(b ClipboardBackend) ([]string, bool) {
+	targets, err := b.Targets()
+	if err != nil {
+		return nil, false
+	}
+
+	offered := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		offered[target] = true
+	}
+
+	if offered[mimeGnomeCopied] {
+		if data, err := b.Read(mimeGnomeCopied); err == nil {
+			if paths, ok := parseGnomeCopiedFiles(string(data)); ok {
+				return paths, true
+			}
+		}
+	}
+	if offered[mimeURIList] {
+		if data, err := b.Read(mimeURIList); err == nil {
+			return parseURIList(string(data))
+		}
+	}
+	return nil, false
+}
+
+// fileURI converts an absolute path to a file:// URI
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// fileListContents returns the representations that let file managers paste
+// the files again, followed by the paths as plain text for everything else
+func fileListContents(paths []string) []ClipboardContent {
+	uris := make([]string, len(paths))
+	for i, path := range paths {
+		uris[i] = fileURI(path)
+	}
+
+	return []ClipboardContent{
+		{MimeType: mimeURIList, Data: []byte(strings.Join(uris, "\r\n") + "\r\n")},
+		{MimeType: mimeGnomeCopied, Data: []byte("copy\n" + strings.Join(uris, "\n"))},
+		{MimeType: mimeText, Data: []byte(strings.Join(paths, "\n"))},
+	}
+}
+
+// filePaths returns the paths stored in a file list item
+func filePaths(item ClipboardItem) []string {
+	if item.Content == "" {
+		return nil
+	}
+	return strings.Split(item.Content, "\n")
+}
+
+// fileListInfo describes the files of a file list item as they are now on disk
+type fileListInfo struct {
+	missing []string // Paths that no longer exist
+	size    int64    // Total size of the files that exist, including folder contents
+	partial bool     // Folder sizes were cut short by fileSizeWalkLimit
+}
+
+// statFileList checks which files still exist and adds up their sizes
+func statFileList(paths []string) fileListInfo {
+	var info fileListInfo
+	visited := 0
+
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			info.missing = append(info.missing, path)
+			continue
+		}
+		if !stat.IsDir() {
+			info.size += stat.Size()
+			continue
+		}
+
+		filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			visited++
+			if visited > fileSizeWalkLimit {
+				info.partial = true
+				return fs.SkipAll
+			}
+			if d.Type().IsRegular() {
+				if fi, err := d.Info(); err == nil {
+					info.size += fi.Size()
+				}
+			}
+			return nil
+		})
+	}
+
+	return info
+}
+
+// formatFileSize renders a byte count for display
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+// describeFileList summarises a file list as "N files: a, b, c…"
+func describeFileList(paths []string, maxNames int) string {
+	names := make([]string, 0, maxNames)
+	for i, path := range paths {
+		if i == maxNames {
+			names = append(names, "…")
+			break
+		}
+		names = append(names, filepath.Base(path))
+	}
+
+	return fileCount(len(paths)) + ": " + strings.Join(names, ", ")
+}
+
+// fileCount renders "1 file" or "N files"
+func fileCount(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// addFilesToHistory adds a file list copied from app to clipboard history
+func addFilesToHistory(paths []string, app SourceApp) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	if len(paths) == 0 {
+		return
+	}
+	content := strings.Join(paths, "\n")
+
+	newItem := ClipboardItem{
+		Type:        ItemTypeFiles,
+		Content:     content,
+		Timestamp:   time.Now(),
+		Source:      SourceClipboard,
+		App:         app.Name,
+		AppClass:    app.Class,
+		WindowTitle: app.Title,
+	}
+
+	// Skip if it's the same as the last item
+	if len(history) > 0 && history[len(history)-1].Content == content {
+		return
+	}
+
+	saved, expired, err := storeClipboardItem(newItem)
+	if err != nil {
+		fmt.Printf("Error saving file list to database: %v\n", err)
+		return
+	}
+
+	appendToHistory(saved, expired)
+}
+
+// recordCopiedFiles adds a file list to history and prints a short notice
+func recordCopiedFiles(paths []string, app SourceApp) {
+	addFilesToHistory(paths, app)
+	fmt.Printf("📋 Files copied: %s\n", describeFileList(paths, 3))
+}
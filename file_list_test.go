@@ -0,0 +1,208 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseURIList(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		paths []string
+		ok    bool
+	}{
+		{
+			name:  "Local files with CRLF and comments",
+			data:  "# copied by Dolphin\r\nfile:///home/user/a.txt\r\nfile://localhost/home/user/My%20Docs\r\n",
+			paths: []string{"/home/user/a.txt", "/home/user/My Docs"},
+			ok:    true,
+		},
+		{name: "Web link", data: "https://example.com/page\n"},
+		{name: "Mixed local and remote", data: "file:///tmp/a\nsftp://host/tmp/b\n"},
+		{name: "Other host", data: "file://server/share/a\n"},
+		{name: "Empty", data: "\n# nothing\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, ok := parseURIList(tt.data)
+			if ok != tt.ok || strings.Join(paths, "|") != strings.Join(tt.paths, "|") {
+				t.Errorf("parseURIList() = %v, %v; want %v, %v", paths, ok, tt.paths, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseGnomeCopiedFiles(t *testing.T) {
+	paths, ok := parseGnomeCopiedFiles("cut\nfile:///tmp/one\nfile:///tmp/two")
+	if !ok || len(paths) != 2 || paths[1] != "/tmp/two" {
+		t.Errorf("Unexpected result %v, %v", paths, ok)
+	}
+
+	if _, ok := parseGnomeCopiedFiles("file:///tmp/one"); ok {
+		t.Error("Expected data without an action line to be rejected")
+	}
+}
+
+func TestFileListContentsRoundTrip(t *testing.T) {
+	paths := []string{"/tmp/report final.pdf", "/tmp/photos"}
+	backend := newMemoryBackend()
+	backend.Write(fileListContents(paths))
+
+	got, ok := readFileList(backend)
+	if !ok || strings.Join(got, "|") != strings.Join(paths, "|") {
+		t.Fatalf("readFileList() = %v, %v; want %v", got, ok, paths)
+	}
+
+	uris, _ := backend.Read(mimeURIList)
+	if !strings.Contains(string(uris), "file:///tmp/report%20final.pdf\r\n") {
+		t.Errorf("Expected an escaped CRLF-terminated URI list, got %q", uris)
+	}
+	text, _ := backend.Read(mimeText)
+	if string(text) != "/tmp/report final.pdf\n/tmp/photos" {
+		t.Errorf("Expected plain paths as text, got %q", text)
+	}
+}
+
+func TestStatFileList(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	folder := filepath.Join(dir, "folder")
+	os.WriteFile(file, make([]byte, 100), 0644)
+	os.Mkdir(folder, 0755)
+	os.WriteFile(filepath.Join(folder, "b.bin"), make([]byte, 2048), 0644)
+
+	info := statFileList([]string{file, folder, filepath.Join(dir, "gone.txt")})
+
+	if info.size != 2148 {
+		t.Errorf("Expected total size 2148, got %d", info.size)
+	}
+	if len(info.missing) != 1 || filepath.Base(info.missing[0]) != "gone.txt" {
+		t.Errorf("Expected gone.txt to be missing, got %v", info.missing)
+	}
+	if info.partial {
+		t.Error("Expected a complete size for a small folder")
+	}
+}
+
+func TestDescribeFileList(t *testing.T) {
+	if got := describeFileList([]string{"/a/one.txt"}, 3); got != "1 file: one.txt" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	if got := describeFileList([]string{"/a/1", "/a/2", "/a/3", "/a/4"}, 2); got != "4 files: 1, 2, …" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	if got := formatFileSize(1536); got != "1.5 KB" {
+		t.Errorf("formatFileSize(1536) = %q", got)
+	}
+}
+
+func TestWatcherRecordsFileLists(t *testing.T) {
+	backend := newMemoryBackend()
+	w, texts, _ := newTestWatcher(watcherPresets["text-only"], backend)
+	var files [][]string
+	w.onFiles = func(paths []string, app SourceApp) {
+		files = append(files, paths)
+	}
+
+	// Nautilus offers the paths as text alongside the file list
+	backend.Write(append(fileListContents([]string{"/tmp/a.txt", "/tmp/b.txt"})[1:],
+		ClipboardContent{MimeType: mimeText, Data: []byte("/tmp/a.txt\n/tmp/b.txt")}))
+	w.check(false)
+
+	if len(files) != 1 || len(files[0]) != 2 || len(*texts) != 0 {
+		t.Errorf("Expected one file list and no text, got %v and %v", files, *texts)
+	}
+
+	// A copied web link is text, not a file list
+	backend.Write([]ClipboardContent{
+		{MimeType: mimeText, Data: []byte("https://example.com")},
+		{MimeType: mimeURIList, Data: []byte("https://example.com\r\n")},
+	})
+	w.check(false)
+
+	if len(files) != 1 || len(*texts) != 1 {
+		t.Errorf("Expected the link to be recorded as text, got %v and %v", files, *texts)
+	}
+}
+
+func TestFileListHistoryAndRestore(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	backend := setupTestClipboard(t)
+
+	addFilesToHistory([]string{"/tmp/a.txt", "/tmp/b.txt"}, SourceApp{})
+	item := getTestHistoryItem(0)
+	if item.Type != ItemTypeFiles || len(filePaths(item)) != 2 {
+		t.Fatalf("Expected a file list item, got %+v", item)
+	}
+
+	if err := restoreHistoryItem(item.ID); err != nil {
+		t.Fatalf("restoreHistoryItem() failed: %v", err)
+	}
+	targets, _ := backend.Targets()
+	if strings.Join(targets, ",") != mimeURIList+","+mimeGnomeCopied+","+mimeText {
+		t.Errorf("Expected file manager targets, got %v", targets)
+	}
+
+	results, err := searchHistory("b.txt", SearchFilters{Type: ItemTypeFiles, Limit: 10})
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected to find the file list by name, got %v, %v", results, err)
+	}
+
+	// File lists count towards the text limits
+	addToHistory("some text")
+	policy := defaultRetentionPolicy()
+	policy.Text.MaxItems = 1
+	if _, err := enforceRetention(policy); err != nil {
+		t.Fatalf("enforceRetention() failed: %v", err)
+	}
+	if items, _ := loadClipboardHistory(); len(items) != 1 || items[0].Type != ItemTypeText {
+		t.Errorf("Expected only the newer text item to remain, got %+v", items)
+	}
+}
+
+func TestMigrationsAllowFilesType(t *testing.T) {
+	openRawTestDB(t)
+	defer teardownTestDB(t)
+
+	// A database at version 4 with a text item, then upgraded
+	if err := applyMigrations(schemaMigrations[:4]); err != nil {
+		t.Fatalf("applyMigrations() failed: %v", err)
+	}
+	if err := ensureSearchIndex(); err != nil {
+		t.Fatalf("ensureSearchIndex() failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO clipboard_history (type, content, timestamp, pinned) VALUES ('text', 'kept across rebuild', ?, 1)", time.Now()); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO clipboard_history (type, content, timestamp) VALUES ('files', '/tmp/x', ?)", time.Now()); err == nil {
+		t.Fatal("Expected the old schema to reject the files type")
+	}
+
+	if err := createTables(); err != nil {
+		t.Fatalf("createTables() failed: %v", err)
+	}
+
+	items, err := loadClipboardHistory()
+	if err != nil || len(items) != 1 || !items[0].Pinned {
+		t.Fatalf("Expected the pinned item to survive the rebuild, got %+v, %v", items, err)
+	}
+	if _, err := db.Exec("INSERT INTO clipboard_history (type, content, timestamp) VALUES ('files', '/tmp/x', ?)", time.Now()); err != nil {
+		t.Errorf("Expected the files type to be accepted: %v", err)
+	}
+
+	// The search index keeps working after its triggers were dropped with the table
+	results, err := searchHistory("rebuild", SearchFilters{Limit: 10})
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected search to find the migrated item, got %v, %v", results, err)
+	}
+	results, err = searchHistory("tmp", SearchFilters{Limit: 10})
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected search to find the new file list, got %v, %v", results, err)
+	}
+}
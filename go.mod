@@ -6,13 +6,13 @@ toolchain go1.24.9
 
 require (
 	fyne.io/fyne/v2 v2.7.0
-	github.com/atotto/clipboard v0.1.4
+	github.com/BurntSushi/toml v1.5.0
 	github.com/mattn/go-sqlite3 v1.14.32
+	golang.org/x/sys v0.37.0
 )
 
 require (
 	fyne.io/systray v1.11.1-0.20250603113521-ca66a66d8b58 // indirect
-	github.com/BurntSushi/toml v1.5.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fredbi/uri v1.1.1 // indirect
 	github.com/fsnotify/fsnotify v1.9.0 // indirect
@@ -40,7 +40,6 @@ require (
 	github.com/yuin/goldmark v1.7.13 // indirect
 	golang.org/x/image v0.32.0 // indirect
 	golang.org/x/net v0.46.0 // indirect
-	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
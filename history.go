@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -20,14 +22,34 @@ type ClipboardItemType string
 const (
 	ItemTypeText  ClipboardItemType = "text"
 	ItemTypeImage ClipboardItemType = "image"
+	ItemTypeFiles ClipboardItemType = "files" // Files copied in a file manager; Content is one path per line
+)
+
+// ClipboardSource records which X11/Wayland selection an item was captured from
+type ClipboardSource string
+
+const (
+	SourceClipboard ClipboardSource = "clipboard" // Ctrl+C / Ctrl+V
+	SourcePrimary   ClipboardSource = "primary"   // Text selection, pasted with middle-click
 )
 
 // ClipboardItem represents a single clipboard entry that can be text or image
 type ClipboardItem struct {
-	Type      ClipboardItemType `json:"type"`
-	Content   string            `json:"content"`   // Text content or base64 encoded image
-	Timestamp time.Time         `json:"timestamp"`
-	ImageMeta *ImageMetadata    `json:"image_meta,omitempty"` // Metadata for images
+	ID          int64              `json:"id,omitempty"` // SQLite row ID, stable across concurrent writes
+	Type        ClipboardItemType  `json:"type"`
+	Content     string             `json:"content"` // Text content; base64 encoded image in legacy JSON history
+	Timestamp   time.Time          `json:"timestamp"`
+	ImageMeta   *ImageMetadata     `json:"image_meta,omitempty"`   // Metadata for images
+	ImageData   []byte             `json:"-"`                      // Raw image bytes, stored as a BLOB
+	ContentHash string             `json:"content_hash,omitempty"` // SHA-256 of the normalized content, unique per type
+	UseCount    int                `json:"use_count,omitempty"`    // Number of times the content was copied
+	Pinned      bool               `json:"pinned,omitempty"`       // Pinned items are exempt from retention
+	Source      ClipboardSource    `json:"source,omitempty"`       // Selection the item was copied from
+	Sensitive   string             `json:"sensitive,omitempty"`    // Why the content looks like a secret; empty for ordinary items
+	App         string             `json:"app,omitempty"`          // Application the item was copied from, if known
+	AppClass    string             `json:"app_class,omitempty"`    // WM_CLASS class of that application
+	WindowTitle string             `json:"window_title,omitempty"` // Title of the window it was copied in
+	Formats     []ClipboardContent `json:"-"`                      // Rich representations to save; load with loadClipboardFormats
 }
 
 // ImageMetadata contains metadata about image clipboard items
@@ -43,43 +65,66 @@ var (
 	historyMu sync.RWMutex
 )
 
-const maxHistory = 50
-
-// addToHistory adds a new text item to clipboard history
+// addToHistory adds a new text item copied to the CLIPBOARD selection
 func addToHistory(text string) {
+	addTextToHistory(text, SourceClipboard, nil, SourceApp{})
+}
+
+// addTextToHistory adds a new text item captured from the given selection,
+// along with any rich representations (HTML, RTF, ...) offered with it and
+// the application it came from.
+// Text that looks like a secret is marked sensitive, or not recorded at all
+// if the config says so. It returns the item as stored, and false if nothing
+// was recorded.
+func addTextToHistory(text string, source ClipboardSource, formats []ClipboardContent, app SourceApp) (ClipboardItem, bool) {
 	historyMu.Lock()
 	defer historyMu.Unlock()
 	
 	// Clean and validate the text
 	text = strings.TrimSpace(text)
 	if text == "" || !utf8.ValidString(text) {
-		return
+		return ClipboardItem{}, false
+	}
+	
+	sensitive := getConfig().Sensitive
+	reason := detectSensitive(text, formats, sensitive)
+	if reason != "" && sensitive.Action == sensitiveSkip {
+		fmt.Printf("🔒 Not recording sensitive text (%s)\n", reason)
+		return ClipboardItem{}, false
 	}
 	
 	// Create new text item
 	newItem := ClipboardItem{
-		Type:      ItemTypeText,
-		Content:   text,
-		Timestamp: time.Now(),
+		Type:        ItemTypeText,
+		Content:     text,
+		Timestamp:   time.Now(),
+		Source:      source,
+		Sensitive:   reason,
+		App:         app.Name,
+		AppClass:    app.Class,
+		WindowTitle: app.Title,
+		Formats:     formats,
 	}
 	
 	// Skip if it's the same as the last item
 	if len(history) > 0 && history[len(history)-1].Content == text {
-		return
+		return history[len(history)-1], true
 	}
 	
 	// Save to database
-	if err := saveClipboardItem(newItem); err != nil {
+	saved, expired, err := storeClipboardItem(newItem)
+	if err != nil {
 		fmt.Printf("Error saving text to database: %v\n", err)
-		return
+		return ClipboardItem{}, false
 	}
 	
 	// Update in-memory history
-	refreshHistoryFromDB()
+	appendToHistory(saved, expired)
+	return saved, true
 }
 
-// addImageToHistory adds a new image item to clipboard history
-func addImageToHistory(imageData []byte, format string) {
+// addImageToHistory adds a new image item copied from app to clipboard history
+func addImageToHistory(imageData []byte, format string, app SourceApp) {
 	historyMu.Lock()
 	defer historyMu.Unlock()
 	
@@ -122,48 +167,77 @@ func addImageToHistory(imageData []byte, format string) {
 		Size:   len(imageData),
 	}
 	
-	// Encode image data as base64
-	base64Data := base64.StdEncoding.EncodeToString(imageData)
-	
 	// Create new image item
 	newItem := ClipboardItem{
-		Type:      ItemTypeImage,
-		Content:   base64Data,
-		Timestamp: time.Now(),
-		ImageMeta: imageMeta,
+		Type:        ItemTypeImage,
+		Timestamp:   time.Now(),
+		ImageMeta:   imageMeta,
+		ImageData:   imageData,
+		ContentHash: contentHash(imageData),
+		Source:      SourceClipboard,
+		App:         app.Name,
+		AppClass:    app.Class,
+		WindowTitle: app.Title,
 	}
 	
-	// Skip if it's the same as the last item (compare base64 content)
-	if len(history) > 0 && history[len(history)-1].Content == base64Data {
+	// Skip if it's the same as the last item (compare content hashes)
+	if len(history) > 0 && history[len(history)-1].ContentHash == newItem.ContentHash {
 		return
 	}
 	
 	// Save to database
-	if err := saveClipboardItem(newItem); err != nil {
+	saved, expired, err := storeClipboardItem(newItem)
+	if err != nil {
 		fmt.Printf("Error saving image to database: %v\n", err)
 		return
 	}
 	
 	// Update in-memory history
-	refreshHistoryFromDB()
+	appendToHistory(saved, expired)
 }
 
-// editHistoryItem updates the content of a text item in history by index
-func editHistoryItem(index int, newContent string) error {
-	historyMu.Lock()
-	defer historyMu.Unlock()
-	
-	if index < 0 || index >= len(history) {
-		return fmt.Errorf("invalid index %d for history edit", index)
+// contentHash returns the hex SHA-256 of an item's content
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// itemContentHash returns the hash items are deduplicated by. Text is
+// normalized first so the same text copied with Windows line endings or
+// surrounding whitespace counts as one item.
+func itemContentHash(item ClipboardItem) string {
+	if item.Type == ItemTypeImage {
+		return contentHash(item.ImageData)
+	}
+	return contentHash([]byte(normalizeContent(item.Content)))
+}
+
+// normalizeContent returns text in the form its content hash is taken over
+func normalizeContent(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// decodeLegacyImage moves the base64 content of an image item from JSON
+// history or an old database row into ImageData
+func decodeLegacyImage(item *ClipboardItem) error {
+	if item.Type != ItemTypeImage || len(item.ImageData) > 0 {
+		return nil
 	}
 	
-	// Get the item to edit
-	item := history[index]
-	
-	// Only allow editing text items
-	if item.Type != ItemTypeText {
-		return fmt.Errorf("can only edit text items")
+	data, err := base64.StdEncoding.DecodeString(item.Content)
+	if err != nil {
+		return fmt.Errorf("failed to decode base64 image: %v", err)
 	}
+	item.ImageData = data
+	item.ContentHash = contentHash(data)
+	item.Content = ""
+	return nil
+}
+
+// editHistoryItem updates the content of a text item in history by its ID
+func editHistoryItem(id int64, newContent string) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
 	
 	// Clean and validate the new text
 	newContent = strings.TrimSpace(newContent)
@@ -175,56 +249,171 @@ func editHistoryItem(index int, newContent string) error {
 		return fmt.Errorf("new content is not valid UTF-8")
 	}
 	
-	// Update in database
-	if err := updateClipboardItem(item.Content, newContent, item.Type); err != nil {
+	// Update in database (only text items can be edited)
+	if err := updateClipboardItem(id, newContent); err != nil {
 		return fmt.Errorf("error updating item in database: %v", err)
 	}
 	
-	// Update in-memory history
-	refreshHistoryFromDB()
-	fmt.Printf("Updated history item at index %d\n", index)
+	// Update in-memory history. The edit bumps the timestamp, and any other
+	// item with the same text was merged into this one.
+	updated, err := getClipboardItem(id)
+	if err != nil {
+		refreshHistoryFromDB()
+	} else {
+		var merged []int64
+		for _, item := range history {
+			if item.Type == ItemTypeText && item.ContentHash == updated.ContentHash {
+				merged = append(merged, item.ID)
+			}
+		}
+		dropFromHistory(append(merged, id)...)
+		history = append(history, updated)
+	}
+	fmt.Printf("Updated history item %d\n", id)
 	
 	return nil
 }
 
-// Remove specific item from history by index
-func removeHistoryItem(index int) {
+// removeHistoryItem removes a specific item from history by its ID
+func removeHistoryItem(id int64) error {
 	historyMu.Lock()
 	defer historyMu.Unlock()
 	
-	if index < 0 || index >= len(history) {
-		fmt.Printf("Invalid index %d for history removal\n", index)
-		return
+	// Remove from database
+	if err := deleteClipboardItem(id); err != nil {
+		fmt.Printf("Error removing item from database: %v\n", err)
+		return err
 	}
 	
-	// Get the item to remove
-	item := history[index]
+	// Update in-memory history
+	dropFromHistory(id)
+	fmt.Printf("Removed history item %d\n", id)
+	return nil
+}
+
+// restoreHistoryItem writes the item with the given ID back to the system clipboard
+func restoreHistoryItem(id int64) error {
+	// Always read the current row so a stale UI snapshot can't restore the wrong entry
+	item, err := getClipboardItem(id)
+	if err != nil {
+		return err
+	}
 	
-	// Remove from database
-	if err := deleteClipboardItem(item.Content, item.Type); err != nil {
-		fmt.Printf("Error removing item from database: %v\n", err)
-		return
+	switch item.Type {
+	case ItemTypeText:
+		b, err := getClipboardBackend()
+		if err != nil {
+			return fmt.Errorf("error writing text to clipboard: %v", err)
+		}
+		if err := b.Write(textItemContents(item)); err != nil {
+			return fmt.Errorf("error writing text to clipboard: %v", err)
+		}
+		if item.Sensitive != "" {
+			fmt.Printf("Restored text to clipboard: %s\n", maskedText(item))
+		} else {
+			fmt.Printf("Restored text to clipboard: %.50s", item.Content)
+			if len(item.Content) > 50 {
+				fmt.Print("...")
+			}
+			fmt.Println()
+		}
+	case ItemTypeImage:
+		imageData := item.ImageData
+		if len(imageData) == 0 {
+			return fmt.Errorf("image %d has no data", item.ID)
+		}
+		
+		format := "png"
+		if item.ImageMeta != nil {
+			format = item.ImageMeta.Format
+		}
+		
+		if err := restoreImageToSystemClipboard(imageData, format); err != nil {
+			return fmt.Errorf("error restoring image to clipboard: %v", err)
+		}
+		fmt.Printf("Restored %s image to clipboard", strings.ToUpper(format))
+		if item.ImageMeta != nil {
+			fmt.Printf(" (%dx%d)", item.ImageMeta.Width, item.ImageMeta.Height)
+		}
+		fmt.Println()
+	case ItemTypeFiles:
+		b, err := getClipboardBackend()
+		if err != nil {
+			return fmt.Errorf("error restoring files to clipboard: %v", err)
+		}
+		paths := filePaths(item)
+		if err := b.Write(fileListContents(paths)); err != nil {
+			return fmt.Errorf("error restoring files to clipboard: %v", err)
+		}
+		fmt.Printf("Restored %s to clipboard\n", describeFileList(paths, 3))
+	default:
+		return fmt.Errorf("unsupported item type %q", item.Type)
+	}
+	
+	return nil
+}
+
+// setHistoryItemPinned pins or unpins a history item by its ID.
+// Pinned items are listed first and never removed by retention or clear.
+func setHistoryItemPinned(id int64, pinned bool) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	
+	if err := setClipboardItemPinned(id, pinned); err != nil {
+		return err
 	}
 	
 	// Update in-memory history
-	refreshHistoryFromDB()
-	fmt.Printf("Removed history item at index %d\n", index)
+	for i := range history {
+		if history[i].ID == id {
+			history[i].Pinned = pinned
+		}
+	}
+	if pinned {
+		fmt.Printf("Pinned history item %d\n", id)
+	} else {
+		fmt.Printf("Unpinned history item %d\n", id)
+	}
+	
+	return nil
 }
 
-// Clear all history with optional UI callback
+// Clear history with optional UI callback. Pinned items are kept.
 func clearHistory(onComplete ...func()) error {
+	return clearHistoryItems(false, onComplete)
+}
+
+// forceClearHistory clears all history including pinned items
+func forceClearHistory(onComplete ...func()) error {
+	return clearHistoryItems(true, onComplete)
+}
+
+// clearHistoryItems removes history from the database and memory, then runs the callbacks
+func clearHistoryItems(force bool, onComplete []func()) error {
 	historyMu.Lock()
 	defer historyMu.Unlock()
 	
 	// Clear from database
-	if err := clearClipboardHistory(); err != nil {
+	if err := clearClipboardHistory(force); err != nil {
 		fmt.Printf("Error clearing history from database: %v\n", err)
 		return err
 	}
 	
-	// Clear in-memory history
-	history = []ClipboardItem{}
-	fmt.Println("Clipboard history cleared.")
+	// Keep pinned items visible unless they were cleared too
+	kept := []ClipboardItem{}
+	if !force {
+		for _, item := range history {
+			if item.Pinned {
+				kept = append(kept, item)
+			}
+		}
+	}
+	history = kept
+	if len(history) > 0 {
+		fmt.Printf("Clipboard history cleared (%d pinned item(s) kept).\n", len(history))
+	} else {
+		fmt.Println("Clipboard history cleared.")
+	}
 	
 	// Call UI callback if provided
 	for _, callback := range onComplete {
@@ -270,6 +459,11 @@ func loadHistory() {
 		fmt.Printf("Warning: failed to migrate from JSON: %v\n", err)
 	}
 	
+	// Secrets past their TTL are gone even if no daemon was running to sweep them
+	if _, err := expireSensitiveItems(getConfig().Sensitive.TTL.Duration); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	
 	// Load history from database
 	refreshHistoryFromDB()
 }
@@ -292,6 +486,43 @@ func getHistoryCopy() []ClipboardItem {
 }
 
 
+// appendToHistory applies a saved item to in-memory history without reloading
+// the table. Content copied again keeps its row, which moves to the end, and
+// rows removed by retention are dropped. Image bytes aren't kept in memory;
+// they are loaded on demand with loadImageData. The caller must hold historyMu.
+func appendToHistory(item ClipboardItem, expired []int64) {
+	item.ImageData = nil
+	item.Formats = nil
+	
+	// Only an upsert into an existing row needs the old entry removed
+	if item.UseCount > 1 {
+		dropFromHistory(item.ID)
+	}
+	history = append(history, item)
+	dropFromHistory(expired...)
+}
+
+// dropFromHistory removes the items with the given IDs from in-memory
+// history. The caller must hold historyMu.
+func dropFromHistory(ids ...int64) {
+	if len(ids) == 0 {
+		return
+	}
+	
+	drop := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	
+	kept := history[:0]
+	for _, item := range history {
+		if !drop[item.ID] {
+			kept = append(kept, item)
+		}
+	}
+	history = kept
+}
+
 // refreshHistoryFromDB loads the current history from database into memory
 func refreshHistoryFromDB() {
 	loadedHistory, err := loadClipboardHistory()
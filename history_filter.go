@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// historyFilter holds the popup's search text and quick filter toggles
+type historyFilter struct {
+	query      string
+	showText   bool
+	showImages bool
+	showFiles  bool
+	pinnedOnly bool
+}
+
+// newHistoryFilter returns a filter that shows everything
+func newHistoryFilter() historyFilter {
+	return historyFilter{showText: true, showImages: true, showFiles: true}
+}
+
+// fuzzyMaxSpanFactor bounds how spread out a fuzzy match may be relative to
+// the query length, so short queries don't match every long item
+const fuzzyMaxSpanFactor = 3
+
+// fuzzyMaxRunes limits how much of an item is scanned for fuzzy matches
+const fuzzyMaxRunes = 4000
+
+// matchText reports whether query matches text, case-insensitively.
+// A substring match is preferred; otherwise the query's characters must appear
+// in order within a short window (fuzzy match). The returned rune positions
+// are the matched characters in text, suitable for highlighting.
+func matchText(query, text string) (positions []int, exact bool, ok bool) {
+	q := lowerRunes(query)
+	if len(q) == 0 {
+		return nil, true, true
+	}
+
+	t := lowerRunes(text)
+
+	// Substring match
+	if idx := indexRunes(t, q); idx >= 0 {
+		positions = make([]int, len(q))
+		for i := range q {
+			positions[i] = idx + i
+		}
+		return positions, true, true
+	}
+
+	// Fuzzy match: find the tightest in-order occurrence of the query runes
+	if len(t) > fuzzyMaxRunes {
+		t = t[:fuzzyMaxRunes]
+	}
+	maxSpan := len(q) * fuzzyMaxSpanFactor
+	bestSpan := -1
+
+	for start := range t {
+		if t[start] != q[0] {
+			continue
+		}
+		candidate := []int{start}
+		qi := 1
+		for ti := start + 1; ti < len(t) && qi < len(q) && ti-start < maxSpan; ti++ {
+			if t[ti] == q[qi] {
+				candidate = append(candidate, ti)
+				qi++
+			}
+		}
+		if qi < len(q) {
+			continue
+		}
+		span := candidate[len(candidate)-1] - start + 1
+		if bestSpan < 0 || span < bestSpan {
+			bestSpan = span
+			positions = candidate
+		}
+	}
+
+	if bestSpan < 0 {
+		return nil, false, false
+	}
+	return positions, false, true
+}
+
+// lowerRunes lowercases rune by rune so indexes still line up with the original text
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// indexRunes returns the index of the first occurrence of sub in s, or -1
+func indexRunes(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// searchableText returns the text a filter query is matched against
+func searchableText(item ClipboardItem) string {
+	if item.Type == ItemTypeImage {
+		if item.ImageMeta != nil {
+			return fmt.Sprintf("image %s %dx%d", item.ImageMeta.Format, item.ImageMeta.Width, item.ImageMeta.Height)
+		}
+		return "image"
+	}
+	// Matching a hidden secret would let it be guessed a character at a time
+	if item.Sensitive != "" {
+		return maskedText(item)
+	}
+	return item.Content
+}
+
+// filterHistory returns the items that pass the filter, keeping their order
+// but placing substring matches ahead of fuzzy ones
+func filterHistory(items []ClipboardItem, filter historyFilter) []ClipboardItem {
+	var exactMatches, fuzzyMatches []ClipboardItem
+	query := strings.TrimFunc(filter.query, unicode.IsSpace)
+
+	for _, item := range items {
+		if item.Type == ItemTypeText && !filter.showText {
+			continue
+		}
+		if item.Type == ItemTypeImage && !filter.showImages {
+			continue
+		}
+		if item.Type == ItemTypeFiles && !filter.showFiles {
+			continue
+		}
+		if filter.pinnedOnly && !item.Pinned {
+			continue
+		}
+
+		_, exact, ok := matchText(query, searchableText(item))
+		if !ok {
+			continue
+		}
+		if exact {
+			exactMatches = append(exactMatches, item)
+		} else {
+			fuzzyMatches = append(fuzzyMatches, item)
+		}
+	}
+
+	return append(exactMatches, fuzzyMatches...)
+}
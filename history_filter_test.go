@@ -0,0 +1,177 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchText(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		text      string
+		ok        bool
+		exact     bool
+		positions []int
+	}{
+		{
+			name:      "Empty query matches everything",
+			query:     "",
+			text:      "anything",
+			ok:        true,
+			exact:     true,
+			positions: nil,
+		},
+		{
+			name:      "Case-insensitive substring",
+			query:     "WORLD",
+			text:      "hello world",
+			ok:        true,
+			exact:     true,
+			positions: []int{6, 7, 8, 9, 10},
+		},
+		{
+			name:      "Fuzzy match",
+			query:     "hlwd",
+			text:      "hello world",
+			ok:        true,
+			exact:     false,
+			positions: []int{0, 2, 6, 10},
+		},
+		{
+			name:  "Characters out of order",
+			query: "dlrow",
+			text:  "hello world",
+			ok:    false,
+		},
+		{
+			name:  "Characters too far apart",
+			query: "ab",
+			text:  "a.......................b",
+			ok:    false,
+		},
+		{
+			name:      "Positions are rune indexes",
+			query:     "wö",
+			text:      "ääwörld",
+			ok:        true,
+			exact:     true,
+			positions: []int{2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions, exact, ok := matchText(tt.query, tt.text)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+			if !ok {
+				return
+			}
+			if exact != tt.exact {
+				t.Errorf("Expected exact=%v, got %v", tt.exact, exact)
+			}
+			if len(positions) != len(tt.positions) {
+				t.Fatalf("Expected positions %v, got %v", tt.positions, positions)
+			}
+			for i := range positions {
+				if positions[i] != tt.positions[i] {
+					t.Errorf("Expected positions %v, got %v", tt.positions, positions)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	items := []ClipboardItem{
+		{ID: 1, Type: ItemTypeText, Content: "git status", Timestamp: time.Now()},
+		{ID: 2, Type: ItemTypeText, Content: "go test ./...", Timestamp: time.Now(), Pinned: true},
+		{ID: 3, Type: ItemTypeImage, Content: "", Timestamp: time.Now(), ImageMeta: &ImageMetadata{Format: "png", Width: 10, Height: 10}},
+		{ID: 4, Type: ItemTypeText, Content: "grep -rn TODO", Timestamp: time.Now()},
+		{ID: 5, Type: ItemTypeText, Content: "hunter2hunter2", Timestamp: time.Now(), Sensitive: "password manager"},
+	}
+
+	tests := []struct {
+		name     string
+		filter   historyFilter
+		expected []int64
+	}{
+		{
+			name:     "No filter",
+			filter:   newHistoryFilter(),
+			expected: []int64{1, 2, 3, 4, 5},
+		},
+		{
+			name:     "Substring query",
+			filter:   historyFilter{query: "TODO", showText: true, showImages: true},
+			expected: []int64{4},
+		},
+		{
+			name:     "Fuzzy query",
+			filter:   historyFilter{query: "gst", showText: true, showImages: true},
+			expected: []int64{1, 2},
+		},
+		{
+			name:     "Text only",
+			filter:   historyFilter{showText: true},
+			expected: []int64{1, 2, 4, 5},
+		},
+		{
+			name:     "Images only",
+			filter:   historyFilter{showImages: true},
+			expected: []int64{3},
+		},
+		{
+			name:     "Image metadata is searchable",
+			filter:   historyFilter{query: "png", showText: true, showImages: true},
+			expected: []int64{3},
+		},
+		{
+			name:     "Hidden secrets are not searched",
+			filter:   historyFilter{query: "hunter", showText: true},
+			expected: nil,
+		},
+		{
+			name:     "Sensitive items match their label",
+			filter:   historyFilter{query: "password manager", showText: true},
+			expected: []int64{5},
+		},
+		{
+			name:     "Pinned only",
+			filter:   historyFilter{showText: true, showImages: true, pinnedOnly: true},
+			expected: []int64{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := filterHistory(items, tt.filter)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected IDs %v, got %d items", tt.expected, len(result))
+			}
+			for i, item := range result {
+				if item.ID != tt.expected[i] {
+					t.Errorf("Expected IDs %v, got ID %d at position %d", tt.expected, item.ID, i)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterHistoryRanksSubstringMatchesFirst(t *testing.T) {
+	items := []ClipboardItem{
+		{ID: 1, Type: ItemTypeText, Content: "s-t-a-t-u-s"},
+		{ID: 2, Type: ItemTypeText, Content: "git status"},
+	}
+
+	result := filterHistory(items, historyFilter{query: "status", showText: true})
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(result))
+	}
+	if result[0].ID != 2 || result[1].ID != 1 {
+		t.Errorf("Expected substring match first, got IDs %d, %d", result[0].ID, result[1].ID)
+	}
+}
@@ -1,12 +1,13 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
 	"image"
 	"image/color"
 	"image/jpeg"
 	"image/png"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"fyne.io/fyne/v2"
@@ -23,15 +24,20 @@ type HistoryListItem struct {
 	widget.BaseWidget
 	item     ClipboardItem
 	index    int
-	onDelete func(int)
-	onSelect func(int)
-	onEdit   func(int)
+	onDelete func(int64)
+	onSelect func(int64)
+	onEdit   func(int64)
+	onPin    func(int64)
+	
+	// Filter text whose matched characters are highlighted
+	highlight string
 	
 	// Internal widgets
 	textWidget     *widget.RichText
 	imageWidget    *canvas.Image
 	deleteButton   *widget.Button
 	editButton     *widget.Button
+	pinButton      *widget.Button
 	container      *fyne.Container
 	background     *canvas.Rectangle
 	
@@ -39,16 +45,21 @@ type HistoryListItem struct {
 	isHovered       bool
 	deleteHovered   bool
 	editHovered     bool
+	
+	// Keyboard selection in the popup
+	selected        bool
 }
 
-// NewHistoryListItem creates a new history list item widget
-func NewHistoryListItem(clipboardItem ClipboardItem, index int, onDelete func(int), onSelect func(int), onEdit func(int)) *HistoryListItem {
+// NewHistoryListItem creates a new history list item widget.
+// index is the display position; callbacks receive the item's database ID.
+func NewHistoryListItem(clipboardItem ClipboardItem, index int, onDelete func(int64), onSelect func(int64), onEdit func(int64), onPin func(int64)) *HistoryListItem {
 	item := &HistoryListItem{
 		item:     clipboardItem,
 		index:    index,
 		onDelete: onDelete,
 		onSelect: onSelect,
 		onEdit:   onEdit,
+		onPin:    onPin,
 	}
 	
 	item.ExtendBaseWidget(item)
@@ -65,7 +76,7 @@ func (h *HistoryListItem) createContent() {
 	// Create delete button with X icon and custom hover handling
 	h.deleteButton = widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
 		if h.onDelete != nil {
-			h.onDelete(h.index)
+			h.onDelete(h.item.ID)
 		}
 	})
 	
@@ -73,19 +84,31 @@ func (h *HistoryListItem) createContent() {
 	h.deleteButton.Resize(fyne.NewSize(28, 28)) // Slightly larger for better touch targets
 	h.deleteButton.Importance = widget.LowImportance
 	
+	// Create pin toggle; pinned items show it highlighted
+	h.pinButton = widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+		if h.onPin != nil {
+			h.onPin(h.item.ID)
+		}
+	})
+	h.pinButton.Resize(fyne.NewSize(28, 28))
+	h.pinButton.Importance = widget.LowImportance
+	if h.item.Pinned {
+		h.pinButton.Importance = widget.HighImportance
+	}
+	
 	// Create edit button for text items only
 	var buttonContainer *fyne.Container
 	if h.item.Type == ItemTypeText {
 		h.editButton = widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() {
 			if h.onEdit != nil {
-				h.onEdit(h.index)
+				h.onEdit(h.item.ID)
 			}
 		})
 		h.editButton.Resize(fyne.NewSize(28, 28))
 		h.editButton.Importance = widget.LowImportance
-		buttonContainer = container.NewHBox(h.editButton, h.deleteButton)
+		buttonContainer = container.NewHBox(h.pinButton, h.editButton, h.deleteButton)
 	} else {
-		buttonContainer = container.NewHBox(h.deleteButton)
+		buttonContainer = container.NewHBox(h.pinButton, h.deleteButton)
 	}
 	
 	var contentWidget fyne.CanvasObject
@@ -101,13 +124,12 @@ func (h *HistoryListItem) createContent() {
 		h.textWidget.Resize(fyne.NewSize(0, maxHeight))
 		
 		// Set the text content with proper formatting
-		displayText := h.prepareDisplayText()
-		h.textWidget.ParseMarkdown(displayText)
+		h.renderText()
 		
 		contentWidget = h.textWidget
 	} else if h.item.Type == ItemTypeImage {
 		// Create image preview widget
-		if img, err := h.createImageFromBase64(); err == nil {
+		if img, err := h.createImageFromData(); err == nil {
 			h.imageWidget = canvas.NewImageFromImage(img)
 			h.imageWidget.FillMode = canvas.ImageFillContain
 			h.imageWidget.SetMinSize(fyne.NewSize(100, 60))
@@ -130,6 +152,16 @@ func (h *HistoryListItem) createContent() {
 			h.textWidget.ParseMarkdown(fmt.Sprintf("**[IMAGE ERROR]** %v", err))
 			contentWidget = h.textWidget
 		}
+	} else if h.item.Type == ItemTypeFiles {
+		contentWidget = h.createFileListContent()
+	}
+	
+	// Items are tagged with where they came from when it is known
+	if source := h.sourceLabelText(); source != "" {
+		sourceLabel := widget.NewLabel(source)
+		sourceLabel.TextStyle = fyne.TextStyle{Italic: true}
+		sourceLabel.Truncation = fyne.TextTruncateEllipsis
+		contentWidget = container.NewBorder(nil, sourceLabel, nil, nil, contentWidget)
 	}
 	
 	// Create content container with content and action buttons
@@ -151,8 +183,84 @@ func (h *HistoryListItem) createContent() {
 	h.updateHoverState()
 }
 
+// sourceLabelText describes where the item came from: middle-click
+// selections are told apart from copies, followed by the application and
+// window title
+func (h *HistoryListItem) sourceLabelText() string {
+	var parts []string
+	if h.item.Source == SourcePrimary {
+		parts = append(parts, "selection")
+	}
+	if app := describeSource(h.item); app != "" {
+		parts = append(parts, app)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// createFileListContent lists copied files with a file or folder icon each,
+// marks files that no longer exist, and shows the count and total size
+func (h *HistoryListItem) createFileListContent() fyne.CanvasObject {
+	paths := filePaths(h.item)
+	info := statFileList(paths)
+	
+	const maxFileRows = 4
+	rows := container.NewVBox()
+	for i, path := range paths {
+		if i == maxFileRows {
+			rows.Add(widget.NewLabel(fmt.Sprintf("… and %d more", len(paths)-maxFileRows)))
+			break
+		}
+		
+		icon := theme.FileIcon()
+		name := widget.NewLabel(filepath.Base(path))
+		name.Truncation = fyne.TextTruncateEllipsis
+		
+		stat, err := os.Stat(path)
+		switch {
+		case err != nil:
+			icon = theme.ErrorIcon()
+			name.SetText(filepath.Base(path) + " (missing)")
+			name.Importance = widget.LowImportance
+		case stat.IsDir():
+			icon = theme.FolderIcon()
+		}
+		
+		rows.Add(container.NewBorder(nil, nil, widget.NewIcon(icon), nil, name))
+	}
+	
+	summary := fileCount(len(paths)) + " · " + formatFileSize(info.size)
+	if info.partial {
+		summary += "+"
+	}
+	if len(info.missing) > 0 {
+		summary += fmt.Sprintf(" · %d missing", len(info.missing))
+	}
+	summaryLabel := widget.NewLabel(summary)
+	summaryLabel.TextStyle = fyne.TextStyle{Italic: true}
+	
+	return container.NewBorder(nil, summaryLabel, nil, nil, rows)
+}
+
 // prepareDisplayText formats the text for display with proper wrapping and truncation
 func (h *HistoryListItem) prepareDisplayText() string {
+	text := h.plainDisplayText()
+	
+	// Escape markdown special characters to prevent formatting issues
+	text = strings.ReplaceAll(text, "*", "\\*")
+	text = strings.ReplaceAll(text, "_", "\\_")
+	text = strings.ReplaceAll(text, "#", "\\#")
+	text = strings.ReplaceAll(text, "[", "\\[")
+	text = strings.ReplaceAll(text, "]", "\\]")
+	
+	return text
+}
+
+// plainDisplayText returns the truncated display text before markdown escaping
+func (h *HistoryListItem) plainDisplayText() string {
+	if h.item.Sensitive != "" {
+		return maskedText(h.item)
+	}
+	
 	text := strings.TrimSpace(h.item.Content)
 	
 	// Replace tabs with spaces for better display
@@ -180,22 +288,74 @@ func (h *HistoryListItem) prepareDisplayText() string {
 		text = strings.Join(lines[:maxLines], "\n") + "\n..."
 	}
 	
-	// Escape markdown special characters to prevent formatting issues
-	text = strings.ReplaceAll(text, "*", "\\*")
-	text = strings.ReplaceAll(text, "_", "\\_")
-	text = strings.ReplaceAll(text, "#", "\\#")
-	text = strings.ReplaceAll(text, "[", "\\[")
-	text = strings.ReplaceAll(text, "]", "\\]")
-	
 	return text
 }
 
-// createImageFromBase64 creates an image from base64 data
-func (h *HistoryListItem) createImageFromBase64() (image.Image, error) {
-	// Decode base64 data
-	imageData, err := base64.StdEncoding.DecodeString(h.item.Content)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode base64: %v", err)
+// renderText fills the text widget, highlighting characters that match the filter
+func (h *HistoryListItem) renderText() {
+	if h.highlight == "" {
+		h.textWidget.ParseMarkdown(h.prepareDisplayText())
+		return
+	}
+	
+	h.textWidget.Segments = highlightSegments(h.plainDisplayText(), h.highlight)
+	h.textWidget.Refresh()
+}
+
+// highlightSegments splits text into plain and emphasised runs for the matched characters
+func highlightSegments(text, query string) []widget.RichTextSegment {
+	positions, _, ok := matchText(query, text)
+	if !ok || len(positions) == 0 {
+		return []widget.RichTextSegment{&widget.TextSegment{Text: text, Style: widget.RichTextStyleInline}}
+	}
+	
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+	
+	highlightStyle := widget.RichTextStyleStrong
+	highlightStyle.ColorName = theme.ColorNamePrimary
+	
+	var segments []widget.RichTextSegment
+	runes := []rune(text)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && matched[i] == matched[start] {
+			continue
+		}
+		style := widget.RichTextStyleInline
+		if matched[start] {
+			style = highlightStyle
+		}
+		segments = append(segments, &widget.TextSegment{Text: string(runes[start:i]), Style: style})
+		start = i
+	}
+	
+	return segments
+}
+
+// SetHighlight sets the filter text whose matches are emphasised
+func (h *HistoryListItem) SetHighlight(query string) {
+	h.highlight = strings.TrimSpace(query)
+	if h.textWidget != nil && h.item.Type == ItemTypeText {
+		h.renderText()
+	}
+}
+
+// createImageFromData decodes the item's image bytes, loading them from the
+// database on first use since in-memory history doesn't hold them
+func (h *HistoryListItem) createImageFromData() (image.Image, error) {
+	if len(h.item.ImageData) == 0 {
+		data, err := loadImageData(h.item.ID)
+		if err != nil {
+			return nil, err
+		}
+		h.item.ImageData = data
+	}
+	imageData := h.item.ImageData
+	if len(imageData) == 0 {
+		return nil, fmt.Errorf("image has no data")
 	}
 	
 	// Create a reader from the image data
@@ -203,6 +363,7 @@ func (h *HistoryListItem) createImageFromBase64() (image.Image, error) {
 	
 	// Try to decode based on the format
 	var img image.Image
+	var err error
 	if h.item.ImageMeta != nil {
 		switch h.item.ImageMeta.Format {
 		case "png":
@@ -286,6 +447,11 @@ func (h *HistoryListItem) updateHoverState() {
 		h.background.FillColor = itemHover
 		h.background.StrokeColor = theme.PrimaryColor()
 		h.background.StrokeWidth = 1
+	} else if h.selected {
+		// Item has keyboard focus - same colors as hover with a heavier outline
+		h.background.FillColor = itemHover
+		h.background.StrokeColor = theme.PrimaryColor()
+		h.background.StrokeWidth = 2
 	} else if h.isHovered {
 		// Item is hovered - use item hover color
 		h.background.FillColor = itemHover
@@ -318,6 +484,15 @@ func (h *HistoryListItem) updateHoverState() {
 	h.deleteButton.Refresh()
 }
 
+// SetSelected marks the item as the keyboard selection
+func (h *HistoryListItem) SetSelected(selected bool) {
+	if h.selected == selected {
+		return
+	}
+	h.selected = selected
+	h.updateHoverState()
+}
+
 // CreateRenderer creates the renderer for this widget
 func (h *HistoryListItem) CreateRenderer() fyne.WidgetRenderer {
 	return &historyListItemRenderer{
@@ -329,7 +504,7 @@ func (h *HistoryListItem) CreateRenderer() fyne.WidgetRenderer {
 // Tapped handles tap events for item selection
 func (h *HistoryListItem) Tapped(*fyne.PointEvent) {
 	if h.onSelect != nil {
-		h.onSelect(h.index)
+		h.onSelect(h.item.ID)
 	}
 }
 
@@ -453,6 +628,9 @@ func (r *historyListItemRenderer) Refresh() {
 	if r.item.editButton != nil {
 		r.item.editButton.Refresh()
 	}
+	if r.item.pinButton != nil {
+		r.item.pinButton.Refresh()
+	}
 	r.container.Refresh()
 }
 
@@ -468,4 +646,5 @@ func (r *historyListItemRenderer) Destroy() {
 	r.item.textWidget = nil
 	r.item.deleteButton = nil
 	r.item.editButton = nil
+	r.item.pinButton = nil
 }
\ No newline at end of file
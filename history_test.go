@@ -54,7 +54,7 @@ func TestRemoveHistoryItem(t *testing.T) {
 			clearTestHistory(t)
 			addTestItems(t, testItems)
 			
-			removeHistoryItem(tt.index)
+			removeHistoryItem(getTestHistoryItem(tt.index).ID)
 			
 			actualLen := getTestHistoryLength()
 			if actualLen != tt.expectedLen {
@@ -75,7 +75,7 @@ func TestRemoveHistoryItem(t *testing.T) {
 	}
 }
 
-func TestRemoveHistoryItemInvalidIndex(t *testing.T) {
+func TestRemoveHistoryItemInvalidID(t *testing.T) {
 	// Setup test database
 	setupTestDB(t)
 	defer teardownTestDB(t)
@@ -90,20 +90,20 @@ func TestRemoveHistoryItemInvalidIndex(t *testing.T) {
 	originalLen := getTestHistoryLength()
 	
 	tests := []struct {
-		name  string
-		index int
+		name string
+		id   int64
 	}{
 		{
-			name:  "Negative index",
-			index: -1,
+			name: "Negative ID",
+			id:   -1,
 		},
 		{
-			name:  "Index too large",
-			index: 5,
+			name: "Zero ID",
+			id:   0,
 		},
 		{
-			name:  "Index equal to length",
-			index: 3,
+			name: "Nonexistent ID",
+			id:   9999,
 		},
 	}
 	
@@ -113,7 +113,7 @@ func TestRemoveHistoryItemInvalidIndex(t *testing.T) {
 			clearTestHistory(t)
 			addTestItems(t, testItems)
 			
-			removeHistoryItem(tt.index)
+			removeHistoryItem(tt.id)
 			
 			// History should remain unchanged
 			actualLen := getTestHistoryLength()
@@ -124,6 +124,123 @@ func TestRemoveHistoryItemInvalidIndex(t *testing.T) {
 	}
 }
 
+func TestRemoveHistoryItemAfterConcurrentInsert(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	
+	testItems := []ClipboardItem{
+		{Type: ItemTypeText, Content: "item1", Timestamp: time.Now()},
+		{Type: ItemTypeText, Content: "item2", Timestamp: time.Now()},
+	}
+	addTestItems(t, testItems)
+	
+	// Take a UI snapshot, then let another writer insert a row
+	snapshot := getHistoryCopy()
+	target := snapshot[0]
+	addToHistory("inserted by daemon")
+	
+	removeHistoryItem(target.ID)
+	
+	if getTestHistoryLength() != 2 {
+		t.Fatalf("Expected 2 items after removal, got %d", getTestHistoryLength())
+	}
+	for _, item := range getHistoryCopy() {
+		if item.ID == target.ID || item.Content == target.Content {
+			t.Errorf("Expected %q to be removed, but it is still present", target.Content)
+		}
+	}
+}
+
+func TestEditHistoryItem(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	
+	testItems := []ClipboardItem{
+		{Type: ItemTypeText, Content: "item1", Timestamp: time.Now()},
+		{Type: ItemTypeText, Content: "item2", Timestamp: time.Now()},
+	}
+	addTestItems(t, testItems)
+	
+	target := getTestHistoryItem(0)
+	if err := editHistoryItem(target.ID, "  edited  "); err != nil {
+		t.Fatalf("editHistoryItem() failed: %v", err)
+	}
+	
+	edited, err := getClipboardItem(target.ID)
+	if err != nil {
+		t.Fatalf("getClipboardItem() failed: %v", err)
+	}
+	if edited.Content != "edited" {
+		t.Errorf("Expected edited content %q, got %q", "edited", edited.Content)
+	}
+	
+	if err := editHistoryItem(target.ID, "   "); err == nil {
+		t.Error("Expected error when editing with empty content")
+	}
+	if err := editHistoryItem(9999, "missing"); err == nil {
+		t.Error("Expected error when editing a nonexistent ID")
+	}
+}
+
+func TestRecopyBumpsUseCount(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	
+	addToHistory("repeated")
+	first := getTestHistoryItem(0)
+	addToHistory("other")
+	
+	// Same text with Windows line endings and padding is the same item
+	addTextToHistory("  repeated\r\n", SourceClipboard, []ClipboardContent{{MimeType: "text/html", Data: []byte("<b>repeated</b>")}}, SourceApp{})
+	
+	if length := getTestHistoryLength(); length != 2 {
+		t.Fatalf("Expected 2 items, got %d", length)
+	}
+	latest := getTestHistoryItem(1)
+	if latest.ID != first.ID || latest.Content != "repeated" {
+		t.Errorf("Expected the original row to move to the end, got %+v", latest)
+	}
+	if latest.UseCount != 2 || !latest.Timestamp.After(first.Timestamp) {
+		t.Errorf("Expected use count 2 and a newer timestamp, got %d and %v", latest.UseCount, latest.Timestamp)
+	}
+	if formats, _ := loadClipboardFormats(latest.ID); len(formats) != 1 {
+		t.Errorf("Expected the representations of the latest copy, got %+v", formats)
+	}
+	
+	var rows int
+	db.QueryRow("SELECT COUNT(*) FROM clipboard_history WHERE content_hash IS NULL").Scan(&rows)
+	if rows != 0 {
+		t.Errorf("Expected every saved item to be hashed, got %d without a hash", rows)
+	}
+}
+
+func TestEditHistoryItemMergesDuplicate(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	
+	addToHistory("keep me")
+	pinned := getTestHistoryItem(0)
+	if err := setHistoryItemPinned(pinned.ID, true); err != nil {
+		t.Fatalf("setHistoryItemPinned() failed: %v", err)
+	}
+	addToHistory("typo")
+	target := getTestHistoryItem(1)
+	
+	if err := editHistoryItem(target.ID, "keep me"); err != nil {
+		t.Fatalf("editHistoryItem() failed: %v", err)
+	}
+	
+	if length := getTestHistoryLength(); length != 1 {
+		t.Fatalf("Expected the duplicate to be merged, got %d items", length)
+	}
+	merged := getTestHistoryItem(0)
+	if merged.ID != target.ID || !merged.Pinned || merged.UseCount != 2 {
+		t.Errorf("Expected the edited item to take over the pin and uses, got %+v", merged)
+	}
+}
+
 func TestClearHistory(t *testing.T) {
 	// Setup test database
 	setupTestDB(t)
@@ -243,22 +360,26 @@ func TestConcurrentAccess(t *testing.T) {
 	var wg sync.WaitGroup
 	numGoroutines := 5
 	
+	var ids []int64
+	for i := 0; i < numGoroutines; i++ {
+		ids = append(ids, getTestHistoryItem(i).ID)
+	}
+	
 	// Test concurrent deletion
 	wg.Add(numGoroutines)
-	for i := 0; i < numGoroutines; i++ {
-		go func(index int) {
+	for _, id := range ids {
+		go func(id int64) {
 			defer wg.Done()
-			// Try to remove items, some may fail due to invalid indices
-			removeHistoryItem(index)
-		}(i)
+			removeHistoryItem(id)
+		}(id)
 	}
 	
 	wg.Wait()
 	
-	// History should still be valid (no panics or corruption)
+	// Exactly the targeted items should be gone
 	currentLen := getTestHistoryLength()
-	if currentLen < 0 || currentLen > 10 {
-		t.Errorf("History length after concurrent deletion is invalid: %d", currentLen)
+	if currentLen != 10-numGoroutines {
+		t.Errorf("Expected %d items after concurrent deletion, got %d", 10-numGoroutines, currentLen)
 	}
 }
 
@@ -278,6 +399,11 @@ func TestConcurrentClearAndRemove(t *testing.T) {
 	}
 	addTestItems(t, testItems)
 	
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, getTestHistoryItem(i).ID)
+	}
+	
 	var wg sync.WaitGroup
 	
 	// Start concurrent operations
@@ -295,8 +421,8 @@ func TestConcurrentClearAndRemove(t *testing.T) {
 	// Goroutine 2: Try to remove items
 	go func() {
 		defer wg.Done()
-		for i := 0; i < 5; i++ {
-			removeHistoryItem(i)
+		for _, id := range ids {
+			removeHistoryItem(id)
 			time.Sleep(5 * time.Millisecond)
 		}
 	}()
@@ -310,6 +436,174 @@ func TestConcurrentClearAndRemove(t *testing.T) {
 	}
 }
 
+// assertHistoryMatchesDB checks that incremental updates left in-memory history
+// identical to a full reload, apart from image bytes which are loaded lazily
+func assertHistoryMatchesDB(t *testing.T, step string) {
+	t.Helper()
+	
+	stored, err := loadClipboardHistory()
+	if err != nil {
+		t.Fatalf("%s: loadClipboardHistory() failed: %v", step, err)
+	}
+	memory := getHistoryCopy()
+	if len(memory) != len(stored) {
+		t.Fatalf("%s: expected %d items in memory, got %d", step, len(stored), len(memory))
+	}
+	for i := range stored {
+		got, want := memory[i], stored[i]
+		if got.ID != want.ID || got.Content != want.Content || got.Pinned != want.Pinned || got.UseCount != want.UseCount || got.ImageData != nil {
+			t.Errorf("%s: item %d is %+v in memory, %+v in the database", step, i, got, want)
+		}
+	}
+}
+
+func TestIncrementalHistoryUpdates(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	
+	imageData, err := createTestImage()
+	if err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+	
+	addToHistory("first")
+	addImageToHistory(imageData, "png", SourceApp{})
+	addFilesToHistory([]string{"/tmp/a.txt"}, SourceApp{})
+	addToHistory("second")
+	assertHistoryMatchesDB(t, "add")
+	
+	addToHistory("first")
+	assertHistoryMatchesDB(t, "re-copy")
+	
+	second := getTestHistoryItem(2)
+	if err := setHistoryItemPinned(second.ID, true); err != nil {
+		t.Fatalf("setHistoryItemPinned() failed: %v", err)
+	}
+	assertHistoryMatchesDB(t, "pin")
+	
+	if err := editHistoryItem(second.ID, "first"); err != nil {
+		t.Fatalf("editHistoryItem() failed: %v", err)
+	}
+	assertHistoryMatchesDB(t, "edit into duplicate")
+	
+	removeHistoryItem(getTestHistoryItem(0).ID)
+	assertHistoryMatchesDB(t, "remove")
+	
+	// Retention triggered by a capture drops the oldest unpinned text
+	configMu.Lock()
+	saved := config
+	config.Retention.Text.MaxItems = 1
+	configMu.Unlock()
+	defer func() {
+		configMu.Lock()
+		config = saved
+		configMu.Unlock()
+	}()
+	addToHistory("third")
+	addToHistory("fourth")
+	assertHistoryMatchesDB(t, "retention")
+	
+	if err := clearHistory(); err != nil {
+		t.Fatalf("clearHistory() failed: %v", err)
+	}
+	assertHistoryMatchesDB(t, "clear")
+	if getTestHistoryLength() != 1 {
+		t.Errorf("Expected the pinned item to survive clear, got %d items", getTestHistoryLength())
+	}
+}
+
+// seedHistory inserts n text items directly and loads them into memory
+func seedHistory(b *testing.B, n int) {
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatalf("Failed to begin: %v", err)
+	}
+	start := time.Now().Add(-time.Duration(n) * time.Second)
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("seeded item %d", i)
+		_, err := tx.Exec("INSERT INTO clipboard_history (type, content, timestamp, content_hash) VALUES ('text', ?, ?, ?)",
+			content, start.Add(time.Duration(i)*time.Second), contentHash([]byte(content)))
+		if err != nil {
+			b.Fatalf("Failed to seed history: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatalf("Failed to commit: %v", err)
+	}
+	
+	historyMu.Lock()
+	refreshHistoryFromDB()
+	historyMu.Unlock()
+}
+
+// useRetentionLimits sets the text retention limits for the rest of the benchmark
+func useRetentionLimits(b *testing.B, limits RetentionLimits) {
+	configMu.Lock()
+	saved := config
+	config.Retention = RetentionPolicy{Text: limits}
+	configMu.Unlock()
+	b.Cleanup(func() {
+		configMu.Lock()
+		config = saved
+		configMu.Unlock()
+	})
+}
+
+// retentionBenchmarks are the retention limits captures are benchmarked
+// under. The history is capped at its seeded size, so every new capture
+// evicts the oldest item, as it does in normal use once history is full.
+var retentionBenchmarks = []struct {
+	name   string
+	limits func(size int) RetentionLimits
+}{
+	{"max_items", func(size int) RetentionLimits {
+		return RetentionLimits{MaxItems: size}
+	}},
+	{"max_items+max_age", func(size int) RetentionLimits {
+		return RetentionLimits{MaxItems: size, MaxAge: Duration{30 * 24 * time.Hour}}
+	}},
+}
+
+// BenchmarkAddToHistory captures new text into histories of increasing size
+// with retention on. The time per capture should stay flat as the history grows.
+func BenchmarkAddToHistory(b *testing.B) {
+	for _, retention := range retentionBenchmarks {
+		for _, size := range []int{100, 1000, 10000} {
+			b.Run(fmt.Sprintf("%s/history=%d", retention.name, size), func(b *testing.B) {
+				useRetentionLimits(b, retention.limits(size))
+				setupTestDB(b)
+				defer teardownTestDB(b)
+				seedHistory(b, size)
+				
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					addToHistory(fmt.Sprintf("benchmark copy %d", i))
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkRecopyHistoryItem copies an existing item again, which moves it to
+// the end of history instead of adding a row
+func BenchmarkRecopyHistoryItem(b *testing.B) {
+	for _, retention := range retentionBenchmarks {
+		for _, size := range []int{100, 1000, 10000} {
+			b.Run(fmt.Sprintf("%s/history=%d", retention.name, size), func(b *testing.B) {
+				useRetentionLimits(b, retention.limits(size))
+				setupTestDB(b)
+				defer teardownTestDB(b)
+				seedHistory(b, size)
+				
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					addToHistory(fmt.Sprintf("seeded item %d", i%2))
+				}
+			})
+		}
+	}
+}
+
 // Setup and teardown for tests
 func TestMain(m *testing.M) {
 	// Save original history file path to restore later
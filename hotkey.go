@@ -5,12 +5,111 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
+// HotkeySettings is the [hotkey] section of the config file
+type HotkeySettings struct {
+	Binding string `toml:"binding"` // Shortcut that opens the popup, e.g. "Ctrl+Shift+V"
+}
+
+// defaultHotkeySettings opens the popup with Ctrl+Shift+V
+func defaultHotkeySettings() HotkeySettings {
+	return HotkeySettings{Binding: "Ctrl+Shift+V"}
+}
+
+// validate checks the binding can be registered
+func (h HotkeySettings) validate() error {
+	_, err := parseHotkey(h.Binding)
+	return err
+}
+
+// hotkeyModifiers maps accepted modifier names to their canonical name and
+// GNOME accelerator
+var hotkeyModifiers = map[string][2]string{
+	"ctrl":    {"Ctrl", "<Primary>"},
+	"control": {"Ctrl", "<Primary>"},
+	"shift":   {"Shift", "<Shift>"},
+	"alt":     {"Alt", "<Alt>"},
+	"super":   {"Meta", "<Super>"},
+	"meta":    {"Meta", "<Super>"},
+}
+
+// hotkeyBinding is a parsed shortcut
+type hotkeyBinding struct {
+	modifiers [][2]string // Canonical name and GNOME accelerator of each modifier
+	key       string      // Key name as written, e.g. "V" or "F12"
+}
+
+// parseHotkey parses a shortcut such as "Ctrl+Shift+V" or "Super+F12"
+func parseHotkey(binding string) (hotkeyBinding, error) {
+	var hk hotkeyBinding
+	parts := strings.Split(binding, "+")
+	if strings.TrimSpace(binding) == "" || len(parts) < 2 {
+		return hk, fmt.Errorf("invalid hotkey %q (expected modifiers and a key, e.g. Ctrl+Shift+V)", binding)
+	}
+	
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := hotkeyModifiers[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return hk, fmt.Errorf("invalid hotkey %q: unknown modifier %q (expected Ctrl, Shift, Alt or Super)", binding, part)
+		}
+		hk.modifiers = append(hk.modifiers, mod)
+	}
+	
+	key := strings.TrimSpace(parts[len(parts)-1])
+	isLetterOrDigit := len(key) == 1 && (unicode.IsLetter(rune(key[0])) || unicode.IsDigit(rune(key[0])))
+	isFunctionKey := false
+	if len(key) >= 2 && (key[0] == 'F' || key[0] == 'f') {
+		n, err := strconv.Atoi(key[1:])
+		isFunctionKey = err == nil && n >= 1 && n <= 24
+	}
+	if !isLetterOrDigit && !isFunctionKey {
+		return hk, fmt.Errorf("invalid hotkey %q: key %q must be a letter, digit or F1-F24", binding, key)
+	}
+	hk.key = strings.ToUpper(key)
+	return hk, nil
+}
+
+// String returns the binding as KDE and the help text write it, e.g. "Ctrl+Shift+V"
+func (hk hotkeyBinding) String() string {
+	names := make([]string, 0, len(hk.modifiers)+1)
+	for _, mod := range hk.modifiers {
+		names = append(names, mod[0])
+	}
+	return strings.Join(append(names, hk.key), "+")
+}
+
+// gnome returns the binding as a GNOME accelerator, e.g. "<Primary><Shift>v"
+func (hk hotkeyBinding) gnome() string {
+	var b strings.Builder
+	for _, mod := range hk.modifiers {
+		b.WriteString(mod[1])
+	}
+	if len(hk.key) == 1 {
+		b.WriteString(strings.ToLower(hk.key))
+	} else {
+		b.WriteString(hk.key)
+	}
+	return b.String()
+}
+
+// configuredHotkey returns the hotkey from the config file
+func configuredHotkey() hotkeyBinding {
+	hk, err := parseHotkey(getConfig().Hotkey.Binding)
+	if err != nil {
+		hk, _ = parseHotkey(defaultHotkeySettings().Binding)
+	}
+	return hk
+}
+
 // Setup system-level hotkey using gsettings (GNOME) or other methods
 func setupLinuxHotkeys() {
+	hotkey := configuredHotkey()
 	fmt.Println("Setting up system hotkey integration...")
-	fmt.Println("This will configure Ctrl+Shift+V to open the clipboard GUI.")
+	fmt.Printf("This will configure %s to open the clipboard GUI.\n", hotkey)
 	
 	// Get the absolute path to the current executable
 	execPath, err := os.Executable()
@@ -19,13 +118,31 @@ func setupLinuxHotkeys() {
 		execPath = "./clipboard-manager"
 	}
 	
+	registerHotkey(execPath, hotkey)
+	
+	// Keep the application running
+	fmt.Println("Clipboard manager is running in background. Press Ctrl+C to stop.")
+	fmt.Printf("The GUI will only open when you press %s or run 'clipboard-manager show'\n", hotkey)
+	
+	// Register the shortcut again whenever it is changed in the config file
+	for {
+		<-configReloaded()
+		if next := configuredHotkey(); next.String() != hotkey.String() {
+			hotkey = next
+			registerHotkey(execPath, hotkey)
+		}
+	}
+}
+
+// registerHotkey binds hotkey to 'show' in the desktop's keyboard settings
+func registerHotkey(execPath string, hotkey hotkeyBinding) {
 	// Try to set up GNOME hotkey
-	if setupGnomeHotkey(execPath) {
-		fmt.Println("✓ GNOME hotkey configured: Ctrl+Shift+V")
-		fmt.Println("  Press Ctrl+Shift+V from anywhere to open clipboard history")
-	} else if setupKDEHotkey(execPath) {
-		fmt.Println("✓ KDE hotkey configured: Ctrl+Shift+V")
-		fmt.Println("  Press Ctrl+Shift+V from anywhere to open clipboard history")
+	if setupGnomeHotkey(execPath, hotkey) {
+		fmt.Printf("✓ GNOME hotkey configured: %s\n", hotkey)
+		fmt.Printf("  Press %s from anywhere to open clipboard history\n", hotkey)
+	} else if setupKDEHotkey(execPath, hotkey) {
+		fmt.Printf("✓ KDE hotkey configured: %s\n", hotkey)
+		fmt.Printf("  Press %s from anywhere to open clipboard history\n", hotkey)
 	} else {
 		fmt.Println("⚠ Could not configure system hotkey automatically")
 		fmt.Println("Manual setup instructions:")
@@ -33,17 +150,12 @@ func setupLinuxHotkeys() {
 		fmt.Println("2. Go to Keyboard Shortcuts")
 		fmt.Println("3. Add a custom shortcut:")
 		fmt.Printf("   Command: %s show\n", execPath)
-		fmt.Println("   Shortcut: Ctrl+Shift+V")
+		fmt.Printf("   Shortcut: %s\n", hotkey)
 	}
-	
-	// Keep the application running
-	fmt.Println("Clipboard manager is running in background. Press Ctrl+C to stop.")
-	fmt.Println("The GUI will only open when you press Ctrl+Shift+V or run 'clipboard-manager show'")
-	select {} // Block forever
 }
 
 // Setup GNOME hotkey using gsettings
-func setupGnomeHotkey(execPath string) bool {
+func setupGnomeHotkey(execPath string, hotkey hotkeyBinding) bool {
 	// Check if gsettings is available
 	if _, err := exec.LookPath("gsettings"); err != nil {
 		return false
@@ -57,7 +169,7 @@ func setupGnomeHotkey(execPath string) bool {
 		{"gsettings", "set", schemaPath, "custom-keybindings", "['" + customPath + "']"},
 		{"gsettings", "set", schemaPath + ".custom-keybinding:" + customPath, "name", "Clipboard Manager"},
 		{"gsettings", "set", schemaPath + ".custom-keybinding:" + customPath, "command", execPath + " show"},
-		{"gsettings", "set", schemaPath + ".custom-keybinding:" + customPath, "binding", "<Primary><Shift>v"},
+		{"gsettings", "set", schemaPath + ".custom-keybinding:" + customPath, "binding", hotkey.gnome()},
 	}
 	
 	for _, cmd := range commands {
@@ -71,7 +183,7 @@ func setupGnomeHotkey(execPath string) bool {
 }
 
 // Setup KDE hotkey using kwriteconfig5
-func setupKDEHotkey(execPath string) bool {
+func setupKDEHotkey(execPath string, hotkey hotkeyBinding) bool {
 	// Check if kwriteconfig5 is available
 	if _, err := exec.LookPath("kwriteconfig5"); err != nil {
 		return false
@@ -79,7 +191,7 @@ func setupKDEHotkey(execPath string) bool {
 	
 	commands := [][]string{
 		{"kwriteconfig5", "--file", "kglobalshortcutsrc", "--group", "clipboard-manager", "--key", "_k_friendly_name", "Clipboard Manager"},
-		{"kwriteconfig5", "--file", "kglobalshortcutsrc", "--group", "clipboard-manager", "--key", "show", "Ctrl+Shift+V,none,Show Clipboard History"},
+		{"kwriteconfig5", "--file", "kglobalshortcutsrc", "--group", "clipboard-manager", "--key", "show", hotkey.String() + ",none,Show Clipboard History"},
 	}
 	
 	for _, cmd := range commands {
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/base64"
+	"bytes"
 	"image"
 	"image/color"
 	"image/png"
@@ -46,7 +46,7 @@ func TestAddImageToHistory(t *testing.T) {
 	}
 	
 	// Add image to history
-	addImageToHistory(imageData, "png")
+	addImageToHistory(imageData, "png", SourceApp{})
 	
 	// Verify image was added
 	actualLen := getTestHistoryLength()
@@ -59,10 +59,15 @@ func TestAddImageToHistory(t *testing.T) {
 		t.Errorf("Expected item type %s, got %s", ItemTypeImage, item.Type)
 	}
 	
-	// Verify base64 content
-	_, err = base64.StdEncoding.DecodeString(item.Content)
-	if err != nil {
-		t.Errorf("Failed to decode base64 content: %v", err)
+	// Verify the raw bytes are stored and hashed, but not kept in memory
+	if len(item.ImageData) != 0 {
+		t.Errorf("Expected in-memory history to leave out image bytes, got %d bytes", len(item.ImageData))
+	}
+	if stored, err := loadImageData(item.ID); err != nil || !bytes.Equal(stored, imageData) {
+		t.Errorf("Expected the image bytes to be stored unchanged, got %d bytes, %v", len(stored), err)
+	}
+	if item.ContentHash != contentHash(imageData) || item.Content != "" {
+		t.Errorf("Expected a content hash and no text content, got %q and %d bytes", item.ContentHash, len(item.Content))
 	}
 	
 	// Verify metadata
@@ -96,14 +101,26 @@ func TestImageDuplicateDetection(t *testing.T) {
 	}
 	
 	// Add same image twice
-	addImageToHistory(imageData, "png")
-	addImageToHistory(imageData, "png")
+	addImageToHistory(imageData, "png", SourceApp{})
+	addImageToHistory(imageData, "png", SourceApp{})
 	
 	// Should only have one item (duplicate detection)
 	actualLen := getTestHistoryLength()
 	if actualLen != 1 {
 		t.Errorf("Expected 1 item in history (duplicate detection), got %d", actualLen)
 	}
+	
+	// Re-copying an older image moves it to the end instead of duplicating it
+	addToHistory("Text in between")
+	addImageToHistory(imageData, "png", SourceApp{})
+	
+	actualLen = getTestHistoryLength()
+	if actualLen != 2 {
+		t.Fatalf("Expected 2 items in history, got %d", actualLen)
+	}
+	if item := getTestHistoryItem(1); item.Type != ItemTypeImage {
+		t.Errorf("Expected the re-copied image to be newest, got %s", item.Type)
+	}
 }
 
 func TestMixedTextAndImageHistory(t *testing.T) {
@@ -122,7 +139,7 @@ func TestMixedTextAndImageHistory(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create test image: %v", err)
 	}
-	addImageToHistory(imageData, "png")
+	addImageToHistory(imageData, "png", SourceApp{})
 	
 	// Add another text item
 	addToHistory("Another text item")
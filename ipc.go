@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The daemon serves a small JSON-RPC 2.0 protocol on a Unix socket so CLI
+// commands can go through the process that owns the clipboard watcher instead
+// of opening the database themselves. Each request and response is a single
+// line of JSON.
+
+// ipcRequest is a JSON-RPC request
+type ipcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// ipcResponse carries either a result or an error for the request with the same ID
+type ipcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ipcError       `json:"error,omitempty"`
+}
+
+// ipcError is a JSON-RPC error object
+type ipcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ipcError) Error() string {
+	return e.Message
+}
+
+// JSON-RPC error codes
+const (
+	ipcParseError     = -32700
+	ipcMethodNotFound = -32601
+	ipcInvalidParams  = -32602
+	ipcInternalError  = -32603
+	ipcCapturePaused  = -32000 // add was refused because capture is paused
+)
+
+// ipcIDParams selects an item for get and delete
+type ipcIDParams struct {
+	ID int64 `json:"id"`
+}
+
+// ipcListParams limits list to the newest items; zero returns everything
+type ipcListParams struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+// ipcAddParams is text to record as if it had been copied
+type ipcAddParams struct {
+	Text      string    `json:"text"`
+	Sensitive bool      `json:"sensitive,omitempty"` // Marked as secret by a password manager
+	App       SourceApp `json:"app"`                 // Application that owned the clipboard
+}
+
+// ipcPinParams pins or unpins an item
+type ipcPinParams struct {
+	ID     int64 `json:"id"`
+	Pinned bool  `json:"pinned"`
+}
+
+// ipcClearParams clears history, including pinned items if Force is set
+type ipcClearParams struct {
+	Force bool `json:"force,omitempty"`
+}
+
+// ipcItem is an item returned by get, including the image bytes that
+// ClipboardItem leaves out of its JSON
+type ipcItem struct {
+	ClipboardItem
+	ImageData []byte `json:"image_data,omitempty"`
+}
+
+// DaemonStatus describes the running daemon
+type DaemonStatus struct {
+	PID     int       `json:"pid"`
+	Mode    string    `json:"mode"`
+	Started time.Time `json:"started"`
+	Paused  bool      `json:"paused"`
+	Items   int       `json:"items"`
+	Socket  string    `json:"socket"`
+}
+
+// errDaemonUnavailable means no daemon is listening on the control socket
+var errDaemonUnavailable = errors.New("clipboard daemon is not running")
+
+// ipcTimeout bounds how long a CLI command waits for the daemon
+const ipcTimeout = 5 * time.Second
+
+// getSocketPath returns the control socket path inside a directory only the
+// user can enter, preferring $XDG_RUNTIME_DIR which is cleared on logout
+func getSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "clipboard-manager", "control.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("clipboard-manager-%d", os.Getuid()), "control.sock")
+}
+
+// makePrivateDir creates dir with mode 0700, or checks that an existing one
+// is a real directory owned by the user and tightens its permissions.
+// Another user's directory at a predictable /tmp path is refused.
+func makePrivateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not a directory owned by the current user", dir)
+	}
+	if info.Mode().Perm() != 0700 {
+		return os.Chmod(dir, 0700)
+	}
+	return nil
+}
+
+// checkSocketOwner refuses a socket that another user created, so commands
+// never send history to a daemon that isn't ours
+func checkSocketOwner(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return errDaemonUnavailable
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("refusing to use %s: it is not owned by the current user", path)
+	}
+	return nil
+}
+
+// ipcServer answers control requests for the daemon
+type ipcServer struct {
+	listener net.Listener
+	path     string
+	mode     string
+	started  time.Time
+	wg       sync.WaitGroup
+}
+
+// startIPCServer listens on the control socket. A socket left behind by a
+// daemon that crashed is replaced; one that still answers is an error.
+func startIPCServer(path, mode string) (*ipcServer, error) {
+	if err := makePrivateDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %v", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another daemon is already listening on %s", path)
+		}
+		os.Remove(path)
+	}
+
+	// Only the owner may control the daemon or read history through it. The
+	// umask makes the socket 0600 from the start rather than after a chmod.
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", path, err)
+	}
+
+	s := &ipcServer{listener: listener, path: path, mode: mode, started: time.Now()}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// startControlSocket starts the control socket for a daemon mode. It returns
+// nil if the socket can't be started; the tray and integration modes carry on
+// without it and CLI commands fall back to the database.
+func startControlSocket(mode string) *ipcServer {
+	s, err := startIPCServer(getSocketPath(), mode)
+	if err != nil {
+		fmt.Printf("Warning: control socket unavailable: %v\n", err)
+		return nil
+	}
+	fmt.Printf("Control socket listening on %s\n", s.path)
+	return s
+}
+
+// Close stops accepting requests and removes the socket file
+func (s *ipcServer) Close() error {
+	if s == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.wg.Wait()
+	os.Remove(s.path)
+	return err
+}
+
+// serve accepts connections until the listener is closed
+func (s *ipcServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn answers requests on one connection until the client hangs up
+func (s *ipcServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		resp := ipcResponse{JSONRPC: "2.0"}
+
+		var req ipcRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = &ipcError{Code: ipcParseError, Message: fmt.Sprintf("invalid request: %v", err)}
+		} else {
+			resp.ID = req.ID
+			result, ipcErr := s.dispatch(req)
+			if ipcErr != nil {
+				resp.Error = ipcErr
+			} else if resp.Result, ipcErr = marshalResult(result); ipcErr != nil {
+				resp.Error = ipcErr
+			}
+		}
+
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// marshalResult encodes a handler result for the response
+func marshalResult(result interface{}) (json.RawMessage, *ipcError) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, &ipcError{Code: ipcInternalError, Message: fmt.Sprintf("failed to encode result: %v", err)}
+	}
+	return data, nil
+}
+
+// decodeParams unmarshals request params into dst
+func decodeParams(req ipcRequest, dst interface{}) *ipcError {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, dst); err != nil {
+		return &ipcError{Code: ipcInvalidParams, Message: fmt.Sprintf("invalid params for %s: %v", req.Method, err)}
+	}
+	return nil
+}
+
+// dispatch runs a single request against the daemon's history
+func (s *ipcServer) dispatch(req ipcRequest) (interface{}, *ipcError) {
+	switch req.Method {
+	case "list":
+		var p ipcListParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		return listHistoryForIPC(p.Limit), nil
+
+	case "get":
+		var p ipcIDParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		item, err := getClipboardItem(p.ID)
+		if err != nil {
+			return nil, &ipcError{Code: ipcInvalidParams, Message: err.Error()}
+		}
+		return ipcItem{ClipboardItem: item, ImageData: item.ImageData}, nil
+
+	case "add":
+		var p ipcAddParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(p.Text) == "" {
+			return nil, &ipcError{Code: ipcInvalidParams, Message: "text cannot be empty"}
+		}
+		if capturePaused.Load() {
+			return nil, &ipcError{Code: ipcCapturePaused, Message: "clipboard capture is paused"}
+		}
+		if !getConfig().Apps.allows(p.App) {
+			return nil, &ipcError{Code: ipcInvalidParams, Message: fmt.Sprintf("copies from %s are ignored", p.App.Name)}
+		}
+		var formats []ClipboardContent
+		if p.Sensitive {
+			formats = []ClipboardContent{{MimeType: passwordManagerHint, Data: []byte("secret")}}
+		}
+		item, ok := addTextToHistory(p.Text, SourceClipboard, formats, p.App)
+		if !ok {
+			return nil, &ipcError{Code: ipcInternalError, Message: "text was not saved"}
+		}
+		return item, nil
+
+	case "delete":
+		var p ipcIDParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		if err := removeHistoryItem(p.ID); err != nil {
+			return nil, &ipcError{Code: ipcInvalidParams, Message: err.Error()}
+		}
+		return true, nil
+
+	case "pin":
+		var p ipcPinParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		if err := setHistoryItemPinned(p.ID, p.Pinned); err != nil {
+			return nil, &ipcError{Code: ipcInvalidParams, Message: err.Error()}
+		}
+		return true, nil
+
+	case "clear":
+		var p ipcClearParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		if err := clearHistoryItems(p.Force, nil); err != nil {
+			return nil, &ipcError{Code: ipcInternalError, Message: err.Error()}
+		}
+		return true, nil
+
+	case "pause", "resume":
+		capturePaused.Store(req.Method == "pause")
+		fmt.Printf("Clipboard capture %sd\n", req.Method)
+		return s.status(), nil
+
+	case "status":
+		return s.status(), nil
+
+	default:
+		return nil, &ipcError{Code: ipcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+// status reports the daemon's current state
+func (s *ipcServer) status() DaemonStatus {
+	return DaemonStatus{
+		PID:     os.Getpid(),
+		Mode:    s.mode,
+		Started: s.started,
+		Paused:  capturePaused.Load(),
+		Items:   getHistoryLength(),
+		Socket:  s.path,
+	}
+}
+
+// listHistoryForIPC returns the newest limit items, oldest first. History is
+// reloaded first because the popup and other commands write to the database
+// from their own processes.
+func listHistoryForIPC(limit int) []ClipboardItem {
+	historyMu.Lock()
+	refreshHistoryFromDB()
+	historyMu.Unlock()
+
+	items := getHistoryCopy()
+	if limit > 0 && len(items) > limit {
+		items = items[len(items)-limit:]
+	}
+	return items
+}
+
+// callDaemon sends one request to the daemon and decodes its result into
+// result, which may be nil. It returns errDaemonUnavailable if no daemon is
+// listening.
+func callDaemon(method string, params, result interface{}) error {
+	return callDaemonAt(getSocketPath(), method, params, result)
+}
+
+// callDaemonAt is callDaemon for an explicit socket path
+func callDaemonAt(path, method string, params, result interface{}) error {
+	if err := checkSocketOwner(path); err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return errDaemonUnavailable
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ipcTimeout))
+
+	req := ipcRequest{JSONRPC: "2.0", ID: 1, Method: method}
+	if params != nil {
+		if req.Params, err = json.Marshal(params); err != nil {
+			return fmt.Errorf("failed to encode params: %v", err)
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+
+	var resp ipcResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read daemon response: %v", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode daemon response: %v", err)
+		}
+	}
+	return nil
+}
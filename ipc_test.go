@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// startTestIPCServer serves the test database on a socket in a temporary directory
+func startTestIPCServer(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "control.sock")
+	s, err := startIPCServer(path, "test")
+	if err != nil {
+		t.Fatalf("startIPCServer() failed: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return path
+}
+
+func TestIPCHistoryMethods(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	path := startTestIPCServer(t)
+
+	var added ClipboardItem
+	if err := callDaemonAt(path, "add", ipcAddParams{Text: "sent over the socket"}, &added); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if added.ID == 0 || added.Content != "sent over the socket" {
+		t.Fatalf("Unexpected added item %+v", added)
+	}
+	callDaemonAt(path, "add", ipcAddParams{Text: "second item"}, nil)
+
+	var items []ClipboardItem
+	if err := callDaemonAt(path, "list", ipcListParams{Limit: 1}, &items); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Content != "second item" {
+		t.Errorf("Expected only the newest item, got %+v", items)
+	}
+
+	if err := callDaemonAt(path, "pin", ipcPinParams{ID: added.ID, Pinned: true}, nil); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	var got ipcItem
+	if err := callDaemonAt(path, "get", ipcIDParams{ID: added.ID}, &got); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if !got.Pinned || got.Content != "sent over the socket" {
+		t.Errorf("Expected the pinned item, got %+v", got)
+	}
+
+	if err := callDaemonAt(path, "clear", ipcClearParams{}, nil); err != nil {
+		t.Fatalf("clear failed: %v", err)
+	}
+	if err := callDaemonAt(path, "delete", ipcIDParams{ID: added.ID}, nil); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if getTestHistoryLength() != 0 {
+		t.Errorf("Expected empty history, got %d items", getTestHistoryLength())
+	}
+
+	// Errors come back as JSON-RPC error objects
+	var rpcErr *ipcError
+	err := callDaemonAt(path, "delete", ipcIDParams{ID: added.ID}, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != ipcInvalidParams {
+		t.Errorf("Expected an invalid params error for a missing item, got %v", err)
+	}
+	err = callDaemonAt(path, "shutdown", nil, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != ipcMethodNotFound {
+		t.Errorf("Expected a method not found error, got %v", err)
+	}
+}
+
+func TestIPCListSeesOtherWriters(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	path := startTestIPCServer(t)
+
+	// The popup writes to the database from its own process
+	if err := saveClipboardItem(ClipboardItem{Type: ItemTypeText, Content: "from the popup"}); err != nil {
+		t.Fatalf("saveClipboardItem() failed: %v", err)
+	}
+
+	var items []ClipboardItem
+	if err := callDaemonAt(path, "list", nil, &items); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Content != "from the popup" {
+		t.Errorf("Expected the item written outside the daemon, got %+v", items)
+	}
+}
+
+func TestIPCPauseAndStatus(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	path := startTestIPCServer(t)
+	defer capturePaused.Store(false)
+
+	var status DaemonStatus
+	if err := callDaemonAt(path, "pause", nil, &status); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if !status.Paused || status.PID != os.Getpid() || status.Mode != "test" || status.Socket != path {
+		t.Errorf("Unexpected status after pause %+v", status)
+	}
+
+	// Copies made while paused are never recorded, even after resuming
+	backend := newMemoryBackend()
+	w, texts, _ := newTestWatcher(watcherPresets["text-only"], backend)
+	writeText(backend, "secret while paused")
+	w.check(false)
+	var rpcErr *ipcError
+	err := callDaemonAt(path, "add", ipcAddParams{Text: "added while paused"}, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != ipcCapturePaused {
+		t.Errorf("Expected add to be refused while paused, got %v", err)
+	}
+	if getTestHistoryLength() != 0 {
+		t.Errorf("Expected nothing to be recorded while paused, got %d items", getTestHistoryLength())
+	}
+
+	if err := callDaemonAt(path, "resume", nil, &status); err != nil || status.Paused {
+		t.Fatalf("resume failed: %v, %+v", err, status)
+	}
+	w.check(false)
+	writeText(backend, "after resume")
+	w.check(false)
+
+	if len(*texts) != 1 || (*texts)[0] != "after resume" {
+		t.Errorf("Expected only the copy after resuming, got %v", *texts)
+	}
+}
+
+func TestIPCSocketLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "control.sock")
+
+	if err := callDaemonAt(path, "status", nil, nil); err != errDaemonUnavailable {
+		t.Errorf("Expected errDaemonUnavailable without a daemon, got %v", err)
+	}
+
+	// A socket left behind by a crashed daemon is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s, err := startIPCServer(path, "test")
+	if err != nil {
+		t.Fatalf("Expected the stale socket to be replaced: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a socket only the owner can use, got %v, %v", info.Mode(), err)
+	}
+
+	// A live socket belongs to another daemon
+	if _, err := startIPCServer(path, "test"); err == nil {
+		t.Error("Expected a second daemon to be refused")
+	}
+
+	s.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the socket to be removed on close, got %v", err)
+	}
+}
+
+func TestIPCSocketDirectory(t *testing.T) {
+	runtime := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtime)
+	path := getSocketPath()
+	if path != filepath.Join(runtime, "clipboard-manager", "control.sock") {
+		t.Fatalf("Expected the socket in a directory of its own, got %s", path)
+	}
+
+	// A directory others can enter is made private before listening
+	if err := os.Mkdir(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create socket directory: %v", err)
+	}
+	s, err := startIPCServer(path, "test")
+	if err != nil {
+		t.Fatalf("startIPCServer() failed: %v", err)
+	}
+	s.Close()
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected a directory only the owner can enter, got %v, %v", info.Mode(), err)
+	}
+
+	// A symlink could point the socket somewhere another user controls
+	link := filepath.Join(runtime, "link")
+	if err := os.Symlink(filepath.Dir(path), link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if _, err := startIPCServer(filepath.Join(link, "control.sock"), "test"); err == nil {
+		t.Error("Expected a symlinked socket directory to be refused")
+	}
+}
+
+func TestIPCRefusesForeignSocket(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing a socket's owner needs root")
+	}
+	path := startTestIPCServer(t)
+	if err := os.Lchown(path, 65534, 65534); err != nil {
+		t.Fatalf("Failed to change socket owner: %v", err)
+	}
+	err := callDaemonAt(path, "status", nil, nil)
+	if err == nil || err == errDaemonUnavailable {
+		t.Errorf("Expected a socket owned by another user to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Only one process may watch the clipboard at a time. Each daemon mode takes
+// an exclusive open file description lock on a lock file in the runtime
+// directory and records who it is there. The kernel releases the lock when the
+// process exits, so a lock file left behind by a crash is never mistaken for a
+// running daemon. Other commands check for the lock with F_OFD_GETLK, which
+// never takes it, so checking can't make a starting daemon fail.
+
+// daemonLockInfo is what the daemon records in the lock file
+type daemonLockInfo struct {
+	PID     int       `json:"pid"`
+	Mode    string    `json:"mode"`
+	Started time.Time `json:"started"`
+}
+
+// daemonLock is a held lock file
+type daemonLock struct {
+	file *os.File
+	info daemonLockInfo
+}
+
+// daemonRunningError is returned when another process holds the lock
+type daemonRunningError struct {
+	info daemonLockInfo
+}
+
+func (e *daemonRunningError) Error() string {
+	if e.info.PID == 0 {
+		return "clipboard daemon is already running"
+	}
+	return fmt.Sprintf("clipboard daemon is already running (PID: %d, mode: %s)", e.info.PID, e.info.Mode)
+}
+
+// getLockPath returns the lock file path in the user's runtime directory
+func getLockPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "clipboard-manager.lock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("clipboard-manager-%d.lock", os.Getuid()))
+}
+
+// acquireDaemonLock takes the lock at path for this process. It fails with a
+// *daemonRunningError if another daemon holds it.
+func acquireDaemonLock(path, mode string) (*daemonLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+
+	if err := setDaemonLock(file, unix.F_WRLCK); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EACCES) {
+			info, _ := readDaemonLock(path)
+			return nil, &daemonRunningError{info: info}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+	}
+
+	lock := &daemonLock{
+		file: file,
+		info: daemonLockInfo{PID: os.Getpid(), Mode: mode, Started: time.Now()},
+	}
+	data, err := json.Marshal(lock.info)
+	if err == nil {
+		err = file.Truncate(0)
+	}
+	if err == nil {
+		_, err = file.WriteAt(append(data, '\n'), 0)
+	}
+	if err != nil {
+		lock.Release()
+		return nil, fmt.Errorf("failed to write lock file: %v", err)
+	}
+
+	return lock, nil
+}
+
+// exitDaemonRunning is the exit status when another daemon holds the lock.
+// The systemd unit doesn't restart on it, since retrying can't succeed.
+const exitDaemonRunning = 3
+
+// claimDaemonLock takes the daemon lock for mode, exiting if another daemon
+// is already running so two watchers never write history at the same time
+func claimDaemonLock(mode string) *daemonLock {
+	lock, err := acquireDaemonLock(getLockPath(), mode)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		var running *daemonRunningError
+		if errors.As(err, &running) {
+			fmt.Println("Use 'clipboard-manager stop' to stop it first.")
+			os.Exit(exitDaemonRunning)
+		}
+		os.Exit(1)
+	}
+	return lock
+}
+
+// Release clears the recorded details and unlocks the file. The file itself is
+// left in place; removing it could let two processes lock different files.
+func (l *daemonLock) Release() {
+	if l == nil {
+		return
+	}
+	l.file.Truncate(0)
+	setDaemonLock(l.file, unix.F_UNLCK)
+	l.file.Close()
+}
+
+// setDaemonLock locks or unlocks the whole file without waiting. Unlike a
+// POSIX record lock, the lock belongs to the open file, so reading the lock
+// file elsewhere in the daemon doesn't release it.
+func setDaemonLock(file *os.File, lockType int16) error {
+	lk := unix.Flock_t{Type: lockType, Whence: io.SeekStart}
+	return unix.FcntlFlock(file.Fd(), unix.F_OFD_SETLK, &lk)
+}
+
+// readDaemonLock reports whether a daemon holds the lock at path and, if so,
+// the details it recorded
+func readDaemonLock(path string) (daemonLockInfo, bool) {
+	var info daemonLockInfo
+
+	file, err := os.Open(path)
+	if err != nil {
+		return info, false
+	}
+	defer file.Close()
+
+	// If nothing would stop a write lock nobody holds it, whatever the file
+	// says. F_OFD_GETLK only asks, so a daemon starting now isn't refused.
+	lk := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart}
+	if err := unix.FcntlFlock(file.Fd(), unix.F_OFD_GETLK, &lk); err != nil || lk.Type == unix.F_UNLCK {
+		return info, false
+	}
+
+	// The daemon writes its details just after locking, so they may briefly
+	// be missing
+	for attempt := 0; attempt < 10; attempt++ {
+		data, err := os.ReadFile(path)
+		if err == nil && json.Unmarshal(data, &info) == nil && info.PID != 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return info, true
+}
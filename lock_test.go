@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDaemonLockIsExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.lock")
+
+	if _, running := readDaemonLock(path); running {
+		t.Fatal("Expected no daemon before the lock is taken")
+	}
+
+	lock, err := acquireDaemonLock(path, "daemon-text-only")
+	if err != nil {
+		t.Fatalf("acquireDaemonLock() failed: %v", err)
+	}
+
+	info, running := readDaemonLock(path)
+	if !running || info.PID != os.Getpid() || info.Mode != "daemon-text-only" || info.Started.IsZero() {
+		t.Errorf("Expected the lock holder's details, got %+v, %v", info, running)
+	}
+
+	// A second watcher is refused and told who holds the lock
+	_, err = acquireDaemonLock(path, "tray")
+	var held *daemonRunningError
+	if !errors.As(err, &held) || held.info.PID != os.Getpid() || held.info.Mode != "daemon-text-only" {
+		t.Errorf("Expected a daemonRunningError naming the holder, got %v", err)
+	}
+
+	lock.Release()
+	if _, running := readDaemonLock(path); running {
+		t.Error("Expected no daemon after the lock is released")
+	}
+
+	lock, err = acquireDaemonLock(path, "tray")
+	if err != nil {
+		t.Fatalf("Expected the lock to be free again: %v", err)
+	}
+	lock.Release()
+}
+
+func TestDaemonLockIgnoresStaleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.lock")
+
+	// A daemon that crashed leaves its details behind but not its lock
+	stale := `{"pid":999999,"mode":"daemon","started":"2024-01-01T00:00:00Z"}`
+	if err := os.WriteFile(path, []byte(stale), 0600); err != nil {
+		t.Fatalf("Failed to write stale lock file: %v", err)
+	}
+
+	if _, running := readDaemonLock(path); running {
+		t.Error("Expected a stale lock file not to count as a running daemon")
+	}
+
+	lock, err := acquireDaemonLock(path, "daemon")
+	if err != nil {
+		t.Fatalf("Expected the stale lock file to be taken over: %v", err)
+	}
+	defer lock.Release()
+
+	if info, _ := readDaemonLock(path); info.PID != os.Getpid() {
+		t.Errorf("Expected the stale details to be replaced, got %+v", info)
+	}
+}
@@ -1,26 +1,36 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"strings"
 	"syscall"
-	"time"
-
-	"github.com/atotto/clipboard"
 )
 
 func main() {
+	// Internal helper started by the clipboard backend to keep serving a
+	// multi-format selection after the process that wrote it exits
+	if len(os.Args) > 1 && os.Args[1] == "serve-selection" {
+		if err := runServeSelection(os.Args[2:]); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Check if we're in a proper environment (skip for certain commands)
 	if len(os.Args) > 1 {
 		mode := os.Args[1]
 		// These commands don't require full graphical environment
 		skipEnvCheck := mode == "help" || mode == "diagnose" || mode == "status" || 
 						mode == "stop" || mode == "startup-status" || 
-						mode == "startup-enable" || mode == "startup-disable"
+						mode == "startup-enable" || mode == "startup-disable" ||
+						mode == "db-migrate" || mode == "search" ||
+						mode == "pin" || mode == "unpin" || mode == "clear" ||
+						mode == "get" || mode == "delete" || mode == "pause" || mode == "resume" ||
+						mode == "service" || mode == "config" || mode == "test-filter"
 		
 		if !skipEnvCheck && !checkEnvironment() {
 			fmt.Println("❌ Environment Check Failed")
@@ -44,9 +54,33 @@ func main() {
 		}
 	}
 
-	// Auto-enable startup on first run (unless explicitly disabling)
-	if len(os.Args) == 1 || (len(os.Args) > 1 && os.Args[1] != "startup-disable") {
-		ensureStartupEnabled()
+	if err := loadConfig(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		fmt.Println("Using default settings.")
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "db-migrate" {
+		// Must run before loadHistory, which applies migrations implicitly
+		dryRun := len(os.Args) > 2 && os.Args[2] == "--dry-run"
+		if err := runMigrateCommand(dryRun); err != nil {
+			fmt.Printf("❌ Migration failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Commands that go through the daemon when it is running
+	if len(os.Args) > 1 {
+		if usage, ok := controlCommandUsage[os.Args[1]]; ok {
+			if err := runControlCommand(os.Args[1], os.Args[2:]); err != nil {
+				fmt.Printf("❌ %s failed: %v\n", os.Args[1], err)
+				if err != errDaemonUnavailable {
+					fmt.Printf("Usage: %s\n", usage)
+				}
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
 	loadHistory() // load previous data
@@ -64,8 +98,15 @@ func main() {
 		return
 	}
 
-	if len(os.Args) > 1 && os.Args[1] == "list" {
-		showTerminalHistory()
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: clipboard-manager search [query] [--type text|image] [--app NAME] [--since 7d|2006-01-02] [--until ...] [--limit N]")
+			os.Exit(1)
+		}
+		if err := runSearchCommand(os.Args[2:]); err != nil {
+			fmt.Printf("❌ Search failed: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -75,6 +116,14 @@ func main() {
 		fmt.Println("  ./clipboard-manager              - Start with system integration")
 		fmt.Println("  ./clipboard-manager show         - Show GUI history (auto-starts daemon)")
 		fmt.Println("  ./clipboard-manager list         - Show terminal history")
+		fmt.Println("  ./clipboard-manager search [query] - Search history (\"phrases\", prefix*, --type, --app, --since, --limit)")
+		fmt.Println("  ./clipboard-manager pin <id>     - Pin an item so it is never trimmed or cleared")
+		fmt.Println("  ./clipboard-manager unpin <id>   - Unpin an item")
+		fmt.Println("  ./clipboard-manager get <id>     - Print an item's content")
+		fmt.Println("  ./clipboard-manager delete <id>  - Delete an item")
+		fmt.Println("  ./clipboard-manager clear [--force] - Clear history (keeps pinned items unless --force)")
+		fmt.Println("  ./clipboard-manager pause        - Stop the daemon recording copies until resumed")
+		fmt.Println("  ./clipboard-manager resume       - Resume recording copies")
 		fmt.Println("  ./clipboard-manager tray         - Start with system tray")
 		fmt.Println("  ./clipboard-manager daemon       - Start in background (no GUI)")
 		fmt.Println("  ./clipboard-manager daemon-text-only - Start daemon (text only, no image monitoring)")
@@ -84,9 +133,13 @@ func main() {
 		fmt.Println("  ./clipboard-manager capture          - Manually capture current clipboard")
 		fmt.Println("  ./clipboard-manager status       - Show daemon status")
 		fmt.Println("  ./clipboard-manager stop         - Stop daemon")
+		fmt.Println("  ./clipboard-manager config show|path|get <key>|set <key> <value> - View or change settings")
+		fmt.Println("  ./clipboard-manager test-filter <text> - Explain whether the noise filter would record the text")
+		fmt.Println("  ./clipboard-manager service install|uninstall|status - Run the daemon as a systemd user service")
 		fmt.Println("  ./clipboard-manager startup-status  - Show startup application status")
 		fmt.Println("  ./clipboard-manager startup-enable  - Enable startup application")
 		fmt.Println("  ./clipboard-manager startup-disable - Disable startup application")
+		fmt.Println("  ./clipboard-manager db-migrate [--dry-run] - Upgrade the history database schema")
 		fmt.Println("  ./clipboard-manager diagnose     - Check environment and requirements")
 		fmt.Println("  ./clipboard-manager help         - Show this help")
 		fmt.Println()
@@ -104,8 +157,34 @@ func main() {
 		return
 	}
 
-	if len(os.Args) > 1 && os.Args[1] == "status" {
-		showDaemonStatus()
+	if len(os.Args) > 1 && os.Args[1] == "test-filter" {
+		readStdin := func() (string, error) {
+			data, err := io.ReadAll(os.Stdin)
+			return string(data), err
+		}
+		if err := runTestFilterCommand(os.Args[2:], readStdin); err != nil {
+			fmt.Printf("❌ test-filter failed: %v\n", err)
+			fmt.Println("Usage: clipboard-manager test-filter [--type text|files|image] <text>  (reads stdin without text)")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfigCommand(os.Args[2:]); err != nil {
+			fmt.Printf("❌ config failed: %v\n", err)
+			fmt.Printf("Usage: %s\n", configUsage)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		if err := runServiceCommand(os.Args[2:]); err != nil {
+			fmt.Printf("❌ service failed: %v\n", err)
+			fmt.Printf("Usage: %s\n", serviceUsage)
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -121,119 +200,41 @@ func main() {
 	}
 
 	if len(os.Args) > 1 && os.Args[1] == "daemon" {
-		// Check if daemon is already running
-		if isDaemonRunning() {
-			fmt.Println("Clipboard daemon is already running. Use 'clipboard-manager stop' to stop it first.")
-			os.Exit(1)
-		}
-		
+		lock := claimDaemonLock("daemon")
 		fmt.Println("Clipboard Manager started in daemon mode (no hotkeys).")
-		fmt.Println("Press Ctrl+C to stop.")
-		
-		// graceful exit (Ctrl+C)
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-		go func() {
-			<-c
-			fmt.Println("\nClosing database...")
-			closeDatabase()
-			os.Exit(0)
-		}()
-
-		watchClipboard() // start daemon without hotkeys
+		runDaemon(lock, "") // watcher preset from the config file
 		return
 	}
 
 	if len(os.Args) > 1 && os.Args[1] == "daemon-text-only" {
+		lock := claimDaemonLock("daemon-text-only")
 		fmt.Println("Clipboard Manager started in text-only daemon mode (no hotkeys, no image monitoring).")
-		fmt.Println("Press Ctrl+C to stop.")
-		
-		// graceful exit (Ctrl+C)
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-		go func() {
-			<-c
-			fmt.Println("\nClosing database...")
-			closeDatabase()
-			os.Exit(0)
-		}()
-
-		watchClipboardTextOnly() // start daemon without hotkeys and image monitoring
+		runDaemon(lock, "text-only")
 		return
 	}
 
 	if len(os.Args) > 1 && os.Args[1] == "daemon-minimal" {
+		lock := claimDaemonLock("daemon-minimal")
 		fmt.Println("Clipboard Manager started in minimal daemon mode (ultra-conservative polling).")
-		fmt.Println("Press Ctrl+C to stop.")
-		
-		// graceful exit (Ctrl+C)
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-		go func() {
-			<-c
-			fmt.Println("\nClosing database...")
-			closeDatabase()
-			os.Exit(0)
-		}()
-
-		watchClipboardMinimal() // start daemon with ultra-minimal monitoring
+		fmt.Println("This mode minimizes system interference but may miss rapid clipboard changes")
+		runDaemon(lock, "minimal")
 		return
 	}
 
 	if len(os.Args) > 1 && os.Args[1] == "daemon-passive" {
+		lock := claimDaemonLock("daemon-passive")
 		fmt.Println("Clipboard Manager started in passive mode (no automatic monitoring).")
 		fmt.Println("Use './clipboard-manager capture' to manually capture current clipboard.")
-		fmt.Println("Press Ctrl+C to stop.")
-		
-		// graceful exit (Ctrl+C)
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-		go func() {
-			<-c
-			fmt.Println("\nClosing database...")
-			closeDatabase()
-			os.Exit(0)
-		}()
-
-		// Just keep the process alive without monitoring
-		select {}
+		runDaemon(lock, "passive")
+		return
 	}
 
 	if len(os.Args) > 1 && os.Args[1] == "daemon-only" {
+		lock := claimDaemonLock("daemon-only")
 		fmt.Println("Clipboard Manager started in daemon-only mode (no hotkeys, no GUI).")
 		fmt.Println("Text-only monitoring to avoid any window creation.")
 		fmt.Println("Use './clipboard-manager show' to open GUI manually.")
-		fmt.Println("Press Ctrl+C to stop.")
-		
-		// graceful exit (Ctrl+C)
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-		go func() {
-			<-c
-			fmt.Println("\nClosing database...")
-			closeDatabase()
-			os.Exit(0)
-		}()
-
-		watchClipboardTextOnly() // start daemon without hotkeys, GUI, or image monitoring
-		return
-	}
-
-	if len(os.Args) > 1 && os.Args[1] == "capture" {
-		// Manual clipboard capture
-		text, err := clipboard.ReadAll()
-		if err != nil {
-			fmt.Printf("Error reading clipboard: %v\n", err)
-			os.Exit(1)
-		}
-		
-		text = strings.TrimSpace(text)
-		if len(text) >= 2 && !isSystemNoise(text) {
-			addToHistory(text)
-			fmt.Printf("📋 Captured: %s\n", text)
-		} else {
-			fmt.Println("No meaningful text found in clipboard")
-		}
+		runDaemon(lock, "text-only")
 		return
 	}
 
@@ -260,18 +261,18 @@ func main() {
 	}
 
 	// Default mode: Start with system integration and hotkeys
-	// Check if daemon is already running
-	if isDaemonRunning() {
-		fmt.Println("Clipboard daemon is already running. Use 'clipboard-manager stop' to stop it first.")
-		os.Exit(1)
-	}
+	// Only one process may watch the clipboard
+	lock := claimDaemonLock("integration")
+	offerStartup()
+	
 	
 	fmt.Println("Clipboard Manager started with system integration.")
 	fmt.Println("Note: This will set up hotkeys but won't show GUI automatically.")
 	fmt.Println("Use 'clipboard-manager show' or press Ctrl+Shift+V to open the GUI.")
 
 	// Start clipboard monitoring in background
-	go watchClipboard()
+	go watchClipboard("")
+	control := startControlSocket("integration")
 
 	// graceful exit (Ctrl+C)
 	c := make(chan os.Signal, 1)
@@ -279,10 +280,16 @@ func main() {
 	go func() {
 		<-c
 		fmt.Println("\nClosing database...")
+		control.Close()
 		closeDatabase()
+		lock.Release()
 		os.Exit(0)
 	}()
 
+	go retentionSweepLoop()
+	go sensitiveSweepLoop()
+	go watchConfigFile()
+
 	// Setup system hotkeys and keep running (but don't auto-show GUI)
 	setupLinuxHotkeys()
 }
@@ -311,8 +318,8 @@ func checkEnvironment() bool {
 		}
 	}
 	
-	// If no external tools, try the Go clipboard library
-	_, err := clipboard.ReadAll()
+	// If no external tools, see whether a clipboard backend can still be selected
+	_, err := getClipboardBackend()
 	return err == nil
 }
 
@@ -368,7 +375,10 @@ func diagnoseEnvironment() {
 	// Test clipboard access
 	fmt.Println()
 	fmt.Println("🧪 Testing clipboard access...")
-	if _, err := clipboard.ReadAll(); err != nil {
+	if backend, err := getClipboardBackend(); err == nil {
+		fmt.Printf("✓ Clipboard backend: %s\n", backend.Name())
+	}
+	if _, err := readClipboardText(); err != nil {
 		fmt.Printf("❌ Clipboard test failed: %v\n", err)
 		fmt.Println()
 		fmt.Println("💡 Possible solutions:")
@@ -386,427 +396,108 @@ func diagnoseEnvironment() {
 	fmt.Println("   • clipboard-manager status         - Check daemon status")
 }
 
-// watches clipboard continuously
-func watchClipboard() {
-	lastText := ""
-	var lastImageData []byte
-	
-	errorCount := 0
-	maxErrors := 5
-	imageCheckCounter := 0
-	
-	// Disable image monitoring if it causes issues (can be made configurable)
-	enableImageMonitoring := true
-	
-	for {
-		// Check for text content first
-		text, textErr := clipboard.ReadAll()
-		
-		if textErr == nil {
-			// Reset error count on successful read
-			errorCount = 0
-			
-			// Clean up the text
-			text = strings.TrimSpace(text)
-			
-			// Check if we have new meaningful text content
-			if len(text) >= 2 && text != lastText && !isSystemNoise(text) {
-				addToHistory(text)
-				lastText = text
-				
-				// Show notification for meaningful content
-				if len(text) > 5 {
-					displayText := text
-					if len(displayText) > 60 {
-						displayText = displayText[:60] + "..."
-					}
-					// Replace newlines for cleaner output
-					displayText = strings.ReplaceAll(displayText, "\n", " ")
-					fmt.Printf("📋 Text copied: %s\n", displayText)
-				}
-			}
-		} else {
-			errorCount++
-			if errorCount <= maxErrors {
-				fmt.Printf("Clipboard text read error (%d/%d): %v\n", errorCount, maxErrors, textErr)
-			}
-		}
-		
-		// Check for image content less frequently (every 3rd iteration) and only if enabled
-		if enableImageMonitoring {
-			imageCheckCounter++
-			if imageCheckCounter%3 == 0 {
-				if imageData, format, imageErr := detectImageInClipboard(); imageErr == nil {
-					// Reset error count on successful image read
-					errorCount = 0
-					
-					// Check if we have new image content (compare first 1KB for efficiency)
-					compareSize := 1024
-					if len(imageData) < compareSize {
-						compareSize = len(imageData)
-					}
-					
-					isNewImage := len(lastImageData) == 0 || 
-						len(lastImageData) != len(imageData) ||
-						!bytes.Equal(imageData[:compareSize], lastImageData[:compareSize])
-					
-					if isNewImage {
-						addImageToHistory(imageData, format)
-						lastImageData = make([]byte, len(imageData))
-						copy(lastImageData, imageData)
-						
-						// Show notification for image
-						sizeKB := len(imageData) / 1024
-						fmt.Printf("📋 Image copied: %s (%d KB)\n", format, sizeKB)
-					}
-				}
-			}
-		}
-		
-		// Handle errors and sleep timing
-		if textErr != nil && errorCount >= maxErrors {
-			fmt.Println("Too many clipboard errors, reducing check frequency...")
-			time.Sleep(10 * time.Second)
-		} else if textErr != nil {
-			time.Sleep(3 * time.Second)
-		} else {
-			// Normal polling - much less aggressive
-			time.Sleep(6 * time.Second)
-		}
-	}
-}
-
-// Check if text is likely system noise
-func isSystemNoise(text string) bool {
-	// Skip very short text
-	if len(text) < 3 {
-		return true
-	}
-	
-	// Skip common system clipboard noise patterns
-	noisePatterns := []string{
-		"signal\"",
-		"syscall\"",
-		"time\"",
-		"import",
-		"package",
-		"func",
-	}
-	
-	for _, pattern := range noisePatterns {
-		if strings.Contains(text, pattern) && len(text) < 50 {
-			return true
-		}
-	}
-	
-	return false
-}
-
 // Terminal-based history viewer as fallback
 func showTerminalHistory() {
-	historyMu.RLock()
-	defer historyMu.RUnlock()
-	
-	if len(history) == 0 {
+	printHistory(getHistoryCopy())
+}
+
+// printHistory prints items (oldest first) pinned first, then newest first
+func printHistory(items []ClipboardItem) {
+	if len(items) == 0 {
 		fmt.Println("No clipboard history yet.")
 		return
 	}
 
-	fmt.Println("\nClipboard History (newest first):")
+	fmt.Println("\nClipboard History (pinned first, then newest first; numbers are item IDs):")
 	fmt.Println(strings.Repeat("-", 50))
 	
-	for i := len(history) - 1; i >= 0; i-- {
-		item := history[i]
-		itemNum := len(history) - i
+	newestFirst := make([]ClipboardItem, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		newestFirst = append(newestFirst, items[i])
+	}
+	pinned, others := partitionPinned(newestFirst)
+	
+	for _, item := range append(pinned, others...) {
+		marker := ""
+		if item.Pinned {
+			marker = "📌 "
+		}
+		from := ""
+		if source := describeSource(item); source != "" {
+			from = fmt.Sprintf("  (from %s)", source)
+		}
 		
 		if item.Type == ItemTypeText {
 			content := item.Content
-			if len(content) > 80 {
+			if item.Sensitive != "" {
+				content = maskedText(item)
+			} else if len(content) > 80 {
 				content = content[:80] + "..."
 			}
 			// Replace newlines with spaces for better terminal display
 			content = strings.ReplaceAll(content, "\n", " ")
-			fmt.Printf("%2d: [TEXT] %s\n", itemNum, content)
+			tag := "[TEXT]"
+			if item.Source == SourcePrimary {
+				tag = "[PRIMARY]"
+			}
+			fmt.Printf("%4d: %s%s %s%s\n", item.ID, marker, tag, content, from)
 		} else if item.Type == ItemTypeImage {
 			if item.ImageMeta != nil {
-				fmt.Printf("%2d: [IMAGE] %s %dx%d (%d KB)\n", 
-					itemNum, 
+				fmt.Printf("%4d: %s[IMAGE] %s %dx%d (%d KB)%s\n", 
+					item.ID, 
+					marker,
 					strings.ToUpper(item.ImageMeta.Format),
 					item.ImageMeta.Width, 
 					item.ImageMeta.Height,
-					item.ImageMeta.Size/1024)
+					item.ImageMeta.Size/1024,
+					from)
 			} else {
-				fmt.Printf("%2d: [IMAGE] Unknown format\n", itemNum)
+				fmt.Printf("%4d: %s[IMAGE] Unknown format%s\n", item.ID, marker, from)
 			}
+		} else if item.Type == ItemTypeFiles {
+			fmt.Printf("%4d: %s[FILES] %s%s\n", item.ID, marker, describeFileList(filePaths(item), 3), from)
 		}
 	}
 	
 	fmt.Println(strings.Repeat("-", 50))
-	fmt.Printf("Total items: %d\n", len(history))
-}
-// watchClipboardTextOnly watches clipboard for text content only (no image monitoring)
-func watchClipboardTextOnly() {
-	lastText := ""
-	
-	errorCount := 0
-	maxErrors := 5
-	
-	for {
-		// Check for text content only
-		text, textErr := clipboard.ReadAll()
-		
-		if textErr == nil {
-			// Reset error count on successful read
-			errorCount = 0
-			
-			// Clean up the text
-			text = strings.TrimSpace(text)
-			
-			// Check if we have new meaningful text content
-			if len(text) >= 2 && text != lastText && !isSystemNoise(text) {
-				addToHistory(text)
-				lastText = text
-				
-				// Show notification for meaningful content
-				if len(text) > 5 {
-					displayText := text
-					if len(displayText) > 60 {
-						displayText = displayText[:60] + "..."
-					}
-					// Replace newlines for cleaner output
-					displayText = strings.ReplaceAll(displayText, "\n", " ")
-					fmt.Printf("📋 Text copied: %s\n", displayText)
-				}
-			}
-		} else {
-			errorCount++
-			if errorCount <= maxErrors {
-				fmt.Printf("Clipboard text read error (%d/%d): %v\n", errorCount, maxErrors, textErr)
-			}
-		}
-		
-		// Handle errors and sleep timing - more conservative for text-only mode
-		if textErr != nil && errorCount >= maxErrors {
-			fmt.Println("Too many clipboard errors, reducing check frequency...")
-			time.Sleep(15 * time.Second)
-		} else if textErr != nil {
-			time.Sleep(5 * time.Second)
-		} else {
-			// Even more conservative polling for text-only mode
-			time.Sleep(3 * time.Second)
-		}
-	}
+	fmt.Printf("Total items: %d\n", len(items))
 }
-
-// watchClipboardMinimal watches clipboard with ultra-conservative polling to avoid system interference
-func watchClipboardMinimal() {
-	lastText := ""
-	
-	errorCount := 0
-	maxErrors := 3
-	
-	fmt.Println("Using ultra-minimal clipboard monitoring (10-second intervals)")
-	fmt.Println("This mode minimizes system interference but may miss rapid clipboard changes")
-	
-	for {
-		// Only check clipboard every 10 seconds to minimize system interference
-		time.Sleep(10 * time.Second)
-		
-		// Check for text content only
-		text, textErr := clipboard.ReadAll()
-		
-		if textErr == nil {
-			// Reset error count on successful read
-			errorCount = 0
-			
-			// Clean up the text
-			text = strings.TrimSpace(text)
-			
-			// Check if we have new meaningful text content
-			if len(text) >= 2 && text != lastText && !isSystemNoise(text) {
-				addToHistory(text)
-				lastText = text
-				
-				// Show notification for meaningful content
-				if len(text) > 5 {
-					displayText := text
-					if len(displayText) > 60 {
-						displayText = displayText[:60] + "..."
-					}
-					// Replace newlines for cleaner output
-					displayText = strings.ReplaceAll(displayText, "\n", " ")
-					fmt.Printf("📋 Text copied: %s\n", displayText)
-				}
-			}
-		} else {
-			errorCount++
-			if errorCount <= maxErrors {
-				fmt.Printf("Clipboard text read error (%d/%d): %v\n", errorCount, maxErrors, textErr)
-			}
-			
-			// If too many errors, wait even longer
-			if errorCount >= maxErrors {
-				fmt.Println("Too many clipboard errors, waiting 30 seconds...")
-				time.Sleep(30 * time.Second)
-			}
-		}
-	}
-}
-
-// showStartupStatus shows the current startup application status
-func showStartupStatus() {
-	autostartFile := os.ExpandEnv("$HOME/.config/autostart/clipboard-manager.desktop")
-	
-	if _, err := os.Stat(autostartFile); os.IsNotExist(err) {
-		fmt.Println("🚀 Startup Application Status: DISABLED")
-		fmt.Println("   • Autostart file does not exist")
-		fmt.Printf("   • Location: %s\n", autostartFile)
-		fmt.Println("   • Run 'clipboard-manager startup-enable' to enable")
-		return
-	}
-	
-	// Check if the file exists and read its content
-	content, err := os.ReadFile(autostartFile)
-	if err != nil {
-		fmt.Printf("❌ Error reading autostart file: %v\n", err)
-		return
+// runDaemon monitors the clipboard in the foreground with the named watcher
+// preset until interrupted. lock is the daemon lock taken for its mode.
+func runDaemon(lock *daemonLock, preset string) {
+	fmt.Println("Press Ctrl+C to stop.")
+	control := startControlSocket(lock.info.Mode)
+	if control == nil {
+		// Without the socket the CLI can't reach the daemon, so don't report
+		// a service that looks healthy but can't be controlled
+		fmt.Println("❌ Not starting the daemon without its control socket")
+		sdNotify("STATUS=Control socket unavailable")
+		lock.Release()
+		closeDatabase()
+		os.Exit(1)
 	}
 	
-	// Check if it's disabled in the file content
-	contentStr := string(content)
-	if strings.Contains(contentStr, "Hidden=true") || strings.Contains(contentStr, "X-GNOME-Autostart-enabled=false") {
-		fmt.Println("🚀 Startup Application Status: DISABLED")
-		fmt.Println("   • Autostart file exists but is disabled")
-	} else {
-		fmt.Println("🚀 Startup Application Status: ENABLED")
-		fmt.Println("   • Will start automatically on login")
+	// Tell systemd the daemon is ready when run as a Type=notify service
+	if _, err := sdNotify("READY=1"); err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
 	
-	fmt.Printf("   • Location: %s\n", autostartFile)
-	fmt.Println("   • Visible in System Settings > Startup Applications")
-	fmt.Println("   • Run 'clipboard-manager startup-disable' to disable")
-	fmt.Println("   • Run 'clipboard-manager startup-enable' to enable")
-}
+	// graceful exit (Ctrl+C)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		sdNotify("STOPPING=1")
+		fmt.Println("\nClosing database...")
+		control.Close()
+		closeDatabase()
+		lock.Release()
+		os.Exit(0)
+	}()
 
-// enableStartup enables the startup application
-func enableStartup() {
-	autostartDir := os.ExpandEnv("$HOME/.config/autostart")
-	autostartFile := autostartDir + "/clipboard-manager.desktop"
-	
-	// Create autostart directory if it doesn't exist
-	if err := os.MkdirAll(autostartDir, 0755); err != nil {
-		fmt.Printf("❌ Error creating autostart directory: %v\n", err)
-		return
-	}
-	
-	// Create the enhanced autostart file
-	content := `[Desktop Entry]
-Name=Clipboard Manager
-GenericName=Clipboard History Manager
-Comment=Clipboard history manager with Ctrl+Shift+V hotkey
-Exec=/usr/local/bin/clipboard-manager clipboard-manager
-Icon=edit-copy
-Terminal=false
-Type=Application
-Categories=Utility;System;Accessibility;
-Keywords=clipboard;history;copy;paste;hotkey;
-X-GNOME-Autostart-enabled=true
-X-KDE-autostart-after=panel
-X-MATE-Autostart-enabled=true
-X-XFCE-Autostart-enabled=true
-Hidden=false
-NoDisplay=false
-StartupNotify=false
-X-GNOME-Autostart-Delay=3
-X-KDE-StartupNotify=false
-OnlyShowIn=GNOME;KDE;XFCE;MATE;Unity;Cinnamon;Pantheon;LXQt;LXDE;
-`
-	
-	if err := os.WriteFile(autostartFile, []byte(content), 0644); err != nil {
-		fmt.Printf("❌ Error creating autostart file: %v\n", err)
-		return
-	}
-	
-	fmt.Println("✅ Startup application ENABLED")
-	fmt.Printf("   • Created: %s\n", autostartFile)
-	fmt.Println("   • Clipboard Manager will start automatically on login")
-	fmt.Println("   • Visible in System Settings > Startup Applications")
-}
+	go retentionSweepLoop()
+	go sensitiveSweepLoop()
+	go watchConfigFile()
 
-// disableStartup disables the startup application
-func disableStartup() {
-	autostartFile := os.ExpandEnv("$HOME/.config/autostart/clipboard-manager.desktop")
-	
-	if _, err := os.Stat(autostartFile); os.IsNotExist(err) {
-		fmt.Println("ℹ️  Startup application is already disabled (file does not exist)")
-		return
-	}
-	
-	if err := os.Remove(autostartFile); err != nil {
-		fmt.Printf("❌ Error removing autostart file: %v\n", err)
-		return
-	}
-	
-	fmt.Println("✅ Startup application DISABLED")
-	fmt.Printf("   • Removed: %s\n", autostartFile)
-	fmt.Println("   • Clipboard Manager will not start automatically on login")
-	fmt.Println("   • Run 'clipboard-manager startup-enable' to re-enable")
+	watchClipboard(preset)
 }
-
-// ensureStartupEnabled automatically enables startup if not already configured
-func ensureStartupEnabled() {
-	autostartDir := os.ExpandEnv("$HOME/.config/autostart")
-	autostartFile := autostartDir + "/clipboard-manager.desktop"
-	
-	// Check if already exists and enabled
-	if _, err := os.Stat(autostartFile); err == nil {
-		// File exists, check if it's enabled
-		content, readErr := os.ReadFile(autostartFile)
-		if readErr == nil {
-			contentStr := string(content)
-			// If not explicitly disabled, assume it's good
-			if !strings.Contains(contentStr, "Hidden=true") && !strings.Contains(contentStr, "X-GNOME-Autostart-enabled=false") {
-				return // Already enabled, nothing to do
-			}
-		}
-	}
-	
-	// Create autostart directory if it doesn't exist
-	if err := os.MkdirAll(autostartDir, 0755); err != nil {
-		// Silently fail - don't interrupt the app startup
-		return
-	}
-	
-	// Create the autostart file
-	content := `[Desktop Entry]
-Name=Clipboard Manager
-GenericName=Clipboard History Manager
-Comment=Clipboard history manager with Ctrl+Shift+V hotkey
-Exec=/usr/local/bin/clipboard-manager clipboard-manager
-Icon=edit-copy
-Terminal=false
-Type=Application
-Categories=Utility;System;Accessibility;
-Keywords=clipboard;history;copy;paste;hotkey;
-X-GNOME-Autostart-enabled=true
-X-KDE-autostart-after=panel
-X-MATE-Autostart-enabled=true
-X-XFCE-Autostart-enabled=true
-Hidden=false
-NoDisplay=false
-StartupNotify=false
-X-GNOME-Autostart-Delay=3
-X-KDE-StartupNotify=false
-OnlyShowIn=GNOME;KDE;XFCE;MATE;Unity;Cinnamon;Pantheon;LXQt;LXDE;
-`
-	
-	if err := os.WriteFile(autostartFile, []byte(content), 0644); err != nil {
-		// Silently fail - don't interrupt the app startup
-		return
-	}
-	
-	// Only show message on first-time setup
-	fmt.Println("✅ Auto-enabled startup application")
-	fmt.Println("   • Clipboard Manager will start automatically on login")
-	fmt.Println("   • Use 'clipboard-manager startup-disable' to disable if needed")
-}
\ No newline at end of file
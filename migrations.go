@@ -0,0 +1,486 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+)
+
+// migration is a single versioned schema change. Steps are applied in order,
+// each inside its own transaction, and the resulting version is recorded in
+// SQLite's PRAGMA user_version.
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// schemaMigrations lists every schema change in the order it must be applied.
+// Never edit or reorder an existing step; append a new one instead.
+var schemaMigrations = []migration{
+	{
+		version:     1,
+		description: "create clipboard_history table and indexes",
+		up: func(tx *sql.Tx) error {
+			// IF NOT EXISTS keeps this safe for databases created before
+			// versioning was introduced (user_version 0 with tables present)
+			_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS clipboard_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				type TEXT NOT NULL CHECK(type IN ('text', 'image')),
+				content TEXT NOT NULL,
+				timestamp DATETIME NOT NULL,
+				image_format TEXT,
+				image_width INTEGER,
+				image_height INTEGER,
+				image_size INTEGER,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_timestamp ON clipboard_history(timestamp DESC);
+			CREATE INDEX IF NOT EXISTS idx_type ON clipboard_history(type);
+			`)
+			return err
+		},
+	},
+	{
+		version:     2,
+		description: "add pinned flag so pinned items are exempt from retention",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			ALTER TABLE clipboard_history ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
+			CREATE INDEX IF NOT EXISTS idx_pinned ON clipboard_history(pinned);
+			`)
+			return err
+		},
+	},
+	{
+		version:     3,
+		description: "add source column recording the selection an item came from",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec("ALTER TABLE clipboard_history ADD COLUMN source TEXT NOT NULL DEFAULT 'clipboard'")
+			return err
+		},
+	},
+	{
+		version:     4,
+		description: "add clipboard_formats table for HTML, RTF, URI list and PNG representations",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS clipboard_formats (
+				item_id INTEGER NOT NULL,
+				mime_type TEXT NOT NULL,
+				data BLOB NOT NULL,
+				PRIMARY KEY (item_id, mime_type)
+			);
+			
+			CREATE TRIGGER IF NOT EXISTS clipboard_formats_delete AFTER DELETE ON clipboard_history
+			BEGIN
+				DELETE FROM clipboard_formats WHERE item_id = old.id;
+			END;
+			
+			-- Edited text no longer matches its rich representations
+			CREATE TRIGGER IF NOT EXISTS clipboard_formats_update AFTER UPDATE OF content ON clipboard_history
+			BEGIN
+				DELETE FROM clipboard_formats WHERE item_id = old.id;
+			END;
+			`)
+			return err
+		},
+	},
+	{
+		version:     5,
+		description: "allow the files item type",
+		up: func(tx *sql.Tx) error {
+			// SQLite can't change a CHECK constraint in place, so the table is
+			// rebuilt. Dropping it also drops its indexes and triggers; the
+			// search index triggers are restored by ensureSearchIndex.
+			_, err := tx.Exec(`
+			CREATE TABLE clipboard_history_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				type TEXT NOT NULL CHECK(type IN ('text', 'image', 'files')),
+				content TEXT NOT NULL,
+				timestamp DATETIME NOT NULL,
+				image_format TEXT,
+				image_width INTEGER,
+				image_height INTEGER,
+				image_size INTEGER,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				pinned INTEGER NOT NULL DEFAULT 0,
+				source TEXT NOT NULL DEFAULT 'clipboard'
+			);
+			
+			INSERT INTO clipboard_history_new (id, type, content, timestamp, image_format, image_width, image_height, image_size, created_at, pinned, source)
+			SELECT id, type, content, timestamp, image_format, image_width, image_height, image_size, created_at, pinned, source
+			FROM clipboard_history;
+			
+			DROP TABLE clipboard_history;
+			ALTER TABLE clipboard_history_new RENAME TO clipboard_history;
+			
+			CREATE INDEX idx_timestamp ON clipboard_history(timestamp DESC);
+			CREATE INDEX idx_type ON clipboard_history(type);
+			CREATE INDEX idx_pinned ON clipboard_history(pinned);
+			
+			CREATE TRIGGER clipboard_formats_delete AFTER DELETE ON clipboard_history
+			BEGIN
+				DELETE FROM clipboard_formats WHERE item_id = old.id;
+			END;
+			
+			CREATE TRIGGER clipboard_formats_update AFTER UPDATE OF content ON clipboard_history
+			BEGIN
+				DELETE FROM clipboard_formats WHERE item_id = old.id;
+			END;
+			`)
+			return err
+		},
+	},
+	{
+		version:     6,
+		description: "store images as BLOBs with a SHA-256 content hash",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			ALTER TABLE clipboard_history ADD COLUMN image_data BLOB;
+			ALTER TABLE clipboard_history ADD COLUMN content_hash TEXT;
+			CREATE INDEX IF NOT EXISTS idx_content_hash ON clipboard_history(content_hash);
+			`)
+			if err != nil {
+				return err
+			}
+			return convertBase64Images(tx)
+		},
+	},
+	{
+		version:     7,
+		description: "deduplicate by a unique content hash and count uses",
+		up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE clipboard_history ADD COLUMN use_count INTEGER NOT NULL DEFAULT 1"); err != nil {
+				return err
+			}
+			if err := backfillContentHashes(tx); err != nil {
+				return err
+			}
+			if err := mergeDuplicateItems(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`
+			DROP INDEX IF EXISTS idx_content_hash;
+			CREATE UNIQUE INDEX idx_content_hash ON clipboard_history(type, content_hash);
+			`)
+			return err
+		},
+	},
+	{
+		version:     8,
+		description: "add sensitive column marking passwords, keys and tokens",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec("ALTER TABLE clipboard_history ADD COLUMN sensitive TEXT NOT NULL DEFAULT ''")
+			return err
+		},
+	},
+	{
+		version:     9,
+		description: "add source_app column recording the application an item was copied from",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec("ALTER TABLE clipboard_history ADD COLUMN source_app TEXT NOT NULL DEFAULT ''")
+			return err
+		},
+	},
+	{
+		version:     10,
+		description: "add source_class and source_title columns recording the window an item was copied from",
+		up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE clipboard_history ADD COLUMN source_class TEXT NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+			_, err := tx.Exec("ALTER TABLE clipboard_history ADD COLUMN source_title TEXT NOT NULL DEFAULT ''")
+			return err
+		},
+	},
+	{
+		version:     11,
+		description: "add an index walking unpinned items in timestamp order for retention",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE INDEX idx_retention ON clipboard_history(pinned, timestamp DESC, id DESC, type)")
+			return err
+		},
+	},
+}
+
+// backfillContentHashes hashes the text and file list rows saved before
+// content hashes were used for everything
+func backfillContentHashes(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, type, content FROM clipboard_history WHERE content_hash IS NULL AND type != 'image'")
+	if err != nil {
+		return fmt.Errorf("failed to list unhashed items: %v", err)
+	}
+	hashes := make(map[int64]string)
+	for rows.Next() {
+		var item ClipboardItem
+		var itemType string
+		if err := rows.Scan(&item.ID, &itemType, &item.Content); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan item: %v", err)
+		}
+		item.Type = ClipboardItemType(itemType)
+		hashes[item.ID] = itemContentHash(item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list unhashed items: %v", err)
+	}
+
+	for id, hash := range hashes {
+		if _, err := tx.Exec("UPDATE clipboard_history SET content_hash = ? WHERE id = ?", hash, id); err != nil {
+			return fmt.Errorf("failed to hash item %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// mergeDuplicateItems keeps the newest row of each set of duplicates, counting
+// the others as uses and keeping it pinned if any of them was
+func mergeDuplicateItems(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+	SELECT type, content_hash, COUNT(*), MAX(pinned)
+	FROM clipboard_history
+	WHERE content_hash IS NOT NULL
+	GROUP BY type, content_hash
+	HAVING COUNT(*) > 1
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to find duplicates: %v", err)
+	}
+	type duplicate struct {
+		itemType, hash string
+		count          int
+		pinned         bool
+	}
+	var duplicates []duplicate
+	for rows.Next() {
+		var d duplicate
+		if err := rows.Scan(&d.itemType, &d.hash, &d.count, &d.pinned); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan duplicates: %v", err)
+		}
+		duplicates = append(duplicates, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to find duplicates: %v", err)
+	}
+
+	for _, d := range duplicates {
+		var keep int64
+		err := tx.QueryRow("SELECT id FROM clipboard_history WHERE type = ? AND content_hash = ? ORDER BY timestamp DESC, id DESC LIMIT 1",
+			d.itemType, d.hash).Scan(&keep)
+		if err != nil {
+			return fmt.Errorf("failed to pick duplicate to keep: %v", err)
+		}
+		if _, err := tx.Exec("DELETE FROM clipboard_history WHERE type = ? AND content_hash = ? AND id != ?", d.itemType, d.hash, keep); err != nil {
+			return fmt.Errorf("failed to merge duplicates: %v", err)
+		}
+		if _, err := tx.Exec("UPDATE clipboard_history SET use_count = ?, pinned = ? WHERE id = ?", d.count, d.pinned, keep); err != nil {
+			return fmt.Errorf("failed to merge duplicates: %v", err)
+		}
+	}
+	return nil
+}
+
+// convertBase64Images moves base64 image content into image_data, one row at
+// a time so a large history isn't decoded into memory all at once. Rows that
+// don't decode could never be shown or restored, so they are dropped.
+func convertBase64Images(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id FROM clipboard_history WHERE type = 'image' AND image_data IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to list images: %v", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan image id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list images: %v", err)
+	}
+
+	for _, id := range ids {
+		var encoded string
+		if err := tx.QueryRow("SELECT content FROM clipboard_history WHERE id = ?", id).Scan(&encoded); err != nil {
+			return fmt.Errorf("failed to read image %d: %v", id, err)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(data) == 0 {
+			if _, err := tx.Exec("DELETE FROM clipboard_history WHERE id = ?", id); err != nil {
+				return fmt.Errorf("failed to drop undecodable image %d: %v", id, err)
+			}
+			continue
+		}
+
+		_, err = tx.Exec("UPDATE clipboard_history SET content = '', image_data = ?, content_hash = ?, image_size = COALESCE(image_size, ?) WHERE id = ?",
+			data, contentHash(data), len(data), id)
+		if err != nil {
+			return fmt.Errorf("failed to convert image %d: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// getSchemaVersion returns the schema version recorded in the database
+func getSchemaVersion() (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+
+	return version, nil
+}
+
+// latestSchemaVersion returns the version the database will be at once all steps are applied
+func latestSchemaVersion(steps []migration) int {
+	if len(steps) == 0 {
+		return 0
+	}
+	return steps[len(steps)-1].version
+}
+
+// validateMigrations makes sure versions start at 1 and increase by exactly one
+func validateMigrations(steps []migration) error {
+	for i, step := range steps {
+		if step.version != i+1 {
+			return fmt.Errorf("migration %q has version %d, expected %d", step.description, step.version, i+1)
+		}
+		if step.up == nil {
+			return fmt.Errorf("migration %d has no up function", step.version)
+		}
+	}
+	return nil
+}
+
+// pendingMigrations returns the steps that still need to run for the given version
+func pendingMigrations(steps []migration, current int) []migration {
+	var pending []migration
+	for _, step := range steps {
+		if step.version > current {
+			pending = append(pending, step)
+		}
+	}
+	return pending
+}
+
+// applyMigrations brings the database schema up to date. Each step runs in its
+// own transaction together with the user_version bump, so a failing step
+// leaves the database at the previous version.
+func applyMigrations(steps []migration) error {
+	if err := validateMigrations(steps); err != nil {
+		return err
+	}
+
+	current, err := getSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if current > latestSchemaVersion(steps) {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, latestSchemaVersion(steps))
+	}
+
+	for _, step := range pendingMigrations(steps, current) {
+		if err := applyMigration(step); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", step.version, step.description, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a single step and records its version atomically
+func applyMigration(step migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	if err := step.up(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// PRAGMA doesn't accept bound parameters; version is an int from our own table
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", step.version)); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record schema version: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %v", err)
+	}
+
+	return nil
+}
+
+// runMigrateCommand implements 'clipboard-manager db-migrate [--dry-run]'
+func runMigrateCommand(dryRun bool) error {
+	if err := openDatabase(); err != nil {
+		return err
+	}
+	defer closeDatabase()
+
+	if err := validateMigrations(schemaMigrations); err != nil {
+		return err
+	}
+
+	current, err := getSchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest := latestSchemaVersion(schemaMigrations)
+
+	fmt.Printf("Database: %s\n", getDatabasePath())
+	fmt.Printf("Current schema version: %d\n", current)
+	fmt.Printf("Latest schema version:  %d\n", latest)
+
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, latest)
+	}
+
+	pending := pendingMigrations(schemaMigrations, current)
+	if len(pending) == 0 {
+		fmt.Println("✓ Database schema is up to date")
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("%d migration(s) would be applied:\n", len(pending))
+	} else {
+		fmt.Printf("Applying %d migration(s):\n", len(pending))
+	}
+
+	for _, step := range pending {
+		fmt.Printf("  • %d: %s\n", step.version, step.description)
+		if dryRun {
+			continue
+		}
+		if err := applyMigration(step); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", step.version, step.description, err)
+		}
+	}
+
+	if dryRun {
+		fmt.Println("Dry run: no changes were made")
+	} else {
+		fmt.Printf("✓ Database migrated to schema version %d\n", latest)
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// openRawTestDB opens an empty database without applying any migrations
+func openRawTestDB(t *testing.T) {
+	var err error
+	db, err = sql.Open("sqlite3", filepath.Join(t.TempDir(), "raw_history.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err = db.Ping(); err != nil {
+		t.Fatalf("Failed to ping test database: %v", err)
+	}
+}
+
+func TestMigrationsFreshDatabase(t *testing.T) {
+	openRawTestDB(t)
+	defer teardownTestDB(t)
+
+	if err := applyMigrations(schemaMigrations); err != nil {
+		t.Fatalf("applyMigrations() failed: %v", err)
+	}
+
+	version, err := getSchemaVersion()
+	if err != nil {
+		t.Fatalf("getSchemaVersion() failed: %v", err)
+	}
+	if version != latestSchemaVersion(schemaMigrations) {
+		t.Errorf("Expected schema version %d, got %d", latestSchemaVersion(schemaMigrations), version)
+	}
+
+	// Running again must be a no-op
+	if err := applyMigrations(schemaMigrations); err != nil {
+		t.Fatalf("second applyMigrations() failed: %v", err)
+	}
+}
+
+func TestMigrationsLegacyDatabase(t *testing.T) {
+	openRawTestDB(t)
+	defer teardownTestDB(t)
+
+	// Simulate a database created before versioning (user_version 0, table present)
+	_, err := db.Exec(`
+	CREATE TABLE clipboard_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL CHECK(type IN ('text', 'image')),
+		content TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		image_format TEXT,
+		image_width INTEGER,
+		image_height INTEGER,
+		image_size INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO clipboard_history (type, content, timestamp) VALUES ('text', 'legacy item', CURRENT_TIMESTAMP);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	if err := applyMigrations(schemaMigrations); err != nil {
+		t.Fatalf("applyMigrations() failed on legacy database: %v", err)
+	}
+
+	items, err := loadClipboardHistory()
+	if err != nil {
+		t.Fatalf("loadClipboardHistory() failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Content != "legacy item" {
+		t.Fatalf("Expected legacy row to survive migration, got %+v", items)
+	}
+	if items[0].Source != SourceClipboard {
+		t.Errorf("Expected legacy row to default to the clipboard source, got %q", items[0].Source)
+	}
+}
+
+func TestMigrationsConvertBase64Images(t *testing.T) {
+	openRawTestDB(t)
+	defer teardownTestDB(t)
+
+	imageData, err := createTestImage()
+	if err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+
+	if err := applyMigrations(schemaMigrations[:5]); err != nil {
+		t.Fatalf("applyMigrations() failed: %v", err)
+	}
+	insert := "INSERT INTO clipboard_history (type, content, timestamp, image_format, image_size) VALUES ('image', ?, CURRENT_TIMESTAMP, 'png', ?)"
+	if _, err := db.Exec(insert, base64.StdEncoding.EncodeToString(imageData), len(imageData)); err != nil {
+		t.Fatalf("Failed to insert base64 image: %v", err)
+	}
+	if _, err := db.Exec(insert, "not base64!", 10); err != nil {
+		t.Fatalf("Failed to insert corrupt image: %v", err)
+	}
+
+	if err := applyMigrations(schemaMigrations); err != nil {
+		t.Fatalf("applyMigrations() failed: %v", err)
+	}
+
+	items, err := loadClipboardHistory()
+	if err != nil {
+		t.Fatalf("loadClipboardHistory() failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected only the decodable image to remain, got %d items", len(items))
+	}
+	stored, err := loadImageData(items[0].ID)
+	if err != nil || !bytes.Equal(stored, imageData) || items[0].Content != "" {
+		t.Errorf("Expected the image bytes to move out of content, got %d bytes and %d chars (%v)", len(stored), len(items[0].Content), err)
+	}
+	if items[0].ContentHash != contentHash(imageData) {
+		t.Errorf("Expected the content hash to be filled in, got %q", items[0].ContentHash)
+	}
+}
+
+func TestMigrationsMergeDuplicates(t *testing.T) {
+	openRawTestDB(t)
+	defer teardownTestDB(t)
+
+	if err := applyMigrations(schemaMigrations[:6]); err != nil {
+		t.Fatalf("applyMigrations() failed: %v", err)
+	}
+	_, err := db.Exec(`
+	INSERT INTO clipboard_history (type, content, timestamp, pinned) VALUES ('text', 'dup', '2024-01-01 10:00:00', 1);
+	INSERT INTO clipboard_history (type, content, timestamp) VALUES ('text', 'unique', '2024-01-01 11:00:00');
+	INSERT INTO clipboard_history (type, content, timestamp) VALUES ('text', 'dup', '2024-01-01 12:00:00');
+	INSERT INTO clipboard_history (type, content, timestamp) VALUES ('files', 'dup', '2024-01-01 13:00:00');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert duplicates: %v", err)
+	}
+
+	if err := applyMigrations(schemaMigrations); err != nil {
+		t.Fatalf("applyMigrations() failed: %v", err)
+	}
+
+	items, err := loadClipboardHistory()
+	if err != nil {
+		t.Fatalf("loadClipboardHistory() failed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected the text duplicates to be merged, got %+v", items)
+	}
+	merged := items[1]
+	if merged.ID != 3 || merged.UseCount != 2 || !merged.Pinned {
+		t.Errorf("Expected the newest copy to be kept, pinned, with 2 uses, got %+v", merged)
+	}
+
+	// The unique index rejects a second row with the same hash
+	_, err = db.Exec("INSERT INTO clipboard_history (type, content, timestamp, content_hash) VALUES ('text', 'dup', CURRENT_TIMESTAMP, ?)", merged.ContentHash)
+	if err == nil {
+		t.Error("Expected a duplicate content hash to be rejected")
+	}
+}
+
+func TestMigrationsRollbackOnFailure(t *testing.T) {
+	openRawTestDB(t)
+	defer teardownTestDB(t)
+
+	steps := []migration{
+		{
+			version:     1,
+			description: "create table",
+			up: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE example (id INTEGER PRIMARY KEY)")
+				return err
+			},
+		},
+		{
+			version:     2,
+			description: "partially applied step",
+			up: func(tx *sql.Tx) error {
+				if _, err := tx.Exec("ALTER TABLE example ADD COLUMN name TEXT"); err != nil {
+					return err
+				}
+				return fmt.Errorf("simulated failure")
+			},
+		},
+	}
+
+	if err := applyMigrations(steps); err == nil {
+		t.Fatal("Expected applyMigrations() to fail")
+	}
+
+	version, err := getSchemaVersion()
+	if err != nil {
+		t.Fatalf("getSchemaVersion() failed: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("Expected schema version 1 after failed step, got %d", version)
+	}
+
+	// The column added by the failed step must have been rolled back
+	if _, err := db.Exec("INSERT INTO example (name) VALUES ('x')"); err == nil {
+		t.Error("Expected column from failed migration to be rolled back")
+	}
+}
+
+func TestMigrationsRejectNewerDatabase(t *testing.T) {
+	openRawTestDB(t)
+	defer teardownTestDB(t)
+
+	newer := latestSchemaVersion(schemaMigrations) + 1
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", newer)); err != nil {
+		t.Fatalf("Failed to set user_version: %v", err)
+	}
+
+	if err := applyMigrations(schemaMigrations); err == nil {
+		t.Error("Expected error for database newer than this build")
+	}
+}
+
+func TestValidateMigrations(t *testing.T) {
+	noop := func(tx *sql.Tx) error { return nil }
+
+	tests := []struct {
+		name    string
+		steps   []migration
+		wantErr bool
+	}{
+		{
+			name:  "Registered migrations",
+			steps: schemaMigrations,
+		},
+		{
+			name:    "Gap in versions",
+			steps:   []migration{{version: 1, up: noop}, {version: 3, up: noop}},
+			wantErr: true,
+		},
+		{
+			name:    "Out of order",
+			steps:   []migration{{version: 2, up: noop}, {version: 1, up: noop}},
+			wantErr: true,
+		},
+		{
+			name:    "Missing up function",
+			steps:   []migration{{version: 1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMigrations(tt.steps)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	noop := func(tx *sql.Tx) error { return nil }
+	steps := []migration{{version: 1, up: noop}, {version: 2, up: noop}, {version: 3, up: noop}}
+
+	pending := pendingMigrations(steps, 1)
+	if len(pending) != 2 || pending[0].version != 2 || pending[1].version != 3 {
+		t.Errorf("Expected versions 2 and 3 pending, got %+v", pending)
+	}
+
+	if len(pendingMigrations(steps, 3)) != 0 {
+		t.Error("Expected no pending migrations at latest version")
+	}
+}
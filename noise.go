@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FilterRules are noise rules that apply to a single item type. Any length or
+// whitespace setting replaces the global one; include and exclude patterns
+// are added to the global ones.
+type FilterRules struct {
+	MinLength      *int     `toml:"min_length"`
+	MaxLength      *int     `toml:"max_length"`
+	SkipWhitespace *bool    `toml:"skip_whitespace"`
+	Include        []string `toml:"include"`
+	Exclude        []string `toml:"exclude"`
+}
+
+// FilterSettings is the [filter] section of the config file. It decides which
+// copies are too trivial or unwanted to keep in history. Text is dropped if it
+// is whitespace only, shorter than MinLength, longer than MaxLength, matches an
+// Exclude pattern, or fails to match any Include pattern when there are some.
+type FilterSettings struct {
+	MinLength      int      `toml:"min_length"`      // Shorter copies are ignored
+	MaxLength      int      `toml:"max_length"`      // Longer copies are ignored; 0 means no limit
+	SkipWhitespace bool     `toml:"skip_whitespace"` // Ignore copies that are only whitespace
+	Include        []string `toml:"include"`         // If set, only copies matching one of these are kept
+	Exclude        []string `toml:"exclude"`         // Copies matching any of these are ignored
+
+	Text  FilterRules `toml:"text"`  // Also used for PRIMARY selections
+	Files FilterRules `toml:"files"` // Matched against the paths, one per line
+	Image FilterRules `toml:"image"` // Only its own length rules apply, in bytes
+
+	// Settings from older config files, read as text exclude rules that only
+	// drop copies shorter than NoiseMaxLength. Use Exclude instead.
+	NoisePatterns  []string `toml:"noise_patterns,omitempty"`   // Text containing any of these is ignored
+	NoiseMaxLength int      `toml:"noise_max_length,omitempty"` // Defaults to defaultNoiseMaxLength
+}
+
+// defaultNoiseMaxLength is the length below which noise_patterns applied
+// when noise_max_length wasn't set
+const defaultNoiseMaxLength = 50
+
+// defaultFilterSettings ignores blank and one- or two-character copies
+func defaultFilterSettings() FilterSettings {
+	return FilterSettings{
+		MinLength:      3,
+		SkipWhitespace: true,
+	}
+}
+
+// validate checks the lengths and compiles every pattern
+func (f FilterSettings) validate() error {
+	for _, itemType := range []ClipboardItemType{ItemTypeText, ItemTypeFiles, ItemTypeImage} {
+		if _, err := f.compile(itemType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rules returns the per-type rules for an item type
+func (f FilterSettings) rules(itemType ClipboardItemType) FilterRules {
+	switch itemType {
+	case ItemTypeFiles:
+		return f.Files
+	case ItemTypeImage:
+		return f.Image
+	default:
+		return f.Text
+	}
+}
+
+// filterPattern is a compiled include or exclude rule
+type filterPattern struct {
+	re          *regexp.Regexp
+	section     string // Config section the rule came from, e.g. "filter.text"
+	key         string // Setting the rule came from, e.g. "exclude"
+	shorterThan int    // If set, the rule only applies to shorter copies
+}
+
+// noiseFilter is the compiled set of rules for one item type
+type noiseFilter struct {
+	itemType       ClipboardItemType
+	minLength      int
+	maxLength      int
+	skipWhitespace bool
+	lengthSection  map[string]string // Section each length or whitespace rule came from
+	include        []filterPattern
+	exclude        []filterPattern
+}
+
+// compile resolves the rules for an item type and compiles their patterns
+func (f FilterSettings) compile(itemType ClipboardItemType) (*noiseFilter, error) {
+	typed := f.rules(itemType)
+	typedSection := "filter." + string(itemType)
+
+	nf := &noiseFilter{
+		itemType:       itemType,
+		minLength:      f.MinLength,
+		maxLength:      f.MaxLength,
+		skipWhitespace: f.SkipWhitespace,
+		lengthSection: map[string]string{
+			"min_length":      "filter",
+			"max_length":      "filter",
+			"skip_whitespace": "filter",
+		},
+	}
+	if itemType == ItemTypeImage {
+		// Image sizes are in bytes, so the global text lengths don't apply
+		nf.minLength, nf.maxLength = 0, 0
+		nf.lengthSection["min_length"] = typedSection
+		nf.lengthSection["max_length"] = typedSection
+	}
+	if typed.MinLength != nil {
+		nf.minLength = *typed.MinLength
+		nf.lengthSection["min_length"] = typedSection
+	}
+	if typed.MaxLength != nil {
+		nf.maxLength = *typed.MaxLength
+		nf.lengthSection["max_length"] = typedSection
+	}
+	if typed.SkipWhitespace != nil {
+		nf.skipWhitespace = *typed.SkipWhitespace
+		nf.lengthSection["skip_whitespace"] = typedSection
+	}
+	if nf.minLength < 0 || nf.maxLength < 0 || f.NoiseMaxLength < 0 {
+		return nil, fmt.Errorf("filter lengths must not be negative")
+	}
+
+	var err error
+	if nf.include, err = compilePatterns(nf.include, f.Include, "filter", "include"); err != nil {
+		return nil, err
+	}
+	if nf.include, err = compilePatterns(nf.include, typed.Include, typedSection, "include"); err != nil {
+		return nil, err
+	}
+	if nf.exclude, err = compilePatterns(nf.exclude, f.Exclude, "filter", "exclude"); err != nil {
+		return nil, err
+	}
+	if nf.exclude, err = compilePatterns(nf.exclude, typed.Exclude, typedSection, "exclude"); err != nil {
+		return nil, err
+	}
+	if itemType == ItemTypeText {
+		nf.exclude = append(nf.exclude, f.noisePatterns()...)
+	}
+	return nf, nil
+}
+
+// compilePatterns appends the compiled patterns from one config section
+func compilePatterns(dst []filterPattern, patterns []string, section, key string) ([]filterPattern, error) {
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s.%s pattern %q: %v", section, key, pattern, err)
+		}
+		dst = append(dst, filterPattern{re: re, section: section, key: key})
+	}
+	return dst, nil
+}
+
+// noisePatterns converts noise_patterns from an older config file into
+// exclude rules: each is a plain substring, and only short text is dropped
+func (f FilterSettings) noisePatterns() []filterPattern {
+	maxLength := f.NoiseMaxLength
+	if maxLength == 0 {
+		maxLength = defaultNoiseMaxLength
+	}
+	var patterns []filterPattern
+	for _, pattern := range f.NoisePatterns {
+		if pattern != "" {
+			re := regexp.MustCompile(regexp.QuoteMeta(pattern))
+			patterns = append(patterns, filterPattern{re: re, section: "filter", key: "noise_patterns", shorterThan: maxLength})
+		}
+	}
+	return patterns
+}
+
+// filterDecision is the outcome of checking a copy against the noise rules
+type filterDecision struct {
+	Keep   bool
+	Rule   string // The rule that dropped the copy, or the include pattern that kept it
+	Reason string // A short explanation for test-filter
+}
+
+// check decides whether content of the filter's item type is kept. Images
+// are only checked against the length rules, using their size in bytes.
+func (nf *noiseFilter) check(content string) filterDecision {
+	length, unit := len(content), "bytes"
+	if nf.itemType != ItemTypeImage {
+		length, unit = utf8.RuneCountInString(content), "characters"
+
+		if nf.skipWhitespace && strings.TrimSpace(content) == "" {
+			return filterDecision{Rule: nf.lengthSection["skip_whitespace"] + ".skip_whitespace", Reason: "it is empty or only whitespace"}
+		}
+	}
+
+	if length < nf.minLength {
+		return filterDecision{
+			Rule:   fmt.Sprintf("%s.min_length = %d", nf.lengthSection["min_length"], nf.minLength),
+			Reason: fmt.Sprintf("it is %d %s long", length, unit),
+		}
+	}
+	if nf.maxLength > 0 && length > nf.maxLength {
+		return filterDecision{
+			Rule:   fmt.Sprintf("%s.max_length = %d", nf.lengthSection["max_length"], nf.maxLength),
+			Reason: fmt.Sprintf("it is %d %s long", length, unit),
+		}
+	}
+	if nf.itemType == ItemTypeImage {
+		return filterDecision{Keep: true, Reason: "no rule matched"}
+	}
+
+	for _, p := range nf.exclude {
+		if p.shorterThan > 0 && length >= p.shorterThan {
+			continue
+		}
+		if p.re.MatchString(content) {
+			return filterDecision{Rule: fmt.Sprintf("%s.%s %q", p.section, p.key, p.re.String()), Reason: "it matches an exclude pattern"}
+		}
+	}
+
+	if len(nf.include) == 0 {
+		return filterDecision{Keep: true, Reason: "no rule matched"}
+	}
+	for _, p := range nf.include {
+		if p.re.MatchString(content) {
+			return filterDecision{Keep: true, Rule: fmt.Sprintf("%s.%s %q", p.section, p.key, p.re.String()), Reason: "it matches an include pattern"}
+		}
+	}
+	return filterDecision{Rule: nf.include[0].section + ".include", Reason: "it matches none of the include patterns"}
+}
+
+// filterCache holds the compiled rules for the current filter settings
+var filterCache struct {
+	sync.Mutex
+	settings FilterSettings
+	filters  map[ClipboardItemType]*noiseFilter
+}
+
+// currentNoiseFilter returns the compiled rules for an item type from the
+// current configuration, recompiling them after a config reload
+func currentNoiseFilter(itemType ClipboardItemType) *noiseFilter {
+	settings := getConfig().Filter
+
+	filterCache.Lock()
+	defer filterCache.Unlock()
+
+	if filterCache.filters == nil || !reflect.DeepEqual(settings, filterCache.settings) {
+		filterCache.settings = settings
+		filterCache.filters = map[ClipboardItemType]*noiseFilter{}
+	}
+	if nf, ok := filterCache.filters[itemType]; ok {
+		return nf
+	}
+
+	nf, err := settings.compile(itemType)
+	if err != nil {
+		// The config is validated when loaded, so this only guards against
+		// settings changed in code
+		nf, _ = defaultFilterSettings().compile(itemType)
+	}
+	filterCache.filters[itemType] = nf
+	return nf
+}
+
+// checkNoise checks copied content of an item type against the noise rules
+func checkNoise(itemType ClipboardItemType, content string) filterDecision {
+	return currentNoiseFilter(itemType).check(content)
+}
+
+// isNoise reports whether copied content of an item type should be ignored
+func isNoise(itemType ClipboardItemType, content string) bool {
+	return !checkNoise(itemType, content).Keep
+}
+
+// normalizeCaptured puts content in the form the capture paths check filter
+// rules against: text is trimmed, and a file list has one path per line with
+// no blank lines
+func normalizeCaptured(itemType ClipboardItemType, content string) string {
+	switch itemType {
+	case ItemTypeText:
+		return strings.TrimSpace(content)
+	case ItemTypeFiles:
+		var paths []string
+		for _, line := range strings.Split(content, "\n") {
+			if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+				paths = append(paths, line)
+			}
+		}
+		return strings.Join(paths, "\n")
+	}
+	return content
+}
+
+// runTestFilterCommand handles 'test-filter [--type text|files|image] <text>',
+// explaining whether the text would be recorded. Without text it reads stdin.
+func runTestFilterCommand(args []string, stdin func() (string, error)) error {
+	itemType := ItemTypeText
+	if len(args) >= 2 && args[0] == "--type" {
+		itemType = ClipboardItemType(args[1])
+		args = args[2:]
+	}
+	switch itemType {
+	case ItemTypeText, ItemTypeFiles, ItemTypeImage:
+	default:
+		return fmt.Errorf("unknown type %q (expected text, files or image)", itemType)
+	}
+
+	var content string
+	if len(args) > 0 {
+		content = strings.Join(args, " ")
+	} else {
+		var err error
+		if content, err = stdin(); err != nil {
+			return fmt.Errorf("failed to read stdin: %v", err)
+		}
+	}
+
+	decision := checkNoise(itemType, normalizeCaptured(itemType, content))
+	if decision.Keep {
+		fmt.Printf("✓ Recorded: %s\n", decision.Reason)
+	} else {
+		fmt.Printf("✗ Ignored: %s\n", decision.Reason)
+	}
+	if decision.Rule != "" {
+		fmt.Printf("  Rule: %s\n", decision.Rule)
+	}
+	return nil
+}
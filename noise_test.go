@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// useFilterConfig parses a [filter] section into the global configuration
+// for the rest of the test
+func useFilterConfig(t *testing.T, data string) {
+	t.Helper()
+	restoreConfigAfter(t)
+	cfg, err := parseConfig(data)
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+	configMu.Lock()
+	config = cfg
+	configMu.Unlock()
+}
+
+func TestDefaultFilterKeepsCode(t *testing.T) {
+	restoreConfigAfter(t)
+
+	for _, text := range []string{"import \"os\"", "func main() {}", "package main"} {
+		if isNoise(ItemTypeText, text) {
+			t.Errorf("Expected %q to be recorded", text)
+		}
+	}
+	for _, text := range []string{"ok", " \t\n "} {
+		if !isNoise(ItemTypeText, text) {
+			t.Errorf("Expected %q to be ignored", text)
+		}
+	}
+}
+
+func TestFilterRules(t *testing.T) {
+	useFilterConfig(t, `
+[filter]
+min_length = 2
+max_length = 40
+exclude = ["^\\d{6}$"]
+
+[filter.text]
+exclude = ["(?i)^password:"]
+
+[filter.files]
+include = ["(?m)\\.pdf$"]
+
+[filter.image]
+max_length = 100
+`)
+
+	tests := []struct {
+		name     string
+		itemType ClipboardItemType
+		content  string
+		keep     bool
+		rule     string
+	}{
+		{name: "Plain text", itemType: ItemTypeText, content: "hello", keep: true},
+		{name: "Too short", itemType: ItemTypeText, content: "a", rule: "filter.min_length = 2"},
+		{name: "Too long", itemType: ItemTypeText, content: strings.Repeat("x", 41), rule: "filter.max_length = 40"},
+		{name: "Global exclude", itemType: ItemTypeText, content: "123456", rule: `filter.exclude "^\\d{6}$"`},
+		{name: "Text exclude", itemType: ItemTypeText, content: "Password: hunter2", rule: `filter.text.exclude "(?i)^password:"`},
+		{name: "Text exclude leaves files alone", itemType: ItemTypeFiles, content: "/tmp/password:.pdf", keep: true, rule: `filter.files.include "(?m)\\.pdf$"`},
+		{name: "Files outside include", itemType: ItemTypeFiles, content: "/tmp/a.txt\n/tmp/b.txt", rule: "filter.files.include"},
+		{name: "Small image", itemType: ItemTypeImage, content: strings.Repeat("x", 100), keep: true},
+		{name: "Large image", itemType: ItemTypeImage, content: strings.Repeat("x", 101), rule: "filter.image.max_length = 100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := checkNoise(tt.itemType, tt.content)
+			if decision.Keep != tt.keep || decision.Rule != tt.rule {
+				t.Errorf("checkNoise() = %+v; want keep %v by rule %q", decision, tt.keep, tt.rule)
+			}
+		})
+	}
+}
+
+func TestFilterConfigErrors(t *testing.T) {
+	if _, err := parseConfig("[filter]\nexclude = [\"(unclosed\"]\n"); err == nil || !strings.Contains(err.Error(), "filter.exclude") {
+		t.Errorf("Expected the invalid pattern to be named, got %v", err)
+	}
+	if _, err := parseConfig("[filter.text]\nmin_length = -1\n"); err == nil {
+		t.Error("Expected a negative length to be rejected")
+	}
+}
+
+func TestWatcherAppliesFilter(t *testing.T) {
+	useFilterConfig(t, "[filter]\nexclude = [\"^secret\"]\n")
+
+	backend := newMemoryBackend()
+	w, texts, _ := newTestWatcher(watcherPresets["text-only"], backend)
+
+	writeText(backend, "secret value")
+	w.check(false)
+	writeText(backend, "kept value")
+	w.check(false)
+
+	if len(*texts) != 1 || (*texts)[0] != "kept value" {
+		t.Errorf("Expected only the text outside the exclude rule, got %v", *texts)
+	}
+}
+
+func TestTestFilterCommand(t *testing.T) {
+	useFilterConfig(t, "[filter]\nexclude = [\"^secret\"]\n")
+
+	noStdin := func() (string, error) {
+		t.Fatal("Expected stdin not to be read when text is given")
+		return "", nil
+	}
+	if err := runTestFilterCommand([]string{"secret", "value"}, noStdin); err != nil {
+		t.Errorf("runTestFilterCommand() failed: %v", err)
+	}
+
+	stdin := func() (string, error) {
+		return "from stdin\n", nil
+	}
+	if err := runTestFilterCommand([]string{"--type", "files"}, stdin); err != nil {
+		t.Errorf("runTestFilterCommand() failed: %v", err)
+	}
+	if err := runTestFilterCommand([]string{"--type", "video", "x"}, stdin); err == nil {
+		t.Error("Expected an unknown type to be rejected")
+	}
+}
+
+func TestTestFilterMatchesCapture(t *testing.T) {
+	useFilterConfig(t, "[filter]\nexclude = [\"^token$\"]\n")
+
+	padded := func() (string, error) {
+		return "  token \n\n", nil
+	}
+	if !isNoise(ItemTypeText, normalizeCaptured(ItemTypeText, "  token \n\n")) {
+		t.Fatal("Expected capture to ignore the padded text")
+	}
+	output := captureStdout(t, func() {
+		if err := runTestFilterCommand(nil, padded); err != nil {
+			t.Errorf("runTestFilterCommand() failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Ignored") {
+		t.Errorf("Expected test-filter to agree with capture, got %q", output)
+	}
+
+	if got := normalizeCaptured(ItemTypeFiles, "/tmp/a\r\n\n/tmp/b c\n"); got != "/tmp/a\n/tmp/b c" {
+		t.Errorf("normalizeCaptured(files) = %q", got)
+	}
+}
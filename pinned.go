@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// partitionPinned moves pinned items ahead of the rest, keeping the relative
+// order within each group
+func partitionPinned(items []ClipboardItem) (pinned, others []ClipboardItem) {
+	for _, item := range items {
+		if item.Pinned {
+			pinned = append(pinned, item)
+		} else {
+			others = append(others, item)
+		}
+	}
+	return pinned, others
+}
+
+// parseItemID parses the single item ID argument of pin, unpin, get and delete
+func parseItemID(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected exactly one item ID")
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid item ID %q", args[0])
+	}
+	return id, nil
+}
+
+// runPinCommand implements 'clipboard-manager pin <id>' and 'clipboard-manager unpin <id>'
+func runPinCommand(args []string, pinned bool) error {
+	id, err := parseItemID(args)
+	if err != nil {
+		return err
+	}
+
+	return setHistoryItemPinned(id, pinned)
+}
+
+// parseClearArgs parses the options of 'clipboard-manager clear'
+func parseClearArgs(args []string) (force bool, err error) {
+	for _, arg := range args {
+		switch arg {
+		case "--force", "-f":
+			force = true
+		default:
+			return false, fmt.Errorf("unknown option %s", arg)
+		}
+	}
+	return force, nil
+}
+
+// runClearCommand implements 'clipboard-manager clear [--force]'
+func runClearCommand(args []string) error {
+	force, err := parseClearArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if force {
+		return forceClearHistory()
+	}
+	return clearHistory()
+}
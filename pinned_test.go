@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClearHistoryKeepsPinned(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	testItems := []ClipboardItem{
+		{Type: ItemTypeText, Content: "item1", Timestamp: time.Now()},
+		{Type: ItemTypeText, Content: "item2", Timestamp: time.Now()},
+		{Type: ItemTypeText, Content: "item3", Timestamp: time.Now()},
+	}
+	addTestItems(t, testItems)
+
+	target := getTestHistoryItem(1)
+	if err := setHistoryItemPinned(target.ID, true); err != nil {
+		t.Fatalf("setHistoryItemPinned() failed: %v", err)
+	}
+
+	if err := clearHistory(); err != nil {
+		t.Fatalf("clearHistory() failed: %v", err)
+	}
+
+	if getTestHistoryLength() != 1 {
+		t.Fatalf("Expected only the pinned item to remain, got length %d", getTestHistoryLength())
+	}
+	if kept := getTestHistoryItem(0); kept.ID != target.ID || !kept.Pinned {
+		t.Errorf("Expected pinned item %d to remain, got %+v", target.ID, kept)
+	}
+
+	if err := forceClearHistory(); err != nil {
+		t.Fatalf("forceClearHistory() failed: %v", err)
+	}
+	if getTestHistoryLength() != 0 {
+		t.Errorf("Expected forced clear to remove pinned items, got length %d", getTestHistoryLength())
+	}
+}
+
+func TestSetHistoryItemPinned(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	addTestItems(t, []ClipboardItem{
+		{Type: ItemTypeText, Content: "snippet", Timestamp: time.Now()},
+	})
+	id := getTestHistoryItem(0).ID
+
+	if err := setHistoryItemPinned(id, true); err != nil {
+		t.Fatalf("setHistoryItemPinned(true) failed: %v", err)
+	}
+	if !getTestHistoryItem(0).Pinned {
+		t.Error("Expected item to be pinned")
+	}
+
+	if err := setHistoryItemPinned(id, false); err != nil {
+		t.Fatalf("setHistoryItemPinned(false) failed: %v", err)
+	}
+	if getTestHistoryItem(0).Pinned {
+		t.Error("Expected item to be unpinned")
+	}
+
+	if err := setHistoryItemPinned(id+100, true); err == nil {
+		t.Error("Expected error when pinning a nonexistent item")
+	}
+}
+
+func TestRecopyKeepsPinned(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	addTestItems(t, []ClipboardItem{
+		{Type: ItemTypeText, Content: "favourite", Timestamp: time.Now()},
+		{Type: ItemTypeText, Content: "other", Timestamp: time.Now()},
+	})
+	if err := setHistoryItemPinned(getTestHistoryItem(0).ID, true); err != nil {
+		t.Fatalf("setHistoryItemPinned() failed: %v", err)
+	}
+
+	// Copying the same text again moves it to the end but must not unpin it
+	addToHistory("favourite")
+
+	last := getTestHistoryItem(getTestHistoryLength() - 1)
+	if last.Content != "favourite" || !last.Pinned {
+		t.Errorf("Expected re-copied item to stay pinned, got %+v", last)
+	}
+}
+
+func TestPartitionPinned(t *testing.T) {
+	items := []ClipboardItem{
+		{ID: 1},
+		{ID: 2, Pinned: true},
+		{ID: 3},
+		{ID: 4, Pinned: true},
+	}
+
+	pinned, others := partitionPinned(items)
+
+	if len(pinned) != 2 || pinned[0].ID != 2 || pinned[1].ID != 4 {
+		t.Errorf("Expected pinned IDs [2 4] in order, got %+v", pinned)
+	}
+	if len(others) != 2 || others[0].ID != 1 || others[1].ID != 3 {
+		t.Errorf("Expected other IDs [1 3] in order, got %+v", others)
+	}
+}
+
+func TestRunPinCommandInvalidID(t *testing.T) {
+	for _, args := range [][]string{{}, {"abc"}, {"0"}, {"1", "2"}} {
+		if err := runPinCommand(args, true); err == nil {
+			t.Errorf("Expected error for args %v", args)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// popupPageSize is how many rows PageUp/PageDown move the selection
+const popupPageSize = 5
+
+// popupNavigator tracks the keyboard selection in the history popup and maps
+// keys to actions on the selected item
+type popupNavigator struct {
+	items    []*HistoryListItem
+	selected int
+
+	// listMode is true while keys go to the list rather than the search entry
+	listMode bool
+
+	// Actions, wired up by refreshUI
+	onClose  func()
+	onSearch func()
+	onMove   func(item *HistoryListItem)
+}
+
+// popupNav keeps the selection across in-place refreshes such as deletes
+var popupNav = &popupNavigator{}
+
+// SetItems replaces the rows being navigated, keeping the selection in range
+func (n *popupNavigator) SetItems(items []*HistoryListItem) {
+	n.items = items
+	n.Select(n.selected)
+}
+
+// Select moves the selection to index, clamped to the list bounds
+func (n *popupNavigator) Select(index int) {
+	if len(n.items) == 0 {
+		n.selected = 0
+		return
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(n.items) {
+		index = len(n.items) - 1
+	}
+
+	n.selected = index
+	for i, item := range n.items {
+		item.SetSelected(i == index)
+	}
+	if n.onMove != nil {
+		n.onMove(n.items[index])
+	}
+}
+
+// Move shifts the selection by delta rows
+func (n *popupNavigator) Move(delta int) {
+	n.Select(n.selected + delta)
+}
+
+// Selected returns the selected row, or nil when the list is empty
+func (n *popupNavigator) Selected() *HistoryListItem {
+	if n.selected < 0 || n.selected >= len(n.items) {
+		return nil
+	}
+	return n.items[n.selected]
+}
+
+// handleKey performs the action bound to a navigation key and reports whether it was used
+func (n *popupNavigator) handleKey(key fyne.KeyName) bool {
+	switch key {
+	case fyne.KeyUp:
+		n.Move(-1)
+	case fyne.KeyDown:
+		n.Move(1)
+	case fyne.KeyPageUp:
+		n.Move(-popupPageSize)
+	case fyne.KeyPageDown:
+		n.Move(popupPageSize)
+	case fyne.KeyHome:
+		n.Select(0)
+	case fyne.KeyEnd:
+		n.Select(len(n.items) - 1)
+	case fyne.KeyReturn, fyne.KeyEnter:
+		if item := n.Selected(); item != nil && item.onSelect != nil {
+			item.onSelect(item.item.ID)
+		}
+	case fyne.KeyDelete:
+		if item := n.Selected(); item != nil && item.onDelete != nil {
+			item.onDelete(item.item.ID)
+		}
+	case fyne.KeyEscape:
+		if n.onClose != nil {
+			n.onClose()
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// handleRune handles typed characters while the list has focus:
+// 1-9 restore that row, E edits and P pins the selection, / jumps to the search box
+func (n *popupNavigator) handleRune(r rune) bool {
+	switch {
+	case r >= '1' && r <= '9':
+		index := int(r - '1')
+		if index >= len(n.items) {
+			return false
+		}
+		n.Select(index)
+		return n.handleKey(fyne.KeyReturn)
+	case r == 'e' || r == 'E':
+		if item := n.Selected(); item != nil && item.onEdit != nil && item.item.Type == ItemTypeText {
+			item.onEdit(item.item.ID)
+		}
+	case r == 'p' || r == 'P':
+		if item := n.Selected(); item != nil && item.onPin != nil {
+			item.onPin(item.item.ID)
+		}
+	case r == '/':
+		if n.onSearch != nil {
+			n.onSearch()
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// popupSearchEntry is the popup's search box. Navigation keys move the list
+// selection instead of the text cursor so the popup never needs the mouse.
+type popupSearchEntry struct {
+	widget.Entry
+	onNavigate func(key fyne.KeyName) bool
+	onFocus    func()
+}
+
+// newPopupSearchEntry creates a single-line search entry
+func newPopupSearchEntry() *popupSearchEntry {
+	entry := &popupSearchEntry{}
+	entry.ExtendBaseWidget(entry)
+	return entry
+}
+
+// TypedKey intercepts navigation keys before the entry handles them
+func (e *popupSearchEntry) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyUp, fyne.KeyDown, fyne.KeyPageUp, fyne.KeyPageDown,
+		fyne.KeyReturn, fyne.KeyEnter, fyne.KeyEscape:
+		if e.onNavigate != nil && e.onNavigate(key.Name) {
+			return
+		}
+	}
+	e.Entry.TypedKey(key)
+}
+
+// FocusGained notifies the popup that keys go to the search box again
+func (e *popupSearchEntry) FocusGained() {
+	if e.onFocus != nil {
+		e.onFocus()
+	}
+	e.Entry.FocusGained()
+}
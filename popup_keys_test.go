@@ -0,0 +1,159 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+)
+
+// newTestNavigator builds a navigator over n text rows with IDs 1..n and
+// records which ID each action was invoked with
+func newTestNavigator(n int) (*popupNavigator, map[string]int64) {
+	calls := make(map[string]int64)
+	record := func(action string) func(int64) {
+		return func(id int64) { calls[action] = id }
+	}
+
+	nav := &popupNavigator{}
+	items := make([]*HistoryListItem, 0, n)
+	for i := 0; i < n; i++ {
+		item := ClipboardItem{ID: int64(i + 1), Type: ItemTypeText, Content: "item"}
+		items = append(items, NewHistoryListItem(item, i, record("delete"), record("select"), record("edit"), record("pin")))
+	}
+	nav.onClose = func() { calls["close"] = 1 }
+	nav.SetItems(items)
+
+	return nav, calls
+}
+
+// TestPopupNavigatorMovement tests arrow, page and home/end movement with clamping
+func TestPopupNavigatorMovement(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	nav, _ := newTestNavigator(12)
+
+	steps := []struct {
+		key      fyne.KeyName
+		expected int
+	}{
+		{fyne.KeyUp, 0}, // Already at the top
+		{fyne.KeyDown, 1},
+		{fyne.KeyPageDown, 1 + popupPageSize},
+		{fyne.KeyPageDown, 1 + 2*popupPageSize},
+		{fyne.KeyPageDown, 11}, // Clamped to the last row
+		{fyne.KeyPageUp, 11 - popupPageSize},
+		{fyne.KeyHome, 0},
+		{fyne.KeyEnd, 11},
+	}
+
+	for _, step := range steps {
+		if !nav.handleKey(step.key) {
+			t.Fatalf("Expected %s to be handled", step.key)
+		}
+		if nav.selected != step.expected {
+			t.Errorf("After %s expected selection %d, got %d", step.key, step.expected, nav.selected)
+		}
+	}
+
+	// Only the selected row shows the focus highlight
+	for i, item := range nav.items {
+		if item.selected != (i == nav.selected) {
+			t.Errorf("Row %d selected=%v, expected %v", i, item.selected, i == nav.selected)
+		}
+	}
+}
+
+// TestPopupNavigatorActions tests that keys act on the selected item's ID
+func TestPopupNavigatorActions(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	nav, calls := newTestNavigator(3)
+
+	nav.handleKey(fyne.KeyDown)
+	nav.handleKey(fyne.KeyReturn)
+	if calls["select"] != 2 {
+		t.Errorf("Expected Enter to restore ID 2, got %d", calls["select"])
+	}
+
+	nav.handleKey(fyne.KeyDelete)
+	if calls["delete"] != 2 {
+		t.Errorf("Expected Delete to remove ID 2, got %d", calls["delete"])
+	}
+
+	nav.handleRune('e')
+	if calls["edit"] != 2 {
+		t.Errorf("Expected E to edit ID 2, got %d", calls["edit"])
+	}
+
+	nav.handleRune('p')
+	if calls["pin"] != 2 {
+		t.Errorf("Expected P to pin ID 2, got %d", calls["pin"])
+	}
+
+	nav.handleRune('3')
+	if calls["select"] != 3 || nav.selected != 2 {
+		t.Errorf("Expected 3 to select and restore ID 3, got ID %d at row %d", calls["select"], nav.selected)
+	}
+
+	if nav.handleRune('9') {
+		t.Error("Expected a digit beyond the list to be ignored")
+	}
+
+	nav.handleKey(fyne.KeyEscape)
+	if calls["close"] != 1 {
+		t.Error("Expected Escape to close the popup")
+	}
+
+	if nav.handleRune('x') {
+		t.Error("Expected unbound characters to be ignored")
+	}
+}
+
+// TestPopupNavigatorEmptyList tests that keys are safe when nothing matches the filter
+func TestPopupNavigatorEmptyList(t *testing.T) {
+	nav := &popupNavigator{}
+	nav.SetItems(nil)
+
+	nav.handleKey(fyne.KeyDown)
+	nav.handleKey(fyne.KeyReturn)
+	nav.handleKey(fyne.KeyDelete)
+	nav.handleRune('1')
+	nav.handleRune('e')
+
+	if nav.Selected() != nil {
+		t.Error("Expected no selection in an empty list")
+	}
+}
+
+// TestPopupSearchEntryKeys tests that the search box forwards navigation keys
+// and keeps handling ordinary editing keys itself
+func TestPopupSearchEntryKeys(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	var forwarded []fyne.KeyName
+	entry := newPopupSearchEntry()
+	entry.onNavigate = func(key fyne.KeyName) bool {
+		forwarded = append(forwarded, key)
+		return true
+	}
+
+	window := testApp.NewWindow("test")
+	window.SetContent(entry)
+	window.Canvas().Focus(entry)
+
+	test.Type(entry, "abc")
+	entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDown})
+	entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+	entry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+
+	if len(forwarded) != 2 || forwarded[0] != fyne.KeyDown || forwarded[1] != fyne.KeyReturn {
+		t.Errorf("Expected Down and Return to be forwarded, got %v", forwarded)
+	}
+	if entry.Text != "ab" {
+		t.Errorf("Expected Backspace to edit the query, got %q", entry.Text)
+	}
+}
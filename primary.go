@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Values for the [primary] sync setting
+const (
+	primarySyncNone        = "none"
+	primarySyncToClipboard = "primary-to-clipboard"
+	primarySyncToPrimary   = "clipboard-to-primary"
+	primarySyncBoth        = "both"
+)
+
+// PrimarySettings is the [primary] section of the config file. It controls
+// whether the PRIMARY (middle-click) selection is recorded and whether it is
+// mirrored to or from CLIPBOARD.
+type PrimarySettings struct {
+	Enabled  bool     `toml:"enabled"`  // Record PRIMARY selections in history
+	Debounce Duration `toml:"debounce"` // How long a selection must stay unchanged before it is recorded
+	Sync     string   `toml:"sync"`     // none, primary-to-clipboard, clipboard-to-primary or both
+}
+
+// defaultPrimarySettings leaves PRIMARY alone until it is enabled in the config file
+func defaultPrimarySettings() PrimarySettings {
+	return PrimarySettings{
+		Debounce: Duration{500 * time.Millisecond},
+		Sync:     primarySyncNone,
+	}
+}
+
+// validate checks the sync mode and debounce delay
+func (p PrimarySettings) validate() error {
+	switch p.Sync {
+	case "", primarySyncNone, primarySyncToClipboard, primarySyncToPrimary, primarySyncBoth:
+	default:
+		return fmt.Errorf("unknown primary sync mode %q (expected %s, %s, %s or %s)",
+			p.Sync, primarySyncNone, primarySyncToClipboard, primarySyncToPrimary, primarySyncBoth)
+	}
+	if p.Debounce.Duration < 0 {
+		return fmt.Errorf("primary debounce must not be negative")
+	}
+	return nil
+}
+
+// syncsToClipboard reports whether new PRIMARY selections are copied into CLIPBOARD
+func (p PrimarySettings) syncsToClipboard() bool {
+	return p.Sync == primarySyncToClipboard || p.Sync == primarySyncBoth
+}
+
+// syncsToPrimary reports whether new CLIPBOARD text is copied into PRIMARY
+func (p PrimarySettings) syncsToPrimary() bool {
+	return p.Sync == primarySyncToPrimary || p.Sync == primarySyncBoth
+}
+
+// watched reports whether the watcher needs to follow PRIMARY at all
+func (p PrimarySettings) watched() bool {
+	return p.Enabled || p.syncsToClipboard()
+}
+
+// recordSelectedText adds a PRIMARY selection to history and prints a short notice
+func recordSelectedText(text string, app SourceApp) {
+	item, ok := addTextToHistory(text, SourcePrimary, nil, app)
+	if !ok {
+		// Nothing was recorded; a skipped secret must not reach the log either
+		return
+	}
+	if item.Sensitive != "" {
+		fmt.Printf("📋 Text selected: %s\n", maskedText(item))
+		return
+	}
+
+	if len(text) > 5 {
+		displayText := text
+		if len(displayText) > 60 {
+			displayText = displayText[:60] + "..."
+		}
+		displayText = strings.ReplaceAll(displayText, "\n", " ")
+		fmt.Printf("📋 Text selected: %s\n", displayText)
+	}
+}
+
+// watchPrimary subscribes to PRIMARY changes. Failing to do so only disables
+// the selection; CLIPBOARD monitoring carries on.
+func (w *Watcher) watchPrimary() *clipboardChangeNotifier {
+	backend, err := w.primaryBackend()
+	if err != nil {
+		fmt.Printf("PRIMARY selection unavailable: %v\n", err)
+		return nil
+	}
+
+	notifier, err := backend.Watch()
+	if err != nil {
+		fmt.Printf("PRIMARY selection notifications unavailable: %v\n", err)
+		return nil
+	}
+	return notifier
+}
+
+// checkPrimary reads PRIMARY and handles it if it changed. With requireStable
+// set the selection must also match the previous read, so text that is still
+// being drag-selected between two polls is skipped. Read errors are expected
+// (PRIMARY is often empty) and ignored.
+func (w *Watcher) checkPrimary(requireStable bool) {
+	backend, err := w.primaryBackend()
+	if err != nil {
+		return
+	}
+
+	data, err := backend.Read(mimeText)
+	if err != nil {
+		return
+	}
+
+	text := normalizeCaptured(ItemTypeText, string(data))
+	stable := text == w.pendingPrimary
+	w.pendingPrimary = text
+	if requireStable && !stable {
+		return
+	}
+
+	if text == w.lastPrimary || isNoise(ItemTypeText, text) {
+		return
+	}
+	w.lastPrimary = text
+	if capturePaused.Load() {
+		return
+	}
+	app, ok := allowedOwner(backend)
+	if !ok {
+		return
+	}
+
+	// Copying a selection with Ctrl+C puts the same text in both selections;
+	// keep the CLIPBOARD entry rather than recording it twice
+	if w.primary.Enabled && text != w.lastText {
+		w.onPrimary(text, app)
+	}
+
+	if w.primary.syncsToClipboard() && text != w.lastText {
+		if clipboard, err := w.backend(); err == nil {
+			if err := clipboard.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte(text)}}); err != nil {
+				fmt.Printf("Failed to sync PRIMARY to CLIPBOARD: %v\n", err)
+			} else if w.primary.Enabled {
+				// Already recorded as a selection; don't record the copy again
+				w.lastText = text
+			}
+		}
+	}
+}
+
+// syncToPrimary mirrors newly copied CLIPBOARD text into PRIMARY
+func (w *Watcher) syncToPrimary(text string) {
+	backend, err := w.primaryBackend()
+	if err != nil {
+		return
+	}
+
+	// Set these first so the resulting PRIMARY change isn't treated as a new selection
+	w.lastPrimary = text
+	w.pendingPrimary = text
+	if err := backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte(text)}}); err != nil {
+		fmt.Printf("Failed to sync CLIPBOARD to PRIMARY: %v\n", err)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// newPrimaryTestWatcher creates a test watcher that also follows an in-memory PRIMARY selection
+func newPrimaryTestWatcher(settings PrimarySettings) (*Watcher, *memoryBackend, *memoryBackend, *[]string, *[]string) {
+	clipboard := newMemoryBackend()
+	p, _ := clipboard.Primary()
+	primary := p.(*memoryBackend)
+
+	w, texts, _ := newTestWatcher(watcherPresets["text-only"], clipboard)
+	w.primary = settings
+	w.primaryBackend = func() (ClipboardBackend, error) {
+		return primary, nil
+	}
+	var selections []string
+	w.onPrimary = func(text string, app SourceApp) {
+		selections = append(selections, text)
+	}
+	return w, clipboard, primary, texts, &selections
+}
+
+func writeText(b ClipboardBackend, text string) {
+	b.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte(text)}})
+}
+
+func readText(t *testing.T, b ClipboardBackend) string {
+	t.Helper()
+	data, err := b.Read(mimeText)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	return string(data)
+}
+
+func TestPrimaryPollRequiresStableSelection(t *testing.T) {
+	w, _, primary, _, selections := newPrimaryTestWatcher(PrimarySettings{Enabled: true})
+
+	// Selection grows while the user drags; only the final text is recorded
+	writeText(primary, "sel")
+	w.poll()
+	writeText(primary, "selected text")
+	w.poll()
+	if len(*selections) != 0 {
+		t.Fatalf("Expected no selections while PRIMARY is changing, got %v", *selections)
+	}
+
+	w.poll()
+	w.poll()
+	if len(*selections) != 1 || (*selections)[0] != "selected text" {
+		t.Errorf("Expected the settled selection to be recorded once, got %v", *selections)
+	}
+}
+
+func TestPrimaryDisabledIsIgnored(t *testing.T) {
+	w, _, primary, _, selections := newPrimaryTestWatcher(defaultPrimarySettings())
+
+	writeText(primary, "selected text")
+	w.poll()
+	w.poll()
+	if len(*selections) != 0 {
+		t.Errorf("Expected PRIMARY to be ignored when disabled, got %v", *selections)
+	}
+}
+
+func TestPrimarySkipsTextAlreadyCopied(t *testing.T) {
+	w, clipboard, primary, texts, selections := newPrimaryTestWatcher(PrimarySettings{Enabled: true})
+
+	// Select then Ctrl+C: both selections hold the same text
+	writeText(primary, "copied selection")
+	writeText(clipboard, "copied selection")
+	w.poll()
+	w.poll()
+
+	if len(*texts) != 1 || len(*selections) != 0 {
+		t.Errorf("Expected one clipboard capture and no selection, got %v and %v", *texts, *selections)
+	}
+}
+
+func TestPrimarySyncToClipboard(t *testing.T) {
+	w, clipboard, primary, texts, selections := newPrimaryTestWatcher(PrimarySettings{Enabled: true, Sync: primarySyncToClipboard})
+
+	writeText(primary, "middle click me")
+	w.poll()
+	w.poll()
+
+	if got := readText(t, clipboard); got != "middle click me" {
+		t.Errorf("Expected PRIMARY to be synced into CLIPBOARD, got %q", got)
+	}
+
+	// The synced copy is not recorded a second time
+	w.poll()
+	if len(*selections) != 1 || len(*texts) != 0 {
+		t.Errorf("Expected a single selection and no clipboard capture, got %v and %v", *selections, *texts)
+	}
+}
+
+func TestPrimarySyncToPrimary(t *testing.T) {
+	w, clipboard, primary, texts, selections := newPrimaryTestWatcher(PrimarySettings{Enabled: true, Sync: primarySyncToPrimary})
+
+	writeText(clipboard, "copied text")
+	w.poll()
+
+	if got := readText(t, primary); got != "copied text" {
+		t.Errorf("Expected CLIPBOARD to be synced into PRIMARY, got %q", got)
+	}
+
+	// The synced selection is not recorded as a new one
+	w.poll()
+	w.poll()
+	if len(*texts) != 1 || len(*selections) != 0 {
+		t.Errorf("Expected one clipboard capture and no selection, got %v and %v", *texts, *selections)
+	}
+}
+
+func TestPrimaryEventDebounce(t *testing.T) {
+	clipboard := newMemoryBackend()
+	p, _ := clipboard.Primary()
+	primary := p.(*memoryBackend)
+	captured := make(chan string, 4)
+
+	w := newWatcher(watcherPresets["text-only"])
+	w.primary = PrimarySettings{Enabled: true, Debounce: Duration{50 * time.Millisecond}}
+	w.backend = func() (ClipboardBackend, error) {
+		return clipboard, nil
+	}
+	w.primaryBackend = func() (ClipboardBackend, error) {
+		return primary, nil
+	}
+	w.onText = func(string, []ClipboardContent, SourceApp) {}
+	w.onPrimary = func(text string, app SourceApp) {
+		captured <- text
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go w.Run(stop)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		primary.mu.Lock()
+		watching := len(primary.watchers) > 0
+		primary.mu.Unlock()
+		if watching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Watcher never subscribed to PRIMARY notifications")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A burst of changes inside the debounce window yields one capture
+	for _, text := range []string{"dr", "drag", "drag select"} {
+		writeText(primary, text)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case text := <-captured:
+		if text != "drag select" {
+			t.Errorf("Expected the final selection, got %q", text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the settled selection to be captured")
+	}
+
+	select {
+	case text := <-captured:
+		t.Errorf("Expected a single capture, also got %q", text)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestPrimarySourcePersisted(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	addTextToHistory("from a selection", SourcePrimary, nil, SourceApp{})
+	addToHistory("from a copy")
+
+	items, err := loadClipboardHistory()
+	if err != nil {
+		t.Fatalf("loadClipboardHistory() failed: %v", err)
+	}
+	sources := map[string]ClipboardSource{}
+	for _, item := range items {
+		sources[item.Content] = item.Source
+	}
+	if sources["from a selection"] != SourcePrimary || sources["from a copy"] != SourceClipboard {
+		t.Errorf("Unexpected sources after reload: %v", sources)
+	}
+}
+
+func TestParseConfigPrimarySection(t *testing.T) {
+	cfg, err := parseConfig(`
+[primary]
+enabled = true
+debounce = "1s"
+sync = "both"
+`)
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+	if !cfg.Primary.Enabled || cfg.Primary.Debounce.Duration != time.Second || !cfg.Primary.syncsToClipboard() || !cfg.Primary.syncsToPrimary() {
+		t.Errorf("Unexpected primary settings %+v", cfg.Primary)
+	}
+
+	defaults, err := parseConfig("")
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+	if defaults.Primary.watched() {
+		t.Error("Expected PRIMARY to be ignored by default")
+	}
+
+	if _, err := parseConfig("[primary]\nsync = \"sideways\"\n"); err == nil {
+		t.Error("Expected an unknown sync mode to be rejected")
+	}
+}
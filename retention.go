@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetentionLimits bounds how much history of a single item type is kept.
+// A zero value for any field means that limit is disabled.
+type RetentionLimits struct {
+	MaxItems int      `toml:"max_items"`
+	MaxBytes int64    `toml:"max_bytes"`
+	MaxAge   Duration `toml:"max_age"`
+}
+
+// RetentionPolicy holds separate limits for text and images
+type RetentionPolicy struct {
+	Text          RetentionLimits `toml:"text"`
+	Image         RetentionLimits `toml:"image"`
+	SweepInterval Duration        `toml:"sweep_interval"` // How often the daemon re-applies the policy
+}
+
+// defaultRetentionPolicy mirrors the previous fixed limit of 50 items
+func defaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		Text: RetentionLimits{
+			MaxItems: 50,
+		},
+		Image: RetentionLimits{
+			MaxItems: 20,
+			MaxBytes: 50 * 1024 * 1024,
+		},
+		SweepInterval: Duration{10 * time.Minute},
+	}
+}
+
+// retentionCandidate is the subset of an unpinned row needed to apply limits
+type retentionCandidate struct {
+	id        int64
+	timestamp time.Time
+	size      int64
+}
+
+// expires reports whether the limits expire c, the i-th candidate counting
+// from the newest, when totalBytes is the size of it and every newer
+// candidate. Once a candidate expires every older one does too, since the
+// count, total size and age only grow. The size limit never evicts the
+// newest item so a single oversized copy doesn't vanish the moment it is
+// captured.
+func (limits RetentionLimits) expires(i int, totalBytes int64, c retentionCandidate, now time.Time) bool {
+	tooMany := limits.MaxItems > 0 && i >= limits.MaxItems
+	tooBig := limits.MaxBytes > 0 && i > 0 && totalBytes > limits.MaxBytes
+	tooOld := limits.MaxAge.Duration > 0 && now.Sub(c.timestamp) > limits.MaxAge.Duration
+	return tooMany || tooBig || tooOld
+}
+
+// retentionSweepLoop periodically re-applies the retention policy so
+// age-based limits take effect even when nothing new is copied
+func retentionSweepLoop() {
+	for {
+		interval := getConfig().Retention.SweepInterval.Duration
+		if interval <= 0 {
+			interval = defaultRetentionPolicy().SweepInterval.Duration
+		}
+		time.Sleep(interval)
+
+		sweepHistory()
+	}
+}
+
+// sweepHistory applies the retention policy once and refreshes in-memory history
+func sweepHistory() {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	removed, err := applyRetention(getConfig().Retention)
+	if err != nil {
+		fmt.Printf("Error applying retention policy: %v\n", err)
+		return
+	}
+
+	if len(removed) > 0 {
+		fmt.Printf("🧹 Retention policy removed %d item(s)\n", len(removed))
+		dropFromHistory(removed...)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionLimitsExpire(t *testing.T) {
+	now := time.Now()
+	candidates := []retentionCandidate{
+		{id: 5, timestamp: now.Add(-1 * time.Hour), size: 100},
+		{id: 4, timestamp: now.Add(-2 * time.Hour), size: 100},
+		{id: 3, timestamp: now.Add(-48 * time.Hour), size: 100},
+		{id: 2, timestamp: now.Add(-72 * time.Hour), size: 100},
+	}
+
+	tests := []struct {
+		name     string
+		limits   RetentionLimits
+		expected []int64
+	}{
+		{
+			name:     "No limits",
+			limits:   RetentionLimits{},
+			expected: nil,
+		},
+		{
+			name:     "Count limit",
+			limits:   RetentionLimits{MaxItems: 2},
+			expected: []int64{3, 2},
+		},
+		{
+			name:     "Byte limit",
+			limits:   RetentionLimits{MaxBytes: 250},
+			expected: []int64{3, 2},
+		},
+		{
+			name:     "Age limit",
+			limits:   RetentionLimits{MaxAge: Duration{24 * time.Hour}},
+			expected: []int64{3, 2},
+		},
+		{
+			name:     "Combined limits",
+			limits:   RetentionLimits{MaxItems: 3, MaxAge: Duration{90 * time.Minute}},
+			expected: []int64{4, 3, 2},
+		},
+		{
+			name:     "Byte limit keeps newest item",
+			limits:   RetentionLimits{MaxBytes: 10},
+			expected: []int64{4, 3, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expired []int64
+			var totalBytes int64
+			for i, c := range candidates {
+				totalBytes += c.size
+				if tt.limits.expires(i, totalBytes, c, now) {
+					expired = append(expired, c.id)
+				}
+			}
+			if len(expired) != len(tt.expected) {
+				t.Fatalf("Expected %v expired, got %v", tt.expected, expired)
+			}
+			for i := range expired {
+				if expired[i] != tt.expected[i] {
+					t.Errorf("Expected %v expired, got %v", tt.expected, expired)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestEnforceRetentionSkipsPinned(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	base := time.Now().Add(-time.Hour)
+	var testItems []ClipboardItem
+	for i := 0; i < 5; i++ {
+		testItems = append(testItems, ClipboardItem{
+			Type:      ItemTypeText,
+			Content:   string(rune('a'+i)) + " item",
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	addTestItems(t, testItems)
+
+	// Pin the oldest item
+	oldest := getTestHistoryItem(0)
+	if _, err := db.Exec("UPDATE clipboard_history SET pinned = 1 WHERE id = ?", oldest.ID); err != nil {
+		t.Fatalf("Failed to pin item: %v", err)
+	}
+
+	policy := RetentionPolicy{Text: RetentionLimits{MaxItems: 2}}
+	removed, err := enforceRetention(policy)
+	if err != nil {
+		t.Fatalf("enforceRetention() failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 items removed, got %d", removed)
+	}
+
+	items, err := loadClipboardHistory()
+	if err != nil {
+		t.Fatalf("loadClipboardHistory() failed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items to remain (1 pinned + 2 newest), got %d", len(items))
+	}
+	if items[0].ID != oldest.ID || !items[0].Pinned {
+		t.Errorf("Expected pinned item %d to survive, got %+v", oldest.ID, items[0])
+	}
+}
+
+func TestEnforceRetentionPerType(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	imageData, err := createTestImage()
+	if err != nil {
+		t.Fatalf("Failed to create test image: %v", err)
+	}
+
+	addToHistory("text item")
+	addImageToHistory(imageData, "png", SourceApp{})
+
+	// Images expire after an hour; text is kept
+	old := time.Now().Add(-2 * time.Hour)
+	if _, err := db.Exec("UPDATE clipboard_history SET timestamp = ?", old); err != nil {
+		t.Fatalf("Failed to age items: %v", err)
+	}
+
+	policy := RetentionPolicy{Image: RetentionLimits{MaxAge: Duration{time.Hour}}}
+	if _, err := enforceRetention(policy); err != nil {
+		t.Fatalf("enforceRetention() failed: %v", err)
+	}
+
+	items, err := loadClipboardHistory()
+	if err != nil {
+		t.Fatalf("loadClipboardHistory() failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Type != ItemTypeText {
+		t.Errorf("Expected only the text item to remain, got %+v", items)
+	}
+}
+
+func TestParseRetentionConfig(t *testing.T) {
+	cfg, err := parseConfig(`
+[retention]
+sweep_interval = "5m"
+
+[retention.text]
+max_items = 100
+max_age = "30d"
+
+[retention.image]
+max_items = 10
+max_bytes = 1048576
+max_age = "24h"
+`)
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+
+	if cfg.Retention.Text.MaxItems != 100 || cfg.Retention.Text.MaxAge.Duration != 30*24*time.Hour {
+		t.Errorf("Unexpected text limits: %+v", cfg.Retention.Text)
+	}
+	if cfg.Retention.Image.MaxBytes != 1048576 || cfg.Retention.Image.MaxAge.Duration != 24*time.Hour {
+		t.Errorf("Unexpected image limits: %+v", cfg.Retention.Image)
+	}
+	if cfg.Retention.SweepInterval.Duration != 5*time.Minute {
+		t.Errorf("Expected sweep interval 5m, got %v", cfg.Retention.SweepInterval)
+	}
+
+	if _, err := parseConfig("[retention.text]\nmax_age = \"soon\"\n"); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
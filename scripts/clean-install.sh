@@ -213,7 +213,7 @@ fi
 # Build fresh binary
 echo "Building fresh binary..."
 go mod tidy
-if ! go build -o clipboard-manager; then
+if ! go build -tags sqlite_fts5 -o clipboard-manager; then
     echo "❌ Build failed!"
     exit 1
 fi
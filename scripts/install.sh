@@ -116,7 +116,7 @@ fi
 # Build the application
 echo "Building clipboard manager..."
 go mod tidy
-go build -o clipboard-manager
+go build -tags sqlite_fts5 -o clipboard-manager
 
 if [ $? -eq 0 ]; then
     echo "Build successful!"
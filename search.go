@@ -0,0 +1,468 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SearchFilters narrows down searchHistory results. Zero values disable a filter.
+type SearchFilters struct {
+	Type  ClipboardItemType // Only return items of this type
+	Since time.Time         // Only return items copied at or after this time
+	Until time.Time         // Only return items copied before this time
+	App   string            // Only return items whose application, window class or title contains this
+	Limit int               // Maximum number of results (defaults to 20)
+}
+
+// SearchResult is a single ranked match
+type SearchResult struct {
+	Item    ClipboardItem
+	Snippet string // Matching excerpt with hits wrapped in [ ]
+}
+
+// ftsAvailable records whether the SQLite build includes FTS5.
+// go-sqlite3 only enables it with the sqlite_fts5 build tag.
+var ftsAvailable bool
+
+// ensureSearchIndex creates the FTS5 index and its sync triggers if the SQLite
+// build supports them. The index is derived data, so it lives outside the
+// versioned migrations and is rebuilt from clipboard_history when first created.
+func ensureSearchIndex() error {
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'clipboard_fts'").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check search index: %v", err)
+	}
+
+	if exists > 0 {
+		// Querying the table fails if this binary was built without FTS5
+		_, err := db.Exec("SELECT rowid FROM clipboard_fts LIMIT 0")
+		ftsAvailable = err == nil
+		if !ftsAvailable {
+			return nil
+		}
+
+		// Migrations that rebuild clipboard_history drop its triggers with it
+		var triggers int
+		err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'clipboard_fts_%'").Scan(&triggers)
+		if err != nil {
+			return fmt.Errorf("failed to check search index: %v", err)
+		}
+		if triggers > 0 {
+			return nil
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin search index creation: %v", err)
+	}
+
+	if exists > 0 {
+		if _, err := tx.Exec("DELETE FROM clipboard_fts"); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to reset search index: %v", err)
+		}
+	} else if _, err := tx.Exec("CREATE VIRTUAL TABLE clipboard_fts USING fts5(content, tokenize = 'unicode61')"); err != nil {
+		// No FTS5 in this build; searchHistory falls back to LIKE matching
+		tx.Rollback()
+		ftsAvailable = false
+		return nil
+	}
+
+	// Text and file paths are indexed; image bytes are meaningless to search
+	indexSQL := `
+	CREATE TRIGGER clipboard_fts_insert AFTER INSERT ON clipboard_history
+	WHEN new.type != 'image'
+	BEGIN
+		INSERT INTO clipboard_fts(rowid, content) VALUES (new.id, new.content);
+	END;
+
+	CREATE TRIGGER clipboard_fts_delete AFTER DELETE ON clipboard_history
+	BEGIN
+		DELETE FROM clipboard_fts WHERE rowid = old.id;
+	END;
+
+	CREATE TRIGGER clipboard_fts_update AFTER UPDATE OF content, type ON clipboard_history
+	BEGIN
+		DELETE FROM clipboard_fts WHERE rowid = old.id;
+		INSERT INTO clipboard_fts(rowid, content) SELECT new.id, new.content WHERE new.type != 'image';
+	END;
+
+	INSERT INTO clipboard_fts(rowid, content)
+	SELECT id, content FROM clipboard_history WHERE type != 'image';
+	`
+	if _, err := tx.Exec(indexSQL); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to create search index: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit search index: %v", err)
+	}
+
+	ftsAvailable = true
+	return nil
+}
+
+// searchTerm is one parsed piece of a user query
+type searchTerm struct {
+	text   string
+	phrase bool // Quoted in the query: match the words in order
+	prefix bool // Ended with '*': match any word starting with text
+}
+
+// parseSearchQuery splits user input into words, "quoted phrases" and prefix* terms
+func parseSearchQuery(query string) []searchTerm {
+	var terms []searchTerm
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if text := strings.TrimSpace(string(runes[i+1 : end])); text != "" {
+				terms = append(terms, searchTerm{text: text, phrase: true})
+			}
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			word := string(runes[i:end])
+			prefix := strings.HasSuffix(word, "*")
+			if word = strings.TrimRight(word, "*"); word != "" {
+				terms = append(terms, searchTerm{text: word, prefix: prefix})
+			}
+			i = end
+		}
+	}
+
+	return terms
+}
+
+// ftsMatchExpression builds an FTS5 MATCH expression from parsed terms.
+// Every term is quoted so punctuation in user input can't break the syntax.
+func ftsMatchExpression(terms []searchTerm) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted := `"` + strings.ReplaceAll(term.text, `"`, `""`) + `"`
+		if term.prefix {
+			quoted += "*"
+		}
+		parts = append(parts, quoted)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// searchHistory returns items matching query, best matches first.
+// Supports plain words, "exact phrases" and prefix* terms, combined with AND.
+// The query may be empty when another filter is set, which lists the items
+// passing the filters, newest first.
+func searchHistory(query string, filters SearchFilters) ([]SearchResult, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	if filters.Limit <= 0 {
+		filters.Limit = 20
+	}
+
+	terms := parseSearchQuery(query)
+	if len(terms) == 0 {
+		if clauses, _ := searchFilterSQL(filters, "h"); len(clauses) == 0 {
+			return nil, fmt.Errorf("search query is empty")
+		}
+		return searchHistoryFiltered(filters)
+	}
+
+	if ftsAvailable {
+		return searchHistoryFTS(terms, filters)
+	}
+	return searchHistoryLike(terms, filters)
+}
+
+// searchFilterSQL returns WHERE clauses and arguments for the non-text filters
+func searchFilterSQL(filters SearchFilters, alias string) ([]string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	if filters.Type != "" {
+		clauses = append(clauses, alias+".type = ?")
+		args = append(args, string(filters.Type))
+	}
+	// julianday() normalises the stored timezone offsets before comparing
+	if !filters.Since.IsZero() {
+		clauses = append(clauses, "julianday("+alias+".timestamp) >= julianday(?)")
+		args = append(args, filters.Since)
+	}
+	if !filters.Until.IsZero() {
+		clauses = append(clauses, "julianday("+alias+".timestamp) < julianday(?)")
+		args = append(args, filters.Until)
+	}
+	if filters.App != "" {
+		// LIKE is case-insensitive for ASCII, so "firefox" finds "Firefox"
+		pattern := "%" + escapeLike(filters.App) + "%"
+		var matches []string
+		for _, column := range []string{"source_app", "source_class", "source_title"} {
+			matches = append(matches, alias+"."+column+` LIKE ? ESCAPE '\'`)
+			args = append(args, pattern)
+		}
+		clauses = append(clauses, "("+strings.Join(matches, " OR ")+")")
+	}
+
+	return clauses, args
+}
+
+// searchHistoryFTS ranks matches with FTS5's bm25
+func searchHistoryFTS(terms []searchTerm, filters SearchFilters) ([]SearchResult, error) {
+	clauses, args := searchFilterSQL(filters, "h")
+	clauses = append([]string{"clipboard_fts MATCH ?"}, clauses...)
+	args = append([]interface{}{ftsMatchExpression(terms)}, args...)
+	args = append(args, filters.Limit)
+
+	columns := strings.ReplaceAll("h."+clipboardItemColumns, ", ", ", h.")
+	query := `
+	SELECT ` + columns + `, snippet(clipboard_fts, 0, '[', ']', '…', 12)
+	FROM clipboard_fts
+	JOIN clipboard_history h ON h.id = clipboard_fts.rowid
+	WHERE ` + strings.Join(clauses, " AND ") + `
+	ORDER BY bm25(clipboard_fts), h.timestamp DESC
+	LIMIT ?
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %v", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var snippet string
+		item, err := scanClipboardItem(scannerWithExtra{rows, &snippet})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %v", err)
+		}
+		results = append(results, SearchResult{Item: item, Snippet: snippet})
+	}
+
+	return results, rows.Err()
+}
+
+// searchHistoryLike is the fallback used when SQLite lacks FTS5.
+// Phrases and words become substring matches; results are ordered by recency.
+func searchHistoryLike(terms []searchTerm, filters SearchFilters) ([]SearchResult, error) {
+	clauses, args := searchFilterSQL(filters, "h")
+	clauses = append(clauses, "h.type != 'image'")
+	for _, term := range terms {
+		clauses = append(clauses, `h.content LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(term.text)+"%")
+	}
+	args = append(args, filters.Limit)
+
+	columns := strings.ReplaceAll("h."+clipboardItemColumns, ", ", ", h.")
+	query := `
+	SELECT ` + columns + `
+	FROM clipboard_history h
+	WHERE ` + strings.Join(clauses, " AND ") + `
+	ORDER BY h.timestamp DESC
+	LIMIT ?
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %v", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		item, err := scanClipboardItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %v", err)
+		}
+		results = append(results, SearchResult{Item: item, Snippet: likeSnippet(item.Content, terms[0].text)})
+	}
+
+	return results, rows.Err()
+}
+
+// searchHistoryFiltered lists the items passing the non-text filters, newest first
+func searchHistoryFiltered(filters SearchFilters) ([]SearchResult, error) {
+	clauses, args := searchFilterSQL(filters, "h")
+	args = append(args, filters.Limit)
+
+	columns := strings.ReplaceAll("h."+clipboardItemColumns, ", ", ", h.")
+	query := `
+	SELECT ` + columns + `
+	FROM clipboard_history h
+	WHERE ` + strings.Join(clauses, " AND ") + `
+	ORDER BY h.timestamp DESC
+	LIMIT ?
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %v", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		item, err := scanClipboardItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %v", err)
+		}
+		results = append(results, SearchResult{Item: item, Snippet: likeSnippet(searchableText(item), "")})
+	}
+
+	return results, rows.Err()
+}
+
+// scannerWithExtra appends extra destinations after the standard item columns
+type scannerWithExtra struct {
+	row   rowScanner
+	extra *string
+}
+
+func (s scannerWithExtra) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra)...)
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	return strings.ReplaceAll(s, "_", `\_`)
+}
+
+// likeSnippet returns a short excerpt around the first case-insensitive match,
+// or the start of content when term is empty or not found.
+// It works on runes: lowercasing can change a character's byte length, so
+// byte offsets into the lowercased text don't line up with content.
+func likeSnippet(content, term string) string {
+	runes := []rune(strings.ReplaceAll(content, "\n", " "))
+	idx := indexRunes(lowerRunes(string(runes)), lowerRunes(term))
+	if idx < 0 || term == "" {
+		if len(runes) > 80 {
+			return string(runes[:80]) + "…"
+		}
+		return string(runes)
+	}
+	termEnd := idx + len([]rune(term))
+
+	start := idx - 30
+	prefix := "…"
+	if start <= 0 {
+		start = 0
+		prefix = ""
+	}
+	end := termEnd + 30
+	suffix := "…"
+	if end >= len(runes) {
+		end = len(runes)
+		suffix = ""
+	}
+
+	return prefix + string(runes[start:idx]) + "[" + string(runes[idx:termEnd]) + "]" + string(runes[termEnd:end]) + suffix
+}
+
+// parseSinceFlag accepts either a duration back from now ("7d", "12h") or a date ("2006-01-02")
+func parseSinceFlag(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	var d Duration
+	if err := d.UnmarshalText([]byte(value)); err != nil {
+		return time.Time{}, fmt.Errorf("expected a duration like 7d or a date like 2006-01-02, got %q", value)
+	}
+	return now.Add(-d.Duration), nil
+}
+
+// runSearchCommand implements 'clipboard-manager search [query] [--type T] [--app A] [--since S] [--until U] [--limit N]'
+func runSearchCommand(args []string) error {
+	var queryParts []string
+	var filters SearchFilters
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			queryParts = append(queryParts, arg)
+			continue
+		}
+		if i+1 >= len(args) {
+			return fmt.Errorf("missing value for %s", arg)
+		}
+		value := args[i+1]
+		i++
+
+		switch arg {
+		case "--type":
+			filters.Type = ClipboardItemType(value)
+		case "--app":
+			filters.App = value
+		case "--since", "--until":
+			t, err := parseSinceFlag(value, time.Now())
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", arg, err)
+			}
+			if arg == "--since" {
+				filters.Since = t
+			} else {
+				filters.Until = t
+			}
+		case "--limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid --limit: %q", value)
+			}
+			filters.Limit = n
+		default:
+			return fmt.Errorf("unknown option %s", arg)
+		}
+	}
+
+	results, err := searchHistory(strings.Join(queryParts, " "), filters)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No matching clipboard items.")
+		return nil
+	}
+
+	for _, result := range results {
+		snippet := strings.ReplaceAll(result.Snippet, "\n", " ")
+		if result.Item.Sensitive != "" {
+			snippet = maskedText(result.Item)
+		}
+		when := result.Item.Timestamp.Format("2006-01-02 15:04")
+		if source := describeSource(result.Item); source != "" {
+			when += ", " + source
+		}
+		fmt.Printf("%5d: [%s] %s  (%s)\n",
+			result.Item.ID,
+			strings.ToUpper(string(result.Item.Type)),
+			snippet,
+			when)
+	}
+	fmt.Printf("%d match(es)\n", len(results))
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	terms := parseSearchQuery(`hello "exact phrase" pre* "" *`)
+
+	if len(terms) != 3 {
+		t.Fatalf("Expected 3 terms, got %d: %+v", len(terms), terms)
+	}
+	if terms[0].text != "hello" || terms[0].phrase || terms[0].prefix {
+		t.Errorf("Unexpected first term: %+v", terms[0])
+	}
+	if terms[1].text != "exact phrase" || !terms[1].phrase {
+		t.Errorf("Unexpected phrase term: %+v", terms[1])
+	}
+	if terms[2].text != "pre" || !terms[2].prefix {
+		t.Errorf("Unexpected prefix term: %+v", terms[2])
+	}
+
+	expr := ftsMatchExpression(terms)
+	if expr != `"hello" AND "exact phrase" AND "pre"*` {
+		t.Errorf("Unexpected FTS expression: %s", expr)
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	now := time.Now()
+	testItems := []ClipboardItem{
+		{Type: ItemTypeText, Content: "the quick brown fox", Timestamp: now.Add(-48 * time.Hour), App: "firefox", AppClass: "firefox", WindowTitle: "Foxes - Mozilla Firefox"},
+		{Type: ItemTypeText, Content: "brown sugar recipe", Timestamp: now.Add(-1 * time.Hour), App: "Gedit", AppClass: "Gedit", WindowTitle: "recipes.txt"},
+		{Type: ItemTypeText, Content: "kubectl get pods --namespace=prod", Timestamp: now},
+	}
+	addTestItems(t, testItems)
+
+	tests := []struct {
+		name     string
+		query    string
+		filters  SearchFilters
+		expected []string
+	}{
+		{
+			name:     "Single word",
+			query:    "brown",
+			expected: []string{"the quick brown fox", "brown sugar recipe"},
+		},
+		{
+			name:     "Phrase",
+			query:    `"quick brown"`,
+			expected: []string{"the quick brown fox"},
+		},
+		{
+			name:     "Prefix",
+			query:    "kube*",
+			expected: []string{"kubectl get pods --namespace=prod"},
+		},
+		{
+			name:     "Punctuation in query",
+			query:    "--namespace=prod",
+			expected: []string{"kubectl get pods --namespace=prod"},
+		},
+		{
+			name:     "Date filter",
+			query:    "brown",
+			filters:  SearchFilters{Since: now.Add(-24 * time.Hour)},
+			expected: []string{"brown sugar recipe"},
+		},
+		{
+			name:     "Type filter",
+			query:    "brown",
+			filters:  SearchFilters{Type: ItemTypeImage},
+			expected: nil,
+		},
+		{
+			name:     "App filter",
+			query:    "brown",
+			filters:  SearchFilters{App: "Firefox"},
+			expected: []string{"the quick brown fox"},
+		},
+		{
+			name:     "Window title filter",
+			query:    "brown",
+			filters:  SearchFilters{App: "recipes"},
+			expected: []string{"brown sugar recipe"},
+		},
+		{
+			name:     "No match",
+			query:    "zebra",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := searchHistory(tt.query, tt.filters)
+			if err != nil {
+				t.Fatalf("searchHistory() failed: %v", err)
+			}
+			if len(results) != len(tt.expected) {
+				t.Fatalf("Expected %d results, got %d: %+v", len(tt.expected), len(results), results)
+			}
+			found := map[string]bool{}
+			for _, result := range results {
+				if result.Item.ID == 0 {
+					t.Error("Expected search result to carry its ID")
+				}
+				found[result.Item.Content] = true
+			}
+			for _, content := range tt.expected {
+				if !found[content] {
+					t.Errorf("Expected %q in results", content)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchIndexStaysInSync(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	addTestItems(t, []ClipboardItem{
+		{Type: ItemTypeText, Content: "original wording", Timestamp: time.Now()},
+	})
+	item := getTestHistoryItem(0)
+
+	if err := editHistoryItem(item.ID, "replacement wording"); err != nil {
+		t.Fatalf("editHistoryItem() failed: %v", err)
+	}
+	if results, _ := searchHistory("original", SearchFilters{}); len(results) != 0 {
+		t.Errorf("Expected edited-away text not to match, got %d results", len(results))
+	}
+	if results, _ := searchHistory("replacement", SearchFilters{}); len(results) != 1 {
+		t.Errorf("Expected edited text to match, got %d results", len(results))
+	}
+
+	removeHistoryItem(item.ID)
+	if results, _ := searchHistory("replacement", SearchFilters{}); len(results) != 0 {
+		t.Errorf("Expected deleted item not to match, got %d results", len(results))
+	}
+}
+
+func TestSearchHistoryEmptyQuery(t *testing.T) {
+	// Setup test database
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	if _, err := searchHistory("  ", SearchFilters{}); err == nil {
+		t.Error("Expected error for empty query")
+	}
+}
+
+func TestSearchHistoryFiltersOnly(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+
+	now := time.Now()
+	addTestItems(t, []ClipboardItem{
+		{Type: ItemTypeText, Content: "first firefox copy", Timestamp: now.Add(-2 * time.Hour), App: "firefox"},
+		{Type: ItemTypeText, Content: "from the editor", Timestamp: now.Add(-time.Hour), App: "Gedit"},
+		{Type: ItemTypeText, Content: "second firefox copy", Timestamp: now, App: "firefox"},
+	})
+
+	results, err := searchHistory("", SearchFilters{App: "firefox"})
+	if err != nil {
+		t.Fatalf("searchHistory() failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Item.Content != "second firefox copy" || results[1].Item.Content != "first firefox copy" {
+		t.Fatalf("Expected the firefox copies newest first, got %+v", results)
+	}
+	if results[0].Snippet != "second firefox copy" {
+		t.Errorf("Expected the content as snippet, got %q", results[0].Snippet)
+	}
+
+	results, err = searchHistory("", SearchFilters{Since: now.Add(-90 * time.Minute)})
+	if err != nil || len(results) != 2 {
+		t.Errorf("Expected 2 items since the filter time, got %d, %v", len(results), err)
+	}
+}
+
+func TestLikeSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		term    string
+		want    string
+	}{
+		{name: "ASCII", content: "docker compose up", term: "COMPOSE", want: "docker [compose] up"},
+		{name: "Lowercase is longer", content: "ȺȺȺȺȺȺȺȺ foo", term: "foo", want: "ȺȺȺȺȺȺȺȺ [foo]"},
+		{name: "Lowercase is shorter", content: "İİİİ foo bar", term: "FOO", want: "İİİİ [foo] bar"},
+		{name: "Non-ASCII match", content: "Grüße aus Köln", term: "köln", want: "Grüße aus [Köln]"},
+		{name: "Trimmed on characters", content: strings.Repeat("é", 40) + "x", term: "x", want: "…" + strings.Repeat("é", 30) + "[x]"},
+		{name: "No match", content: strings.Repeat("ü", 90), term: "zzz", want: strings.Repeat("ü", 80) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := likeSnippet(tt.content, tt.term)
+			if got != tt.want {
+				t.Errorf("likeSnippet(%q, %q) = %q, want %q", tt.content, tt.term, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("likeSnippet(%q, %q) returned invalid UTF-8", tt.content, tt.term)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// passwordManagerHint is the target KeePassXC, KWallet and other password
+// managers offer next to a copied secret, with the value "secret". It is kept
+// as a rich format so restoring the item marks it as secret again.
+const passwordManagerHint = "x-kde-passwordManagerHint"
+
+// Values for the [sensitive] action setting
+const (
+	sensitiveSkip = "skip" // Never record secrets
+	sensitiveMask = "mask" // Record secrets but show them masked
+	sensitiveOff  = "off"  // Record secrets like any other text
+)
+
+// SensitiveSettings is the [sensitive] section of the config file. It
+// controls what happens to copies that look like passwords, keys or tokens.
+type SensitiveSettings struct {
+	Action         string   `toml:"action"`          // skip, mask or off
+	TTL            Duration `toml:"ttl"`             // Delete recorded secrets this long after they were copied; 0 keeps them
+	DetectPatterns bool     `toml:"detect_patterns"` // Also look for AWS keys, JWTs and private keys, not just password manager hints
+	MinEntropy     float64  `toml:"min_entropy"`     // Bits per character that make a single token look random; 0 disables the check
+}
+
+// defaultSensitiveSettings masks secrets and deletes them after ten minutes
+func defaultSensitiveSettings() SensitiveSettings {
+	return SensitiveSettings{
+		Action:         sensitiveMask,
+		TTL:            Duration{10 * time.Minute},
+		DetectPatterns: true,
+		MinEntropy:     4.0,
+	}
+}
+
+// validate checks the action, TTL and entropy threshold
+func (s SensitiveSettings) validate() error {
+	switch s.Action {
+	case sensitiveSkip, sensitiveMask, sensitiveOff:
+	default:
+		return fmt.Errorf("unknown sensitive action %q (expected %s, %s or %s)", s.Action, sensitiveSkip, sensitiveMask, sensitiveOff)
+	}
+	if s.TTL.Duration < 0 {
+		return fmt.Errorf("sensitive ttl must not be negative")
+	}
+	if s.MinEntropy < 0 {
+		return fmt.Errorf("sensitive min_entropy must not be negative")
+	}
+	return nil
+}
+
+// secretPatterns recognise well-known credential formats
+var secretPatterns = []struct {
+	re     *regexp.Regexp
+	reason string
+}{
+	{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY[A-Z ]*-----`), "private key"},
+	{regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), "AWS access key"},
+	{regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[:=]\s*["']?[A-Za-z0-9/+]{40}`), "AWS secret key"},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{16,}`), "JSON web token"},
+}
+
+// minSecretLength is the shortest token the entropy check considers
+const minSecretLength = 20
+
+// detectSensitive returns why copied text looks like a secret, or "" if it
+// doesn't. formats are the representations offered with the text, which
+// carry the password manager hint.
+func detectSensitive(text string, formats []ClipboardContent, settings SensitiveSettings) string {
+	if settings.Action == sensitiveOff {
+		return ""
+	}
+	if hint, ok := findFormat(formats, passwordManagerHint); ok && strings.TrimSpace(string(hint)) == "secret" {
+		return "password manager"
+	}
+	if !settings.DetectPatterns {
+		return ""
+	}
+
+	for _, p := range secretPatterns {
+		if p.re.MatchString(text) {
+			return p.reason
+		}
+	}
+	if settings.MinEntropy > 0 && looksRandom(strings.TrimSpace(text), settings.MinEntropy) {
+		return "random token"
+	}
+	return ""
+}
+
+// looksRandom reports whether text is a single generated-looking token: long,
+// mixing upper case, lower case and digits, with high per-character entropy.
+// URLs and paths are left alone.
+func looksRandom(text string, minEntropy float64) bool {
+	if len(text) < minSecretLength || strings.Contains(text, "://") || strings.HasPrefix(text, "/") {
+		return false
+	}
+
+	var upper, lower, digit bool
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			return false
+		}
+		upper = upper || unicode.IsUpper(r)
+		lower = lower || unicode.IsLower(r)
+		digit = digit || unicode.IsDigit(r)
+		counts[r]++
+		total++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	return shannonEntropy(counts, total) >= minEntropy
+}
+
+// shannonEntropy returns the entropy in bits per character of a character histogram
+func shannonEntropy(counts map[rune]int, total int) float64 {
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// secretHint returns the password manager hint as a representation to store
+// with text, when the copy was marked as secret. wl-paste --watch passes the
+// mark to 'capture' as CLIPBOARD_STATE=sensitive.
+func secretHint(b ClipboardBackend) []ClipboardContent {
+	hint := []ClipboardContent{{MimeType: passwordManagerHint, Data: []byte("secret")}}
+	if os.Getenv("CLIPBOARD_STATE") == "sensitive" {
+		return hint
+	}
+	if b == nil {
+		return nil
+	}
+	if data, err := b.Read(passwordManagerHint); err == nil && strings.TrimSpace(string(data)) == "secret" {
+		return hint
+	}
+	return nil
+}
+
+// maskedText is what is shown instead of a sensitive item's content
+func maskedText(item ClipboardItem) string {
+	return fmt.Sprintf("🔒 Hidden (%s) ••••••••", item.Sensitive)
+}
+
+// expireSensitiveItems deletes unpinned sensitive items copied more than ttl
+// ago and returns their IDs. A zero ttl keeps them.
+func expireSensitiveItems(ttl time.Duration) ([]int64, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if ttl <= 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Query("DELETE FROM clipboard_history WHERE sensitive != '' AND pinned = 0 AND julianday(timestamp) < julianday(?) RETURNING id",
+		time.Now().Add(-ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired sensitive items: %v", err)
+	}
+	defer rows.Close()
+
+	var expired []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expired item: %v", err)
+		}
+		expired = append(expired, id)
+	}
+	return expired, rows.Err()
+}
+
+// sensitiveSweepInterval is how often recorded secrets are checked against their TTL
+const sensitiveSweepInterval = 30 * time.Second
+
+// sensitiveSweepLoop deletes recorded secrets once their TTL has passed
+func sensitiveSweepLoop() {
+	for {
+		time.Sleep(sensitiveSweepInterval)
+		sweepSensitiveItems()
+	}
+}
+
+// sweepSensitiveItems deletes expired secrets once and refreshes in-memory history
+func sweepSensitiveItems() {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	expired, err := expireSensitiveItems(getConfig().Sensitive.TTL.Duration)
+	if err != nil {
+		fmt.Printf("Error deleting expired sensitive items: %v\n", err)
+		return
+	}
+	if len(expired) > 0 {
+		fmt.Printf("🔒 Deleted %d expired sensitive item(s)\n", len(expired))
+		dropFromHistory(expired...)
+	}
+}
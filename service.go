@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// The daemon can run as a systemd user service instead of an autostart entry,
+// so systemd supervises it, restarts it after a crash and collects its output
+// in the journal. The unit uses Type=notify; the daemon reports readiness with
+// sd_notify once it holds the daemon lock and its control socket is up.
+
+// serviceName is the systemd user unit installed by 'service install'
+const serviceName = "clipboard-manager.service"
+
+// serviceUsage is shown when the service subcommand is missing or unknown
+const serviceUsage = "clipboard-manager service install|uninstall|status"
+
+// runSystemctl runs systemctl for the user manager; tests replace it
+var runSystemctl = func(args ...string) (string, error) {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// getServiceUnitPath returns where the user unit file is installed
+func getServiceUnitPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find home directory: %v", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "systemd", "user", serviceName), nil
+}
+
+// getExecutablePath returns the absolute path of the running binary with
+// symlinks resolved, so a unit keeps working if the link is changed
+func getExecutablePath() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find executable: %v", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path, nil
+}
+
+// systemdQuote quotes a word for an ExecStart line. Specifiers start with %,
+// so it is always doubled.
+func systemdQuote(word string) string {
+	word = strings.ReplaceAll(word, "%", "%%")
+	if !strings.ContainsAny(word, " \t\"'\\;$") {
+		return word
+	}
+	word = strings.ReplaceAll(word, `\`, `\\`)
+	word = strings.ReplaceAll(word, `"`, `\"`)
+	return `"` + word + `"`
+}
+
+// generateServiceUnit returns the unit file that runs execPath in daemon mode.
+// It isn't restarted when another daemon already holds the lock, and gives
+// up after repeated failures to start instead of retrying forever.
+func generateServiceUnit(execPath string) string {
+	return fmt.Sprintf(`[Unit]
+Description=Clipboard Manager daemon
+Documentation=https://github.com/MiniduTH/linux-clipboard-manager
+PartOf=graphical-session.target
+After=graphical-session.target
+StartLimitIntervalSec=60
+StartLimitBurst=5
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart=%s daemon
+Restart=on-failure
+RestartSec=3
+RestartPreventExitStatus=%d
+SyslogIdentifier=clipboard-manager
+
+[Install]
+WantedBy=graphical-session.target
+`, systemdQuote(execPath), exitDaemonRunning)
+}
+
+// sdNotify sends a state such as "READY=1" to systemd. It reports false
+// without an error when the process wasn't started by a Type=notify unit.
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to notify socket: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %v", err)
+	}
+	return true, nil
+}
+
+// runServiceCommand handles 'service install|uninstall|status'
+func runServiceCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one of install, uninstall or status")
+	}
+
+	switch args[0] {
+	case "install":
+		return installService()
+	case "uninstall":
+		return uninstallService()
+	case "status":
+		return showServiceStatus()
+	default:
+		return fmt.Errorf("unknown service command %q", args[0])
+	}
+}
+
+// installService writes the unit file, then enables and starts it
+func installService() error {
+	// A daemon started some other way would hold the lock and make the
+	// service fail to start; one started by the service is just restarted
+	active, _ := runSystemctl("is-active", serviceName)
+	if info, running := readDaemonLock(getLockPath()); running && active != "active" {
+		return fmt.Errorf("a clipboard daemon is already running (PID: %d, mode: %s); stop it first with 'clipboard-manager stop'", info.PID, info.Mode)
+	}
+
+	unitPath, err := getServiceUnitPath()
+	if err != nil {
+		return err
+	}
+	execPath, err := getExecutablePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(unitPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(unitPath), err)
+	}
+	if err := os.WriteFile(unitPath, []byte(generateServiceUnit(execPath)), 0644); err != nil {
+		return fmt.Errorf("failed to write unit file: %v", err)
+	}
+
+	if out, err := runSystemctl("daemon-reload"); err != nil {
+		return fmt.Errorf("systemctl daemon-reload failed: %v %s", err, out)
+	}
+	if out, err := runSystemctl("enable", "--now", serviceName); err != nil {
+		return fmt.Errorf("systemctl enable failed: %v %s", err, out)
+	}
+	if active == "active" {
+		if out, err := runSystemctl("restart", serviceName); err != nil {
+			return fmt.Errorf("systemctl restart failed: %v %s", err, out)
+		}
+	}
+
+	fmt.Println("✅ Clipboard Manager service installed and started")
+	fmt.Printf("   • Unit: %s\n", unitPath)
+	fmt.Printf("   • Runs: %s daemon\n", execPath)
+	fmt.Println("   • Restarted automatically if it crashes")
+	fmt.Println("   • Logs: journalctl --user -u clipboard-manager")
+	if isAutostartEnabled() {
+		fmt.Println("   • The autostart entry is still enabled; run 'clipboard-manager startup-disable' so only the service starts on login")
+	}
+	return nil
+}
+
+// uninstallService stops and disables the service and removes its unit file
+func uninstallService() error {
+	unitPath, err := getServiceUnitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(unitPath); os.IsNotExist(err) {
+		fmt.Println("ℹ️  Clipboard Manager service is not installed")
+		return nil
+	}
+
+	if out, err := runSystemctl("disable", "--now", serviceName); err != nil {
+		return fmt.Errorf("systemctl disable failed: %v %s", err, out)
+	}
+	if err := os.Remove(unitPath); err != nil {
+		return fmt.Errorf("failed to remove unit file: %v", err)
+	}
+	if out, err := runSystemctl("daemon-reload"); err != nil {
+		return fmt.Errorf("systemctl daemon-reload failed: %v %s", err, out)
+	}
+
+	fmt.Println("✅ Clipboard Manager service uninstalled")
+	fmt.Printf("   • Removed: %s\n", unitPath)
+	return nil
+}
+
+// showServiceStatus reports whether the service is installed, enabled and running
+func showServiceStatus() error {
+	unitPath, err := getServiceUnitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(unitPath); os.IsNotExist(err) {
+		fmt.Println("⚙️  Service Status: NOT INSTALLED")
+		fmt.Println("   • Run 'clipboard-manager service install' to install it")
+		return nil
+	}
+
+	// is-enabled and is-active exit non-zero for disabled and inactive units
+	// but still print the state
+	enabled, _ := runSystemctl("is-enabled", serviceName)
+	active, _ := runSystemctl("is-active", serviceName)
+	if enabled == "" {
+		enabled = "unknown"
+	}
+	if active == "" {
+		active = "unknown"
+	}
+
+	fmt.Println("⚙️  Service Status: INSTALLED")
+	fmt.Printf("   • Unit: %s\n", unitPath)
+	fmt.Printf("   • Enabled: %s\n", enabled)
+	fmt.Printf("   • Active: %s\n", active)
+	if info, running := readDaemonLock(getLockPath()); running {
+		fmt.Printf("   • Daemon PID: %d (%s)\n", info.PID, info.Mode)
+	}
+	fmt.Println("   • Logs: journalctl --user -u clipboard-manager")
+	return nil
+}
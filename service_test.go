@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubSystemctl records systemctl calls instead of running them
+func stubSystemctl(t *testing.T, active string) *[]string {
+	t.Helper()
+	var calls []string
+	saved := runSystemctl
+	runSystemctl = func(args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		if args[0] == "is-active" {
+			return active, nil
+		}
+		return "", nil
+	}
+	t.Cleanup(func() {
+		runSystemctl = saved
+	})
+	return &calls
+}
+
+func TestGenerateServiceUnit(t *testing.T) {
+	unit := generateServiceUnit("/opt/Clipboard Manager/clipboard-manager")
+
+	for _, line := range []string{
+		"Type=notify",
+		"Restart=on-failure",
+		"RestartPreventExitStatus=3",
+		"StartLimitBurst=5",
+		`ExecStart="/opt/Clipboard Manager/clipboard-manager" daemon`,
+		"WantedBy=graphical-session.target",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("Expected the unit to contain %q:\n%s", line, unit)
+		}
+	}
+
+	if got := systemdQuote("/home/user/100%/clipboard-manager"); got != "/home/user/100%%/clipboard-manager" {
+		t.Errorf("Expected %% to be escaped, got %q", got)
+	}
+}
+
+func TestInstallAndUninstallService(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	calls := stubSystemctl(t, "inactive")
+
+	if err := runServiceCommand([]string{"install"}); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	unitPath := filepath.Join(config, "systemd", "user", serviceName)
+	unit, err := os.ReadFile(unitPath)
+	if err != nil {
+		t.Fatalf("Expected the unit file to be written: %v", err)
+	}
+	execPath, _ := getExecutablePath()
+	if !strings.Contains(string(unit), "ExecStart="+systemdQuote(execPath)+" daemon\n") {
+		t.Errorf("Expected the unit to run this executable, got:\n%s", unit)
+	}
+	if strings.Join(*calls, "|") != "is-active clipboard-manager.service|daemon-reload|enable --now clipboard-manager.service" {
+		t.Errorf("Unexpected systemctl calls %v", *calls)
+	}
+
+	*calls = nil
+	if err := runServiceCommand([]string{"uninstall"}); err != nil {
+		t.Fatalf("uninstall failed: %v", err)
+	}
+	if _, err := os.Stat(unitPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the unit file to be removed, got %v", err)
+	}
+	if strings.Join(*calls, "|") != "disable --now clipboard-manager.service|daemon-reload" {
+		t.Errorf("Unexpected systemctl calls %v", *calls)
+	}
+}
+
+func TestInstallServiceRefusesRunningDaemon(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	stubSystemctl(t, "inactive")
+
+	lock, err := acquireDaemonLock(getLockPath(), "tray")
+	if err != nil {
+		t.Fatalf("acquireDaemonLock() failed: %v", err)
+	}
+	defer lock.Release()
+
+	if err := runServiceCommand([]string{"install"}); err == nil {
+		t.Error("Expected install to refuse while another daemon holds the lock")
+	}
+
+	if err := runServiceCommand([]string{"enable"}); err == nil {
+		t.Error("Expected an unknown service command to be rejected")
+	}
+}
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := sdNotify("READY=1"); sent || err != nil {
+		t.Errorf("Expected nothing to be sent outside systemd, got %v, %v", sent, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	if sent, err := sdNotify("READY=1"); !sent || err != nil {
+		t.Fatalf("sdNotify() = %v, %v", sent, err)
+	}
+	buf := make([]byte, 64)
+	n, _, err := conn.ReadFromUnix(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("Expected READY=1, got %q, %v", buf[:n], err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SourceApp identifies the application a copy came from, as far as the
+// display server can tell. Fields that couldn't be found are empty.
+type SourceApp struct {
+	Name     string `json:"name,omitempty"`     // WM_CLASS class, or the process name without one
+	Instance string `json:"instance,omitempty"` // WM_CLASS instance, e.g. "keepassxc"
+	Class    string `json:"class,omitempty"`    // WM_CLASS class, e.g. "KeePassXC"
+	Process  string `json:"process,omitempty"`  // Executable name from /proc, e.g. "keepassxc"
+	PID      int    `json:"pid,omitempty"`
+	Title    string `json:"title,omitempty"` // Title of the window the copy was made in, if known
+}
+
+// selectionOwnerBackend is implemented by backends that can tell which
+// application owns their selection
+type selectionOwnerBackend interface {
+	Owner() (SourceApp, error)
+}
+
+// selectionOwner returns the application owning b's selection. It is empty
+// when the backend can't tell, as on Wayland.
+func selectionOwner(b ClipboardBackend) SourceApp {
+	ob, ok := b.(selectionOwnerBackend)
+	if !ok {
+		return SourceApp{}
+	}
+	app, err := ob.Owner()
+	if err != nil {
+		return SourceApp{}
+	}
+	return app
+}
+
+// maxSourceTitleRunes is how much of a window title describeSource shows
+const maxSourceTitleRunes = 60
+
+// describeSource names the application and window an item was copied from,
+// e.g. "Firefox — Release notes", or returns "" if they are unknown
+func describeSource(item ClipboardItem) string {
+	if item.App == "" {
+		return ""
+	}
+	title := []rune(item.WindowTitle)
+	if len(title) == 0 {
+		return item.App
+	}
+	if len(title) > maxSourceTitleRunes {
+		return item.App + " — " + string(title[:maxSourceTitleRunes]) + "…"
+	}
+	return item.App + " — " + string(title)
+}
+
+// AppSettings is the [apps] section of the config file. Patterns are
+// case-insensitive shell globs matched against an application's WM_CLASS
+// instance and class and its process name.
+type AppSettings struct {
+	Deny  []string `toml:"deny"`  // Copies from these applications are never recorded
+	Allow []string `toml:"allow"` // If set, only copies from these applications are recorded
+}
+
+// validate checks that every pattern is a valid glob
+func (s AppSettings) validate() error {
+	for _, patterns := range [][]string{s.Deny, s.Allow} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid apps pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// allows reports whether copies from app are recorded. Copies whose
+// application is unknown are always recorded, since on Wayland that is every
+// copy.
+func (s AppSettings) allows(app SourceApp) bool {
+	if app.Name == "" {
+		return true
+	}
+	if matchesApp(s.Deny, app) {
+		return false
+	}
+	return len(s.Allow) == 0 || matchesApp(s.Allow, app)
+}
+
+// matchesApp reports whether any pattern matches one of app's names
+func matchesApp(patterns []string, app SourceApp) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		for _, name := range []string{app.Instance, app.Class, app.Process} {
+			if name == "" {
+				continue
+			}
+			if ok, _ := path.Match(pattern, strings.ToLower(name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAppSettingsAllows(t *testing.T) {
+	keepass := SourceApp{Name: "KeePassXC", Instance: "keepassxc", Class: "KeePassXC", Process: "keepassxc", PID: 42}
+	firefox := SourceApp{Name: "firefox", Instance: "Navigator", Class: "firefox", Process: "firefox"}
+	bitwarden := SourceApp{Name: "bitwarden", Process: "bitwarden"}
+	unknown := SourceApp{}
+
+	deny := AppSettings{Deny: []string{"keepassxc", "Bitwarden*"}}
+	for app, want := range map[*SourceApp]bool{&keepass: false, &bitwarden: false, &firefox: true, &unknown: true} {
+		if got := deny.allows(*app); got != want {
+			t.Errorf("deny.allows(%+v) = %v, want %v", *app, got, want)
+		}
+	}
+
+	allow := AppSettings{Allow: []string{"FIREFOX"}, Deny: []string{"navigator"}}
+	if allow.allows(firefox) {
+		t.Error("Expected deny to win over allow")
+	}
+	allow.Deny = nil
+	if !allow.allows(firefox) || allow.allows(keepass) || !allow.allows(unknown) {
+		t.Error("Expected only firefox and unknown applications to be allowed")
+	}
+
+	if _, err := parseConfig("[apps]\ndeny = [\"[keepass\"]\n"); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+func TestWatcherSkipsDeniedApps(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	restoreConfigAfter(t)
+	configMu.Lock()
+	config.Apps = AppSettings{Deny: []string{"keepassxc"}}
+	configMu.Unlock()
+
+	backend := newMemoryBackend()
+	w := newWatcher(watcherPresets["text-only"])
+	w.backend = func() (ClipboardBackend, error) {
+		return backend, nil
+	}
+
+	backend.setOwner(SourceApp{Name: "KeePassXC", Instance: "keepassxc", Class: "KeePassXC"})
+	writeText(backend, "from the password manager")
+	w.check(false)
+
+	backend.setOwner(SourceApp{Name: "Gedit", Instance: "gedit", Class: "Gedit", PID: 7, Title: "notes.txt - gedit"})
+	writeText(backend, "from the editor")
+	w.check(false)
+
+	if getTestHistoryLength() != 1 {
+		t.Fatalf("Expected only the editor copy to be recorded, got %d items", getTestHistoryLength())
+	}
+	item, err := getClipboardItem(getTestHistoryItem(0).ID)
+	if err != nil || item.Content != "from the editor" || item.App != "Gedit" {
+		t.Errorf("Expected the editor copy with its application, got %+v, %v", item, err)
+	}
+	if item.AppClass != "Gedit" || item.WindowTitle != "notes.txt - gedit" {
+		t.Errorf("Expected the window class and title to be stored, got %q, %q", item.AppClass, item.WindowTitle)
+	}
+}
+
+func TestIPCAddSourceApp(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB(t)
+	restoreConfigAfter(t)
+	configMu.Lock()
+	config.Apps = AppSettings{Deny: []string{"keepassxc"}}
+	configMu.Unlock()
+	path := startTestIPCServer(t)
+
+	var rpcErr *ipcError
+	err := callDaemonAt(path, "add", ipcAddParams{Text: "from the password manager", App: SourceApp{Name: "KeePassXC", Class: "KeePassXC"}}, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != ipcInvalidParams {
+		t.Errorf("Expected a copy from a denied application to be rejected, got %v", err)
+	}
+	if getTestHistoryLength() != 0 {
+		t.Errorf("Expected nothing to be recorded, got %d items", getTestHistoryLength())
+	}
+
+	var added ClipboardItem
+	app := SourceApp{Name: "Gedit", Class: "Gedit", Title: "notes.txt - gedit"}
+	if err := callDaemonAt(path, "add", ipcAddParams{Text: "from the editor", App: app}, &added); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	item, err := getClipboardItem(added.ID)
+	if err != nil || item.App != "Gedit" || item.AppClass != "Gedit" || item.WindowTitle != "notes.txt - gedit" {
+		t.Errorf("Expected the application sent with the copy to be stored, got %+v, %v", item, err)
+	}
+}
+
+func TestDescribeSource(t *testing.T) {
+	tests := []struct {
+		item ClipboardItem
+		want string
+	}{
+		{ClipboardItem{}, ""},
+		{ClipboardItem{WindowTitle: "orphan title"}, ""},
+		{ClipboardItem{App: "Gedit"}, "Gedit"},
+		{ClipboardItem{App: "Gedit", WindowTitle: "notes.txt - gedit"}, "Gedit — notes.txt - gedit"},
+		{ClipboardItem{App: "firefox", WindowTitle: strings.Repeat("é", 70)}, "firefox — " + strings.Repeat("é", maxSourceTitleRunes) + "…"},
+	}
+
+	for _, tt := range tests {
+		if got := describeSource(tt.item); got != tt.want {
+			t.Errorf("describeSource(%+v) = %q, want %q", tt.item, got, tt.want)
+		}
+	}
+}
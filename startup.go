@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// startupModeIntegration runs the app with no arguments, setting up hotkeys
+const startupModeIntegration = "integration"
+
+// startupModes are the commands the autostart entry may run on login
+var startupModes = []string{
+	startupModeIntegration, "tray", "daemon", "daemon-text-only",
+	"daemon-minimal", "daemon-passive", "daemon-only",
+}
+
+// StartupSettings is the [startup] section of the config file
+type StartupSettings struct {
+	Mode string `toml:"mode"` // Command the autostart entry runs on login
+}
+
+// defaultStartupSettings starts in integration mode on login, which sets up
+// the popup hotkey as the autostart entry always has
+func defaultStartupSettings() StartupSettings {
+	return StartupSettings{Mode: startupModeIntegration}
+}
+
+// validate checks the startup mode is one the app understands
+func (s StartupSettings) validate() error {
+	if s.Mode == "" {
+		return nil
+	}
+	for _, mode := range startupModes {
+		if s.Mode == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown startup mode %q (expected one of %s)", s.Mode, strings.Join(startupModes, ", "))
+}
+
+// modeName returns the startup mode, treating an empty one as integration
+func (s StartupSettings) modeName() string {
+	if s.Mode == "" {
+		return startupModeIntegration
+	}
+	return s.Mode
+}
+
+// args returns the command line arguments for the startup mode
+func (s StartupSettings) args() []string {
+	if s.modeName() == startupModeIntegration {
+		return nil
+	}
+	return []string{s.Mode}
+}
+
+// getAutostartPath returns the XDG autostart entry path
+func getAutostartPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = os.ExpandEnv("$HOME/.config")
+	}
+	return filepath.Join(dir, "autostart", "clipboard-manager.desktop")
+}
+
+// getStartupPromptedPath returns the marker recording that the first-run
+// question about starting on login has been answered
+func getStartupPromptedPath() string {
+	return filepath.Join(filepath.Dir(getDatabasePath()), "startup-prompted")
+}
+
+// desktopExecReserved are the characters that force an Exec argument to be quoted
+const desktopExecReserved = " \t\n\"'\\><~|&;$*?#()`"
+
+// desktopExecQuote quotes an argument for a desktop entry Exec key, including
+// the escaping of backslashes that applies to every string value
+func desktopExecQuote(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if !strings.ContainsAny(arg, desktopExecReserved) {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		switch r {
+		case '"', '`', '$':
+			b.WriteString(`\` + string(r))
+		case '\\':
+			b.WriteString(`\\\\`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// parseDesktopExec splits an Exec value into arguments, undoing desktopExecQuote
+func parseDesktopExec(value string) ([]string, error) {
+	// Undo the string escaping first, then the Exec quoting
+	value = strings.NewReplacer(`\\`, `\`, `\s`, " ", `\t`, "\t", `\n`, "\n", `\r`, "\r").Replace(value)
+
+	var args []string
+	var current strings.Builder
+	inQuotes, inArg := false, false
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(value):
+			i++
+			current.WriteByte(value[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case !inQuotes && (c == ' ' || c == '\t'):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '%' && i+1 < len(value) && value[i+1] == '%':
+			i++
+			current.WriteByte('%')
+			inArg = true
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in Exec line")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// generateDesktopEntry returns the autostart entry that runs execPath in the
+// given startup mode
+func generateDesktopEntry(execPath string, settings StartupSettings) string {
+	words := []string{desktopExecQuote(execPath)}
+	for _, arg := range settings.args() {
+		words = append(words, desktopExecQuote(arg))
+	}
+
+	return `[Desktop Entry]
+Name=Clipboard Manager
+GenericName=Clipboard History Manager
+Comment=Clipboard history manager for Linux
+Exec=` + strings.Join(words, " ") + `
+Icon=edit-copy
+Terminal=false
+Type=Application
+Categories=Utility;System;Accessibility;
+Keywords=clipboard;history;copy;paste;hotkey;
+X-GNOME-Autostart-enabled=true
+X-KDE-autostart-after=panel
+X-MATE-Autostart-enabled=true
+X-XFCE-Autostart-enabled=true
+Hidden=false
+NoDisplay=false
+StartupNotify=false
+X-GNOME-Autostart-Delay=3
+X-KDE-StartupNotify=false
+OnlyShowIn=GNOME;KDE;XFCE;MATE;Unity;Cinnamon;Pantheon;LXQt;LXDE;
+`
+}
+
+// writeAutostartEntry writes the autostart entry for this executable and the
+// configured startup mode
+func writeAutostartEntry() (string, error) {
+	execPath, err := getExecutablePath()
+	if err != nil {
+		return "", err
+	}
+
+	path := getAutostartPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create autostart directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(generateDesktopEntry(execPath, getConfig().Startup)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write autostart file: %v", err)
+	}
+	return path, nil
+}
+
+// isAutostartEnabled reports whether the XDG autostart entry will start the
+// app on login
+func isAutostartEnabled() bool {
+	content, err := os.ReadFile(getAutostartPath())
+	if err != nil {
+		return false
+	}
+	return !isDesktopEntryDisabled(string(content))
+}
+
+// isDesktopEntryDisabled reports whether a desktop entry is switched off
+func isDesktopEntryDisabled(content string) bool {
+	return strings.Contains(content, "Hidden=true") || strings.Contains(content, "X-GNOME-Autostart-enabled=false")
+}
+
+// desktopEntryValue returns the value of a key in the [Desktop Entry] group
+func desktopEntryValue(content, key string) (string, bool) {
+	inEntry := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		if name, value, found := strings.Cut(line, "="); inEntry && found && strings.TrimSpace(name) == key {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// checkAutostartExec validates the Exec line of an autostart entry. It returns
+// the startup mode it runs, or an error describing why it won't start the app.
+func checkAutostartExec(content string) (string, error) {
+	value, ok := desktopEntryValue(content, "Exec")
+	if !ok {
+		return "", fmt.Errorf("the entry has no Exec line")
+	}
+	args, err := parseDesktopExec(value)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("the Exec line is empty")
+	}
+
+	info, err := os.Stat(args[0])
+	if err != nil {
+		return "", fmt.Errorf("Exec points to %s, which does not exist", args[0])
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("Exec points to %s, which is not an executable", args[0])
+	}
+
+	mode := startupModeIntegration
+	if len(args) > 1 {
+		mode = args[1]
+	}
+	if err := (StartupSettings{Mode: mode}).validate(); err != nil || len(args) > 2 {
+		return "", fmt.Errorf("Exec runs %q, which is not a startup mode", strings.Join(args[1:], " "))
+	}
+	return mode, nil
+}
+
+// showStartupStatus shows the current startup application status
+func showStartupStatus() {
+	autostartFile := getAutostartPath()
+
+	content, err := os.ReadFile(autostartFile)
+	if os.IsNotExist(err) {
+		fmt.Println("🚀 Startup Application Status: DISABLED")
+		fmt.Println("   • Autostart file does not exist")
+		fmt.Printf("   • Location: %s\n", autostartFile)
+		fmt.Println("   • Run 'clipboard-manager startup-enable' to enable")
+		return
+	}
+	if err != nil {
+		fmt.Printf("❌ Error reading autostart file: %v\n", err)
+		return
+	}
+
+	contentStr := string(content)
+	mode, execErr := checkAutostartExec(contentStr)
+	switch {
+	case isDesktopEntryDisabled(contentStr):
+		fmt.Println("🚀 Startup Application Status: DISABLED")
+		fmt.Println("   • Autostart file exists but is disabled")
+	case execErr != nil:
+		fmt.Println("🚀 Startup Application Status: BROKEN")
+		fmt.Printf("   • %v\n", execErr)
+		fmt.Println("   • Run 'clipboard-manager startup-enable' to fix it")
+	default:
+		fmt.Println("🚀 Startup Application Status: ENABLED")
+		fmt.Printf("   • Will start automatically on login in %s mode\n", mode)
+	}
+
+	if execErr == nil {
+		execPath, _ := getExecutablePath()
+		value, _ := desktopEntryValue(contentStr, "Exec")
+		if args, _ := parseDesktopExec(value); execPath != "" && args[0] != execPath {
+			fmt.Printf("   • Runs %s, not this executable (%s)\n", args[0], execPath)
+		}
+		if configured := getConfig().Startup.modeName(); configured != mode {
+			fmt.Printf("   • The config file asks for %s mode; run 'clipboard-manager startup-enable' to update it\n", configured)
+		}
+	}
+
+	fmt.Printf("   • Location: %s\n", autostartFile)
+	fmt.Println("   • Visible in System Settings > Startup Applications")
+	fmt.Println("   • Run 'clipboard-manager startup-disable' to disable")
+	fmt.Println("   • Run 'clipboard-manager startup-enable' to enable")
+}
+
+// enableStartup enables the startup application
+func enableStartup() {
+	autostartFile, err := writeAutostartEntry()
+	if err != nil {
+		fmt.Printf("❌ Error enabling startup: %v\n", err)
+		return
+	}
+
+	fmt.Println("✅ Startup application ENABLED")
+	fmt.Printf("   • Created: %s\n", autostartFile)
+	fmt.Printf("   • Clipboard Manager will start automatically on login in %s mode\n", getConfig().Startup.modeName())
+	fmt.Println("   • Visible in System Settings > Startup Applications")
+}
+
+// disableStartup disables the startup application
+func disableStartup() {
+	autostartFile := getAutostartPath()
+
+	if _, err := os.Stat(autostartFile); os.IsNotExist(err) {
+		fmt.Println("ℹ️  Startup application is already disabled (file does not exist)")
+		return
+	}
+
+	if err := os.Remove(autostartFile); err != nil {
+		fmt.Printf("❌ Error removing autostart file: %v\n", err)
+		return
+	}
+
+	fmt.Println("✅ Startup application DISABLED")
+	fmt.Printf("   • Removed: %s\n", autostartFile)
+	fmt.Println("   • Clipboard Manager will not start automatically on login")
+	fmt.Println("   • Run 'clipboard-manager startup-enable' to re-enable")
+}
+
+// offerStartup asks on the first interactive run whether to start on login.
+// Nothing is enabled without a yes, and the question is never asked again.
+func offerStartup() {
+	if stat, err := os.Stdin.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	promptStartup(os.Stdin)
+}
+
+// promptStartup asks the first-run question on in and reports whether it was asked
+func promptStartup(in io.Reader) bool {
+	marker := getStartupPromptedPath()
+	if _, err := os.Stat(marker); err == nil {
+		return false
+	}
+	// Already enabled or disabled by hand
+	if _, err := os.Stat(getAutostartPath()); err == nil {
+		return false
+	}
+
+	fmt.Print("🚀 Start Clipboard Manager automatically when you log in? [y/N] ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err == nil {
+		os.WriteFile(marker, nil, 0644)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		enableStartup()
+	default:
+		fmt.Println("   • Not enabled. Run 'clipboard-manager startup-enable' at any time to enable it")
+	}
+	return true
+}
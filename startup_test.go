@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupTestHome points the config and data directories at a temporary home
+func setupTestHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	return home
+}
+
+func TestDesktopExecRoundTrip(t *testing.T) {
+	for _, arg := range []string{
+		"/usr/local/bin/clipboard-manager",
+		"/home/user/My Apps/clipboard-manager",
+		`/opt/odd "name"/$HOME\bin/100%`,
+	} {
+		args, err := parseDesktopExec(desktopExecQuote(arg) + " daemon")
+		if err != nil || len(args) != 2 || args[0] != arg || args[1] != "daemon" {
+			t.Errorf("Round trip of %q gave %q, %v", arg, args, err)
+		}
+	}
+
+	if _, err := parseDesktopExec(`"/unterminated daemon`); err == nil {
+		t.Error("Expected an unterminated quote to be rejected")
+	}
+}
+
+func TestGenerateDesktopEntry(t *testing.T) {
+	entry := generateDesktopEntry("/opt/clipboard manager/clipboard-manager", StartupSettings{Mode: "tray"})
+	if value, _ := desktopEntryValue(entry, "Exec"); value != `"/opt/clipboard manager/clipboard-manager" tray` {
+		t.Errorf("Unexpected Exec line %q", value)
+	}
+
+	entry = generateDesktopEntry("/usr/bin/clipboard-manager", StartupSettings{Mode: startupModeIntegration})
+	if value, _ := desktopEntryValue(entry, "Exec"); value != "/usr/bin/clipboard-manager" {
+		t.Errorf("Expected integration mode to run without arguments, got %q", value)
+	}
+
+	if _, err := parseConfig("[startup]\nmode = \"daemon-turbo\"\n"); err == nil {
+		t.Error("Expected an unknown startup mode to be rejected")
+	}
+}
+
+func TestCheckAutostartExec(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "clipboard-manager")
+	os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755)
+	notExecutable := filepath.Join(dir, "README")
+	os.WriteFile(notExecutable, nil, 0644)
+
+	tests := []struct {
+		name string
+		exec string
+		mode string
+		err  string
+	}{
+		{name: "Daemon", exec: binary + " daemon-text-only", mode: "daemon-text-only"},
+		{name: "Integration", exec: binary, mode: startupModeIntegration},
+		// What older versions wrote
+		{name: "Old default", exec: "/usr/local/bin/clipboard-manager clipboard-manager", err: "does not exist"},
+		{name: "Bogus argument", exec: binary + " clipboard-manager", err: "not a startup mode"},
+		{name: "Not executable", exec: notExecutable + " daemon", err: "not an executable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := checkAutostartExec("[Desktop Entry]\nName=Clipboard Manager\nExec=" + tt.exec + "\n")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil || mode != tt.mode {
+				t.Errorf("checkAutostartExec() = %q, %v; want %q", mode, err, tt.mode)
+			}
+		})
+	}
+}
+
+func TestStartupNeedsOptIn(t *testing.T) {
+	setupTestHome(t)
+
+	// Declining writes no entry, and the question isn't asked again
+	if !promptStartup(strings.NewReader("\n")) {
+		t.Fatal("Expected the first run to ask")
+	}
+	if _, err := os.Stat(getAutostartPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected no autostart entry without consent, got %v", err)
+	}
+	if promptStartup(strings.NewReader("y\n")) {
+		t.Error("Expected the question to be asked only once")
+	}
+
+	// Agreeing writes an entry for this executable in the configured mode
+	os.Remove(getStartupPromptedPath())
+	if !promptStartup(strings.NewReader("yes\n")) {
+		t.Fatal("Expected the question to be asked again after resetting")
+	}
+	content, err := os.ReadFile(getAutostartPath())
+	if err != nil {
+		t.Fatalf("Expected an autostart entry: %v", err)
+	}
+	mode, err := checkAutostartExec(string(content))
+	if err != nil || mode != startupModeIntegration {
+		t.Errorf("Expected a valid entry in integration mode, got %q, %v", mode, err)
+	}
+}
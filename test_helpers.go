@@ -7,7 +7,7 @@ import (
 )
 
 // setupTestDB initializes a temporary SQLite database for testing
-func setupTestDB(t *testing.T) {
+func setupTestDB(t testing.TB) {
 	// Create a temporary database file
 	tempDir := t.TempDir()
 	testDBPath := filepath.Join(tempDir, "test_history.db")
@@ -27,10 +27,15 @@ func setupTestDB(t *testing.T) {
 	if err = createTables(); err != nil {
 		t.Fatalf("Failed to create test tables: %v", err)
 	}
+	
+	// Start with empty in-memory history; writes only apply their own changes to it
+	historyMu.Lock()
+	history = []ClipboardItem{}
+	historyMu.Unlock()
 }
 
 // teardownTestDB closes the test database
-func teardownTestDB(t *testing.T) {
+func teardownTestDB(t testing.TB) {
 	if db != nil {
 		if err := db.Close(); err != nil {
 			t.Errorf("Failed to close test database: %v", err)
@@ -83,4 +88,13 @@ func getTestHistoryItem(index int) ClipboardItem {
 		return ClipboardItem{}
 	}
 	return history[index]
-}
\ No newline at end of file
+}
+// setupTestClipboard installs an in-memory clipboard backend for the duration of the test
+func setupTestClipboard(t *testing.T) *memoryBackend {
+	backend := newMemoryBackend()
+	setClipboardBackend(backend)
+	t.Cleanup(func() {
+		setClipboardBackend(nil)
+	})
+	return backend
+}
@@ -17,7 +17,7 @@ fi
 
 # Run tests with verbose output
 echo "Running Go tests..."
-if go test -v; then
+if go test -tags sqlite_fts5 -v; then
     echo ""
     echo "✅ All tests passed!"
 else
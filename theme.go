@@ -7,6 +7,50 @@ import (
 	"fyne.io/fyne/v2/theme"
 )
 
+// ThemeColors are the colors the popup overrides for one theme variant
+type ThemeColors struct {
+	Button       HexColor `toml:"button"`
+	Hover        HexColor `toml:"hover"`
+	Pressed      HexColor `toml:"pressed"`
+	DeleteHover  HexColor `toml:"delete_hover"`
+	HighContrast HexColor `toml:"high_contrast"`
+}
+
+// ThemeSettings is the [theme] section of the config file, with separate
+// colors for light and dark desktops
+type ThemeSettings struct {
+	Light ThemeColors `toml:"light"`
+	Dark  ThemeColors `toml:"dark"`
+}
+
+// defaultThemeSettings returns subtle, high-visibility hover colors
+func defaultThemeSettings() ThemeSettings {
+	return ThemeSettings{
+		Light: ThemeColors{
+			Button:       rgb(250, 250, 250),
+			Hover:        rgb(240, 245, 250), // Nice subtle blue-gray hover color - gentle and pleasant
+			Pressed:      rgb(200, 200, 200),
+			DeleteHover:  rgb(255, 200, 200),
+			HighContrast: rgb(180, 180, 180),
+		},
+		Dark: ThemeColors{
+			Button:       rgb(70, 70, 70),
+			Hover:        rgb(60, 60, 60),
+			Pressed:      rgb(90, 90, 90),
+			DeleteHover:  rgb(80, 40, 40),
+			HighContrast: rgb(100, 100, 100),
+		},
+	}
+}
+
+// themeColors returns the configured colors for a theme variant
+func themeColors(variant fyne.ThemeVariant) ThemeColors {
+	if variant == theme.VariantDark {
+		return getConfig().Theme.Dark
+	}
+	return getConfig().Theme.Light
+}
+
 // CustomTheme extends the default theme with better hover colors and contrast
 type CustomTheme struct {
 	fyne.Theme
@@ -25,27 +69,19 @@ func (t *CustomTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant)
 	baseColor := t.Theme.Color(name, variant)
 	
 	// Enhance specific colors for better contrast and visibility
+	colors := themeColors(variant)
 	switch name {
 	case theme.ColorNameButton:
 		// Improve button colors for better hover visibility
-		if variant == theme.VariantDark {
-			return &color.RGBA{R: 70, G: 70, B: 70, A: 255}
-		}
-		return &color.RGBA{R: 250, G: 250, B: 250, A: 255}
+		return &colors.Button.RGBA
 		
 	case theme.ColorNameHover:
 		// Enhanced hover colors for better visibility - use subtle colors
-		if variant == theme.VariantDark {
-			return &color.RGBA{R: 60, G: 60, B: 60, A: 255}
-		}
-		return &color.RGBA{R: 240, G: 245, B: 250, A: 255}
+		return &colors.Hover.RGBA
 		
 	case theme.ColorNamePressed:
 		// Enhanced pressed state colors
-		if variant == theme.VariantDark {
-			return &color.RGBA{R: 90, G: 90, B: 90, A: 255}
-		}
-		return &color.RGBA{R: 200, G: 200, B: 200, A: 255}
+		return &colors.Pressed.RGBA
 	}
 	
 	return baseColor
@@ -53,27 +89,20 @@ func (t *CustomTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant)
 
 // GetHoverColor returns appropriate hover color for custom widgets
 func GetHoverColor(variant fyne.ThemeVariant) color.Color {
-	if variant == theme.VariantDark {
-		return &color.RGBA{R: 60, G: 60, B: 60, A: 255}
-	}
-	// Nice subtle blue-gray hover color - gentle and pleasant
-	return &color.RGBA{R: 240, G: 245, B: 250, A: 255}
+	c := themeColors(variant).Hover.RGBA
+	return &c
 }
 
 // GetDeleteHoverColor returns appropriate hover color for delete buttons
 func GetDeleteHoverColor(variant fyne.ThemeVariant) color.Color {
-	if variant == theme.VariantDark {
-		return &color.RGBA{R: 80, G: 40, B: 40, A: 255}
-	}
-	return &color.RGBA{R: 255, G: 200, B: 200, A: 255}
+	c := themeColors(variant).DeleteHover.RGBA
+	return &c
 }
 
 // GetHighContrastColor returns a high contrast color for better visibility
 func GetHighContrastColor(variant fyne.ThemeVariant) color.Color {
-	if variant == theme.VariantDark {
-		return &color.RGBA{R: 100, G: 100, B: 100, A: 255}
-	}
-	return &color.RGBA{R: 180, G: 180, B: 180, A: 255}
+	c := themeColors(variant).HighContrast.RGBA
+	return &c
 }
 
 // DetectThemeVariant automatically detects if the current theme is light or dark
@@ -40,10 +40,21 @@ func setupSystemTray() {
 
 // Run with system tray (non-blocking)
 func runWithSystemTray() {
+	// Only one process may watch the clipboard
+	lock := claimDaemonLock("tray")
+	defer lock.Release()
+	offerStartup()
+	
 	go func() {
 		// Start clipboard monitoring
-		watchClipboard()
+		watchClipboard("")
 	}()
+	go retentionSweepLoop()
+	go sensitiveSweepLoop()
+	go watchConfigFile()
+	
+	control := startControlSocket("tray")
+	defer control.Close()
 	
 	// Setup system tray (this blocks)
 	setupSystemTray()
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
 	"strings"
 
@@ -9,13 +8,15 @@ import (
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
-	"github.com/atotto/clipboard"
 )
 
+// popupFilter keeps the search text and toggles across in-place refreshes
+var popupFilter = newHistoryFilter()
+
 // refreshUI updates the window content with current history state
 func refreshUI(w fyne.Window) {
-	historyLen := getHistoryLength()
 	historyCopy := getHistoryCopy()
+	historyLen := len(historyCopy)
 	
 	if historyLen == 0 {
 		// Show empty state
@@ -39,91 +40,191 @@ func refreshUI(w fyne.Window) {
 		return
 	}
 	
-	// Create custom history list items
-	var historyItems []fyne.CanvasObject
-	
-	// Define handlers for item selection, deletion, and editing
-	onSelect := func(index int) {
-		// Convert UI index to history array index (newest first display)
-		historyIndex := historyLen - 1 - index
-		originalItem := historyCopy[historyIndex]
-		
-		if originalItem.Type == ItemTypeText {
-			// Restore text to clipboard
-			if err := clipboard.WriteAll(originalItem.Content); err != nil {
-				fmt.Printf("Error writing text to clipboard: %v\n", err)
-				return
-			}
-			fmt.Printf("Restored text to clipboard: %.50s", originalItem.Content)
-			if len(originalItem.Content) > 50 {
-				fmt.Print("...")
-			}
-			fmt.Println()
-		} else if originalItem.Type == ItemTypeImage {
-			// Restore image to clipboard
-			imageData, err := base64.StdEncoding.DecodeString(originalItem.Content)
-			if err != nil {
-				fmt.Printf("Error decoding image: %v\n", err)
-				return
-			}
-			
-			format := "png"
-			if originalItem.ImageMeta != nil {
-				format = originalItem.ImageMeta.Format
-			}
-			
-			if err := restoreImageToSystemClipboard(imageData, format); err != nil {
-				fmt.Printf("Error restoring image to clipboard: %v\n", err)
-				// Fallback: show message about image restoration limitation
-				fmt.Println("Note: Image clipboard restoration may have limited support on this system.")
-			} else {
-				fmt.Printf("Restored %s image to clipboard", strings.ToUpper(format))
-				if originalItem.ImageMeta != nil {
-					fmt.Printf(" (%dx%d)", originalItem.ImageMeta.Width, originalItem.ImageMeta.Height)
-				}
-				fmt.Println()
-			}
+	// Define handlers for item selection, deletion, and editing.
+	// Items are addressed by database ID so rows inserted by the daemon
+	// after this snapshot was taken can't shift the target.
+	onSelect := func(id int64) {
+		if err := restoreHistoryItem(id); err != nil {
+			fmt.Printf("Error restoring item: %v\n", err)
+			// Fallback: show message about image restoration limitation
+			fmt.Println("Note: Image clipboard restoration may have limited support on this system.")
+			return
 		}
 		
 		w.Close()
 	}
 	
-	onDelete := func(index int) {
-		// Convert UI index to history array index (newest first display)
-		historyIndex := historyLen - 1 - index
-		removeHistoryItem(historyIndex)
+	onDelete := func(id int64) {
+		removeHistoryItem(id)
 		
 		// Refresh the UI in place instead of closing and reopening
 		refreshUI(w)
 	}
 	
-	onEdit := func(index int) {
-		// Convert UI index to history array index (newest first display)
-		historyIndex := historyLen - 1 - index
-		originalItem := historyCopy[historyIndex]
-		
-		// Only allow editing text items
-		if originalItem.Type != ItemTypeText {
-			return
+	onEdit := func(id int64) {
+		for _, item := range historyCopy {
+			// Only allow editing text items
+			if item.ID == id && item.Type == ItemTypeText {
+				if item.Sensitive != "" {
+					showRevealDialog(w, item)
+				} else {
+					showEditDialog(w, id, item.Content)
+				}
+				return
+			}
+		}
+	}
+	
+	onPin := func(id int64) {
+		for _, item := range historyCopy {
+			if item.ID == id {
+				if err := setHistoryItemPinned(id, !item.Pinned); err != nil {
+					fmt.Printf("Error updating pin: %v\n", err)
+					return
+				}
+				refreshUI(w)
+				return
+			}
 		}
-		
-		// Show edit dialog
-		showEditDialog(w, historyIndex, originalItem.Content)
 	}
 	
-	// Create custom list items (newest first)
+	// Newest first
+	displayOrder := make([]ClipboardItem, historyLen)
 	for i := 0; i < historyLen; i++ {
-		item := historyCopy[historyLen-1-i]
-		historyItem := NewHistoryListItem(item, i, onDelete, onSelect, onEdit)
-		historyItems = append(historyItems, historyItem)
+		displayOrder[i] = historyCopy[historyLen-1-i]
 	}
 	
 	// Create scrollable container for the history items using VBox layout
-	listContainer := container.NewVBox(historyItems...)
+	listContainer := container.NewVBox()
 	paddedContainer := container.NewPadded(listContainer)
 	scrollContainer := container.NewScroll(paddedContainer)
 	scrollContainer.SetMinSize(fyne.NewSize(680, 400))
 	scrollContainer.Direction = container.ScrollVerticalOnly
+	
+	headerLabel := widget.NewLabel("")
+	headerLabel.Wrapping = fyne.TextWrapWord
+	
+	// renderList rebuilds only the list so the search entry keeps focus while typing.
+	// Changing the filter moves the keyboard selection back to the first row.
+	renderList := func(resetSelection bool) {
+		// Pinned items get their own section at the top
+		pinned, others := partitionPinned(filterHistory(displayOrder, popupFilter))
+		visible := append(pinned, others...)
+		
+		historyItems := make([]fyne.CanvasObject, 0, len(visible)+2)
+		navItems := make([]*HistoryListItem, 0, len(visible))
+		for i, item := range visible {
+			if len(pinned) > 0 && (i == 0 || i == len(pinned)) {
+				title := "Pinned"
+				if i == len(pinned) {
+					title = "Recent"
+				}
+				historyItems = append(historyItems, widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			}
+			
+			historyItem := NewHistoryListItem(item, i, onDelete, onSelect, onEdit, onPin)
+			historyItem.SetHighlight(popupFilter.query)
+			historyItems = append(historyItems, historyItem)
+			navItems = append(navItems, historyItem)
+		}
+		if len(visible) == 0 {
+			noMatches := widget.NewLabel("No items match the current filter.")
+			noMatches.Alignment = fyne.TextAlignCenter
+			historyItems = append(historyItems, noMatches)
+		}
+		
+		listContainer.Objects = historyItems
+		listContainer.Refresh()
+		scrollContainer.ScrollToTop()
+		
+		if resetSelection {
+			popupNav.selected = 0
+		}
+		popupNav.SetItems(navItems)
+		
+		if len(visible) == historyLen {
+			headerLabel.SetText(fmt.Sprintf("Clipboard History (%d items) - Enter or click to copy, 1-9 quick pick, E edit, P pin, Del delete, Esc close", historyLen))
+		} else {
+			headerLabel.SetText(fmt.Sprintf("Clipboard History (%d of %d items) - Enter or click to copy, 1-9 quick pick, E edit, P pin, Del delete, Esc close", len(visible), historyLen))
+		}
+	}
+	
+	// Search entry and quick filter toggles
+	searchEntry := newPopupSearchEntry()
+	searchEntry.SetPlaceHolder("Search history...")
+	searchEntry.SetText(popupFilter.query)
+	searchEntry.OnChanged = func(query string) {
+		popupFilter.query = query
+		renderList(true)
+	}
+	
+	textCheck := widget.NewCheck("Text", nil)
+	textCheck.SetChecked(popupFilter.showText)
+	textCheck.OnChanged = func(checked bool) {
+		popupFilter.showText = checked
+		renderList(true)
+	}
+	
+	imagesCheck := widget.NewCheck("Images", nil)
+	imagesCheck.SetChecked(popupFilter.showImages)
+	imagesCheck.OnChanged = func(checked bool) {
+		popupFilter.showImages = checked
+		renderList(true)
+	}
+	
+	filesCheck := widget.NewCheck("Files", nil)
+	filesCheck.SetChecked(popupFilter.showFiles)
+	filesCheck.OnChanged = func(checked bool) {
+		popupFilter.showFiles = checked
+		renderList(true)
+	}
+	
+	pinnedCheck := widget.NewCheck("Pinned", nil)
+	pinnedCheck.SetChecked(popupFilter.pinnedOnly)
+	pinnedCheck.OnChanged = func(checked bool) {
+		popupFilter.pinnedOnly = checked
+		renderList(true)
+	}
+	
+	filterBar := container.NewBorder(nil, nil, nil,
+		container.NewHBox(textCheck, imagesCheck, filesCheck, pinnedCheck),
+		searchEntry,
+	)
+	
+	// Keyboard navigation: arrows move the selection from the search box and hand
+	// keys to the list, where digits, E and Delete act on rows and / returns to search
+	popupNav.onClose = w.Close
+	popupNav.onSearch = func() {
+		w.Canvas().Focus(searchEntry)
+	}
+	popupNav.onMove = func(item *HistoryListItem) {
+		scrollToItem(scrollContainer, item)
+	}
+	searchEntry.onFocus = func() {
+		popupNav.listMode = false
+	}
+	searchEntry.onNavigate = func(key fyne.KeyName) bool {
+		switch key {
+		case fyne.KeyUp, fyne.KeyDown, fyne.KeyPageUp, fyne.KeyPageDown:
+			popupNav.listMode = true
+			w.Canvas().Unfocus()
+		}
+		return popupNav.handleKey(key)
+	}
+	w.Canvas().SetOnTypedKey(func(event *fyne.KeyEvent) {
+		if popupOverlayShown(w) {
+			return
+		}
+		popupNav.handleKey(event.Name)
+	})
+	w.Canvas().SetOnTypedRune(func(r rune) {
+		if popupOverlayShown(w) {
+			return
+		}
+		popupNav.handleRune(r)
+	})
+	
+	renderList(false)
 
 	// Create buttons with improved styling
 	closeBtn := widget.NewButton("Close", func() {
@@ -132,11 +233,10 @@ func refreshUI(w fyne.Window) {
 	closeBtn.Importance = widget.HighImportance
 
 	buttonContainer := container.NewHBox(closeBtn)
-	headerLabel := widget.NewLabel(fmt.Sprintf("Clipboard History (%d items) - Click to copy, Edit/Delete with buttons", historyLen))
-	headerLabel.Wrapping = fyne.TextWrapWord
 
 	content := container.NewVBox(
 		headerLabel,
+		filterBar,
 		widget.NewSeparator(),
 		scrollContainer,
 		widget.NewSeparator(),
@@ -145,10 +245,35 @@ func refreshUI(w fyne.Window) {
 
 	w.SetContent(content)
 	w.Canvas().Refresh(content)
+	if !popupNav.listMode {
+		w.Canvas().Focus(searchEntry)
+	}
+}
+
+// scrollToItem scrolls the history list just enough to show item
+func scrollToItem(scroll *container.Scroll, item *HistoryListItem) {
+	top := item.Position().Y
+	bottom := top + item.Size().Height
+	viewHeight := scroll.Size().Height
+	
+	if top < scroll.Offset.Y {
+		scroll.Offset.Y = top
+	} else if bottom > scroll.Offset.Y+viewHeight {
+		scroll.Offset.Y = bottom - viewHeight
+	} else {
+		return
+	}
+	scroll.Refresh()
+}
+
+// popupOverlayShown reports whether a dialog is open over the popup, in which
+// case list shortcuts must not act on the rows behind it
+func popupOverlayShown(w fyne.Window) bool {
+	return w.Canvas().Overlays().Top() != nil
 }
 
 // showEditDialog displays a dialog for editing text content
-func showEditDialog(parent fyne.Window, historyIndex int, currentContent string) {
+func showEditDialog(parent fyne.Window, itemID int64, currentContent string) {
 	// Create a multi-line entry for editing
 	entry := widget.NewMultiLineEntry()
 	entry.SetText(currentContent)
@@ -177,7 +302,7 @@ func showEditDialog(parent fyne.Window, historyIndex int, currentContent string)
 		}
 		
 		// Update the item
-		if err := editHistoryItem(historyIndex, newContent); err != nil {
+		if err := editHistoryItem(itemID, newContent); err != nil {
 			fmt.Printf("Error editing item: %v\n", err)
 			errDialog := widget.NewModalPopUp(
 				container.NewVBox(
@@ -223,6 +348,30 @@ func showEditDialog(parent fyne.Window, historyIndex int, currentContent string)
 	
 	dialog.Resize(fyne.NewSize(700, 550))
 	dialog.Show()
+	parent.Canvas().Focus(entry)
+}
+
+// showRevealDialog asks before opening a sensitive item in the edit dialog,
+// which shows its text in the clear
+func showRevealDialog(parent fyne.Window, item ClipboardItem) {
+	var dialog *widget.PopUp
+	dialog = widget.NewModalPopUp(
+		container.NewVBox(
+			widget.NewLabel(maskedText(item)),
+			widget.NewLabel("Editing shows the hidden text. Reveal it?"),
+			container.NewHBox(
+				widget.NewButton("Reveal and Edit", func() {
+					dialog.Hide()
+					showEditDialog(parent, item.ID, item.Content)
+				}),
+				widget.NewButton("Cancel", func() {
+					dialog.Hide()
+				}),
+			),
+		),
+		parent.Canvas(),
+	)
+	dialog.Show()
 }
 
 func showPopup() error {
@@ -239,6 +388,10 @@ func showPopup() error {
 	w.Resize(fyne.NewSize(700, 600))
 	w.CenterOnScreen()
 
+	// Start with the search box focused and the newest item selected
+	popupNav.listMode = false
+	popupNav.selected = 0
+	
 	// Use the refreshUI function to set initial content
 	refreshUI(w)
 	
@@ -7,6 +7,7 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
 )
 
 // TestHistoryListItemRendering tests the custom HistoryListItem widget rendering
@@ -54,11 +55,11 @@ func TestHistoryListItemRendering(t *testing.T) {
 			deleteCallbackCalled := false
 			selectCallbackCalled := false
 			
-			onDelete := func(index int) {
+			onDelete := func(id int64) {
 				deleteCallbackCalled = true
 			}
 			
-			onSelect := func(index int) {
+			onSelect := func(id int64) {
 				selectCallbackCalled = true
 			}
 
@@ -68,8 +69,8 @@ func TestHistoryListItemRendering(t *testing.T) {
 				Content:   tc.text,
 				Timestamp: time.Now(),
 			}
-			onEdit := func(index int) {}
-			item := NewHistoryListItem(clipboardItem, 0, onDelete, onSelect, onEdit)
+			onEdit := func(id int64) {}
+			item := NewHistoryListItem(clipboardItem, 0, onDelete, onSelect, onEdit, nil)
 			
 			// Verify widget was created successfully
 			if item == nil {
@@ -161,35 +162,37 @@ func TestHistoryListItemInteraction(t *testing.T) {
 	// Track callback invocations
 	deleteCallbackCalled := false
 	selectCallbackCalled := false
-	deletedIndex := -1
-	selectedIndex := -1
+	deletedID := int64(-1)
+	selectedID := int64(-1)
 	
-	onDelete := func(index int) {
+	onDelete := func(id int64) {
 		deleteCallbackCalled = true
-		deletedIndex = index
+		deletedID = id
 	}
 	
-	onSelect := func(index int) {
+	onSelect := func(id int64) {
 		selectCallbackCalled = true
-		selectedIndex = index
+		selectedID = id
 	}
 
 	// Create HistoryListItem
 	testText := "Test clipboard content"
 	testIndex := 5
+	testID := int64(42)
 	clipboardItem := ClipboardItem{
+		ID:        testID,
 		Type:      ItemTypeText,
 		Content:   testText,
 		Timestamp: time.Now(),
 	}
-	onEdit := func(index int) {}
-	item := NewHistoryListItem(clipboardItem, testIndex, onDelete, onSelect, onEdit)
+	onEdit := func(id int64) {}
+	item := NewHistoryListItem(clipboardItem, testIndex, onDelete, onSelect, onEdit, nil)
 
 	// Test item selection (tap)
 	t.Run("Item selection", func(t *testing.T) {
 		// Reset callback states
 		selectCallbackCalled = false
-		selectedIndex = -1
+		selectedID = -1
 
 		// Simulate tap event
 		item.Tapped(&fyne.PointEvent{
@@ -200,8 +203,8 @@ func TestHistoryListItemInteraction(t *testing.T) {
 		if !selectCallbackCalled {
 			t.Error("Select callback was not called")
 		}
-		if selectedIndex != testIndex {
-			t.Errorf("Expected selected index %d, got %d", testIndex, selectedIndex)
+		if selectedID != testID {
+			t.Errorf("Expected selected ID %d, got %d", testID, selectedID)
 		}
 	})
 
@@ -209,7 +212,7 @@ func TestHistoryListItemInteraction(t *testing.T) {
 	t.Run("Delete button click", func(t *testing.T) {
 		// Reset callback states
 		deleteCallbackCalled = false
-		deletedIndex = -1
+		deletedID = -1
 
 		// Simulate delete button click
 		if item.deleteButton != nil {
@@ -222,8 +225,8 @@ func TestHistoryListItemInteraction(t *testing.T) {
 		if !deleteCallbackCalled {
 			t.Error("Delete callback was not called")
 		}
-		if deletedIndex != testIndex {
-			t.Errorf("Expected deleted index %d, got %d", testIndex, deletedIndex)
+		if deletedID != testID {
+			t.Errorf("Expected deleted ID %d, got %d", testID, deletedID)
 		}
 	})
 
@@ -323,7 +326,7 @@ func TestHistoryListItemTextWrapping(t *testing.T) {
 				Content:   tc.text,
 				Timestamp: time.Now(),
 			}
-			item := NewHistoryListItem(clipboardItem, 0, nil, nil, nil)
+			item := NewHistoryListItem(clipboardItem, 0, nil, nil, nil, nil)
 
 			// Test text preparation
 			displayText := item.prepareDisplayText()
@@ -396,7 +399,7 @@ func TestHistoryListItemUpdates(t *testing.T) {
 		Content:   originalText,
 		Timestamp: time.Now(),
 	}
-	item := NewHistoryListItem(clipboardItem, originalIndex, nil, nil, nil)
+	item := NewHistoryListItem(clipboardItem, originalIndex, nil, nil, nil, nil)
 
 	// Test item update
 	t.Run("Item update", func(t *testing.T) {
@@ -479,4 +482,43 @@ func containsEscapedMarkdown(text string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+// TestHistoryListItemHighlight tests that filter matches are emphasised in the text widget
+func TestHistoryListItemHighlight(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	clipboardItem := ClipboardItem{
+		Type:      ItemTypeText,
+		Content:   "hello world",
+		Timestamp: time.Now(),
+	}
+	item := NewHistoryListItem(clipboardItem, 0, nil, nil, nil, nil)
+	item.SetHighlight("wor")
+
+	var highlighted, plain string
+	for _, segment := range item.textWidget.Segments {
+		textSegment, ok := segment.(*widget.TextSegment)
+		if !ok {
+			t.Fatalf("Expected text segments, got %T", segment)
+		}
+		if textSegment.Style.TextStyle.Bold {
+			highlighted += textSegment.Text
+		} else {
+			plain += textSegment.Text
+		}
+	}
+
+	if highlighted != "wor" {
+		t.Errorf("Expected highlighted text %q, got %q", "wor", highlighted)
+	}
+	if plain != "hello ld" {
+		t.Errorf("Expected plain text %q, got %q", "hello ld", plain)
+	}
+
+	// Clearing the highlight restores the escaped markdown rendering
+	item.SetHighlight("")
+	if len(item.textWidget.Segments) == 0 {
+		t.Error("Expected text to be rendered after clearing highlight")
+	}
+}
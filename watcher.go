@@ -0,0 +1,489 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// WatcherOptions controls how the clipboard is monitored
+type WatcherOptions struct {
+	PollInterval    time.Duration // Delay between successful polls
+	ErrorInterval   time.Duration // Delay after a failed read
+	BackoffInterval time.Duration // Delay once MaxErrors reads in a row have failed
+	MaxErrors       int           // Consecutive failures before backing off; errors past this are not logged
+	MonitorImages   bool          // Whether images are captured at all
+	ImageEvery      int           // Check for images on every Nth poll
+	EventDriven     bool          // Prefer change notifications over polling
+	Passive         bool          // Don't monitor; items are only added with 'capture'
+}
+
+// watcherPresets are the monitoring profiles behind the daemon-* subcommands
+var watcherPresets = map[string]WatcherOptions{
+	"default": {
+		PollInterval:    6 * time.Second,
+		ErrorInterval:   3 * time.Second,
+		BackoffInterval: 10 * time.Second,
+		MaxErrors:       5,
+		MonitorImages:   true,
+		ImageEvery:      3,
+		EventDriven:     true,
+	},
+	"text-only": {
+		PollInterval:    3 * time.Second,
+		ErrorInterval:   5 * time.Second,
+		BackoffInterval: 15 * time.Second,
+		MaxErrors:       5,
+		ImageEvery:      1,
+		EventDriven:     true,
+	},
+	"minimal": {
+		PollInterval:    10 * time.Second,
+		ErrorInterval:   10 * time.Second,
+		BackoffInterval: 40 * time.Second,
+		MaxErrors:       3,
+		ImageEvery:      1,
+		EventDriven:     true,
+	},
+	"passive": {
+		Passive: true,
+	},
+}
+
+// watcherPresetNames returns the preset names in a stable order for messages
+func watcherPresetNames() []string {
+	names := make([]string, 0, len(watcherPresets))
+	for name := range watcherPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WatcherSettings is the [watcher] section of the config file. Preset picks
+// the profile for modes that don't name one; any other key that is set
+// overrides the active preset.
+type WatcherSettings struct {
+	Preset          string    `toml:"preset"`
+	PollInterval    *Duration `toml:"poll_interval"`
+	ErrorInterval   *Duration `toml:"error_interval"`
+	BackoffInterval *Duration `toml:"backoff_interval"`
+	MaxErrors       *int      `toml:"max_errors"`
+	MonitorImages   *bool     `toml:"monitor_images"`
+	ImageEvery      *int      `toml:"image_check_every"`
+	EventDriven     *bool     `toml:"event_driven"`
+}
+
+// options resolves the settings for a preset. An empty preset uses the one
+// from the config file, falling back to "default".
+func (s WatcherSettings) options(preset string) (WatcherOptions, error) {
+	if preset == "" {
+		preset = s.Preset
+	}
+	if preset == "" {
+		preset = "default"
+	}
+
+	opts, ok := watcherPresets[preset]
+	if !ok {
+		return WatcherOptions{}, fmt.Errorf("unknown watcher preset %q (expected one of: %s)", preset, strings.Join(watcherPresetNames(), ", "))
+	}
+
+	if s.PollInterval != nil {
+		opts.PollInterval = s.PollInterval.Duration
+	}
+	if s.ErrorInterval != nil {
+		opts.ErrorInterval = s.ErrorInterval.Duration
+	}
+	if s.BackoffInterval != nil {
+		opts.BackoffInterval = s.BackoffInterval.Duration
+	}
+	if s.MaxErrors != nil {
+		opts.MaxErrors = *s.MaxErrors
+	}
+	if s.MonitorImages != nil {
+		opts.MonitorImages = *s.MonitorImages
+	}
+	if s.ImageEvery != nil {
+		opts.ImageEvery = *s.ImageEvery
+	}
+	if s.EventDriven != nil {
+		opts.EventDriven = *s.EventDriven
+	}
+
+	if opts.Passive {
+		return opts, nil
+	}
+	if opts.PollInterval <= 0 || opts.ErrorInterval <= 0 || opts.BackoffInterval <= 0 {
+		return WatcherOptions{}, fmt.Errorf("watcher intervals must be greater than zero")
+	}
+	if opts.MaxErrors < 1 {
+		return WatcherOptions{}, fmt.Errorf("watcher max_errors must be at least 1")
+	}
+	if opts.ImageEvery < 1 {
+		return WatcherOptions{}, fmt.Errorf("watcher image_check_every must be at least 1")
+	}
+
+	return opts, nil
+}
+
+// capturePaused stops watchers from recording anything; it is set with the
+// daemon's pause and resume control requests
+var capturePaused atomic.Bool
+
+// Watcher records clipboard changes into history, either from change
+// notifications or by polling with a backoff on repeated read errors
+type Watcher struct {
+	opts    WatcherOptions
+	primary PrimarySettings
+	backend func() (ClipboardBackend, error)
+	onText  func(string, []ClipboardContent, SourceApp)
+	onImage func([]byte, string, SourceApp)
+	onFiles func([]string, SourceApp)
+
+	primaryBackend func() (ClipboardBackend, error)
+	onPrimary      func(string, SourceApp)
+
+	lastText       string
+	lastImageHash  string // SHA-256 of the whole last image, so changes anywhere in it are seen
+	lastPrimary    string
+	pendingPrimary string // PRIMARY as of the last read, recorded once it stops changing
+	errorCount     int
+	pollCount      int
+}
+
+// newWatcher creates a watcher that saves into history through the active backend
+func newWatcher(opts WatcherOptions) *Watcher {
+	return &Watcher{
+		opts:           opts,
+		primary:        defaultPrimarySettings(),
+		backend:        getClipboardBackend,
+		onText:         recordCopiedText,
+		onImage:        recordCopiedImage,
+		onFiles:        recordCopiedFiles,
+		primaryBackend: getPrimaryBackend,
+		onPrimary:      recordSelectedText,
+	}
+}
+
+// recordCopiedText adds text and its rich representations to history and prints a short notice
+func recordCopiedText(text string, formats []ClipboardContent, app SourceApp) {
+	item, ok := addTextToHistory(text, SourceClipboard, formats, app)
+	if !ok {
+		// Nothing was recorded; a skipped secret must not reach the log either
+		return
+	}
+	if item.Sensitive != "" {
+		fmt.Printf("📋 Text copied: %s\n", maskedText(item))
+		return
+	}
+
+	if len(text) > 5 {
+		displayText := text
+		if len(displayText) > 60 {
+			displayText = displayText[:60] + "..."
+		}
+		// Replace newlines for cleaner output
+		displayText = strings.ReplaceAll(displayText, "\n", " ")
+		fmt.Printf("📋 Text copied: %s\n", displayText)
+	}
+}
+
+// recordCopiedImage adds an image to history and prints a short notice
+func recordCopiedImage(imageData []byte, format string, app SourceApp) {
+	addImageToHistory(imageData, format, app)
+	fmt.Printf("📋 Image copied: %s (%d KB)\n", format, len(imageData)/1024)
+}
+
+// Run monitors the clipboard until stop is closed. A nil stop channel runs forever.
+func (w *Watcher) Run(stop <-chan struct{}) {
+	if w.opts.Passive {
+		<-stop
+		return
+	}
+
+	if w.opts.EventDriven {
+		err := w.runEvents(stop)
+		if err == nil {
+			return
+		}
+		fmt.Printf("Event-driven monitoring unavailable (%v), falling back to polling\n", err)
+	}
+
+	fmt.Printf("Polling clipboard every %s\n", w.opts.PollInterval)
+	for {
+		delay := w.poll()
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runEvents captures the clipboard each time it changes. It returns nil when
+// stopped, or an error if notifications are unavailable or end, in which case
+// the caller falls back to polling.
+func (w *Watcher) runEvents(stop <-chan struct{}) error {
+	backend, err := w.backend()
+	if err != nil {
+		return err
+	}
+
+	notifier, err := backend.Watch()
+	if err != nil {
+		return err
+	}
+	defer notifier.Close()
+
+	fmt.Printf("Using event-driven clipboard monitoring (%s)\n", notifier.name)
+
+	// PRIMARY changes continuously while text is drag-selected, so it is only
+	// read once no change has arrived for the debounce delay
+	var primaryChanges <-chan struct{}
+	var debounce <-chan time.Time
+	if w.primary.watched() {
+		if pn := w.watchPrimary(); pn != nil {
+			defer pn.Close()
+			primaryChanges = pn.changes
+		}
+	}
+
+	w.check(true)
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case _, ok := <-primaryChanges:
+			if !ok {
+				fmt.Printf("PRIMARY selection notifications stopped\n")
+				primaryChanges = nil
+				continue
+			}
+			debounce = time.After(w.primary.Debounce.Duration)
+			continue
+		case <-debounce:
+			debounce = nil
+			w.checkPrimary(false)
+			continue
+		case _, ok := <-notifier.changes:
+			if !ok {
+				return fmt.Errorf("%s notifications stopped", notifier.name)
+			}
+		}
+
+		time.Sleep(changeSettleDelay)
+
+		// Drop a signal that arrived while settling; this read covers it
+		select {
+		case <-notifier.changes:
+		default:
+		}
+
+		w.check(true)
+	}
+}
+
+// poll performs one polling check and returns how long to wait before the next
+func (w *Watcher) poll() time.Duration {
+	w.pollCount++
+	textErr := w.check(w.pollCount%w.opts.ImageEvery == 0)
+	if w.primary.watched() {
+		w.checkPrimary(true)
+	}
+
+	switch {
+	case textErr == nil:
+		return w.opts.PollInterval
+	case w.errorCount >= w.opts.MaxErrors:
+		if w.errorCount == w.opts.MaxErrors {
+			fmt.Println("Too many clipboard errors, reducing check frequency...")
+		}
+		return w.opts.BackoffInterval
+	default:
+		return w.opts.ErrorInterval
+	}
+}
+
+// check reads the clipboard once, recording new text and, if checkImages is
+// set and images are monitored, new images. It returns the text read error.
+func (w *Watcher) check(checkImages bool) error {
+	text, err := w.readText()
+	if err != nil {
+		w.errorCount++
+		if w.errorCount <= w.opts.MaxErrors {
+			fmt.Printf("Clipboard text read error (%d/%d): %v\n", w.errorCount, w.opts.MaxErrors, err)
+		}
+	} else {
+		w.errorCount = 0
+
+		text = normalizeCaptured(ItemTypeText, text)
+		if text != w.lastText {
+			if capturePaused.Load() {
+				// Remember it so it isn't recorded once capture resumes
+				w.lastText = text
+			} else {
+				w.recordText(text)
+			}
+		}
+	}
+
+	if checkImages && w.opts.MonitorImages {
+		w.checkImage()
+	}
+
+	return err
+}
+
+// readText reads the clipboard as plain text through the backend
+func (w *Watcher) readText() (string, error) {
+	backend, err := w.backend()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := backend.Read(mimeText)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// recordText records new clipboard text. Files copied in a file manager also
+// arrive as text (their paths) and are recorded as a file list instead.
+func (w *Watcher) recordText(text string) {
+	w.lastText = text
+
+	backend, err := w.backend()
+	if err != nil {
+		return
+	}
+	app, ok := allowedOwner(backend)
+	if !ok {
+		return
+	}
+
+	if paths, ok := readFileList(backend); ok {
+		if !isNoise(ItemTypeFiles, strings.Join(paths, "\n")) {
+			w.onFiles(paths, app)
+		}
+		return
+	}
+	if isNoise(ItemTypeText, text) {
+		return
+	}
+
+	formats := readRichFormats(backend)
+	w.onText(text, formats, app)
+
+	// A PNG stored with the text (e.g. copied spreadsheet cells) isn't a separate image copy
+	if png, ok := findFormat(formats, "image/png"); ok {
+		w.lastImageHash = contentHash(png)
+	}
+	if w.primary.syncsToPrimary() {
+		w.syncToPrimary(text)
+	}
+}
+
+// checkImage records the clipboard image if it differs from the last one seen
+func (w *Watcher) checkImage() {
+	backend, err := w.backend()
+	if err != nil {
+		return
+	}
+
+	imageData, format, err := readClipboardImage(backend)
+	if err != nil {
+		return
+	}
+	hash := contentHash(imageData)
+	if hash == w.lastImageHash {
+		return
+	}
+	if capturePaused.Load() || isNoise(ItemTypeImage, string(imageData)) {
+		w.lastImageHash = hash
+		return
+	}
+	app, ok := allowedOwner(backend)
+	if !ok {
+		w.lastImageHash = hash
+		return
+	}
+
+	w.onImage(imageData, format, app)
+	w.lastImageHash = hash
+}
+
+// allowedOwner looks up the application owning a selection and reports
+// whether the [apps] settings let its copies be recorded
+func allowedOwner(backend ClipboardBackend) (SourceApp, bool) {
+	app := selectionOwner(backend)
+	if !getConfig().Apps.allows(app) {
+		fmt.Printf("Ignoring copy from %s\n", app.Name)
+		return app, false
+	}
+	return app, true
+}
+
+// watchClipboard monitors the clipboard with the named preset; an empty name
+// uses the preset from the config file. The watcher is rebuilt when a config
+// reload changes its settings. It blocks forever.
+func watchClipboard(preset string) {
+	var previous *Watcher
+	for {
+		reloaded := configReloaded()
+		cfg := getConfig()
+
+		w := newWatcher(watcherOptionsFor(cfg, preset))
+		w.primary = cfg.Primary
+		if previous != nil {
+			// Carry over what was last seen so nothing is recorded twice
+			w.lastText = previous.lastText
+			w.lastImageHash = previous.lastImageHash
+			w.lastPrimary = previous.lastPrimary
+		}
+		previous = w
+
+		w.Run(watcherSettingsChanged(cfg, reloaded))
+	}
+}
+
+// watcherOptionsFor resolves the watcher options, falling back to the
+// built-in preset if the configured settings are invalid
+func watcherOptionsFor(cfg Config, preset string) WatcherOptions {
+	opts, err := cfg.Watcher.options(preset)
+	if err != nil {
+		fmt.Printf("Warning: %v; using the default watcher settings\n", err)
+		opts = watcherPresets["default"]
+		if preset != "" {
+			if p, ok := watcherPresets[preset]; ok {
+				opts = p
+			}
+		}
+	}
+	return opts
+}
+
+// watcherSettingsChanged returns a channel that is closed once a config
+// reload changes the watcher or primary settings in cfg
+func watcherSettingsChanged(cfg Config, reloaded <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{})
+	go func() {
+		for {
+			<-reloaded
+			reloaded = configReloaded()
+			next := getConfig()
+			if !reflect.DeepEqual(next.Watcher, cfg.Watcher) || next.Primary != cfg.Primary {
+				fmt.Println("Watcher settings changed, restarting clipboard monitoring")
+				close(changed)
+				return
+			}
+		}
+	}()
+	return changed
+}
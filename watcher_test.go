@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// flakyBackend fails the next failures reads, then behaves like the wrapped memory backend
+type flakyBackend struct {
+	*memoryBackend
+	failures int
+}
+
+func (f *flakyBackend) Read(mimeType string) ([]byte, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, fmt.Errorf("simulated read failure")
+	}
+	return f.memoryBackend.Read(mimeType)
+}
+
+// newTestWatcher creates a watcher over backend that records captures instead of saving them
+func newTestWatcher(opts WatcherOptions, backend ClipboardBackend) (*Watcher, *[]string, *[]string) {
+	var texts, images []string
+	w := newWatcher(opts)
+	w.backend = func() (ClipboardBackend, error) {
+		return backend, nil
+	}
+	w.onText = func(text string, formats []ClipboardContent, app SourceApp) {
+		texts = append(texts, text)
+	}
+	w.onImage = func(data []byte, format string, app SourceApp) {
+		images = append(images, fmt.Sprintf("%s:%s", format, data))
+	}
+	return w, &texts, &images
+}
+
+func TestWatcherPollDetectsChanges(t *testing.T) {
+	backend := newMemoryBackend()
+	w, texts, _ := newTestWatcher(watcherPresets["text-only"], backend)
+
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("  first copy  ")}})
+	w.poll()
+	w.poll()
+
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("x")}})
+	w.poll()
+
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("second copy")}})
+	w.poll()
+
+	want := []string{"first copy", "second copy"}
+	if len(*texts) != len(want) {
+		t.Fatalf("Expected captures %v, got %v", want, *texts)
+	}
+	for i := range want {
+		if (*texts)[i] != want[i] {
+			t.Errorf("Expected capture %d to be %q, got %q", i, want[i], (*texts)[i])
+		}
+	}
+}
+
+func TestWatcherBackoff(t *testing.T) {
+	opts := WatcherOptions{
+		PollInterval:    1 * time.Second,
+		ErrorInterval:   2 * time.Second,
+		BackoffInterval: 30 * time.Second,
+		MaxErrors:       3,
+		ImageEvery:      1,
+	}
+	backend := &flakyBackend{memoryBackend: newMemoryBackend(), failures: 4}
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("recovered")}})
+	w, texts, _ := newTestWatcher(opts, backend)
+
+	want := []time.Duration{
+		2 * time.Second,  // 1st failure
+		2 * time.Second,  // 2nd failure
+		30 * time.Second, // 3rd failure reaches max_errors
+		30 * time.Second, // still failing
+		1 * time.Second,  // success resets the error count
+	}
+	for i, expected := range want {
+		if got := w.poll(); got != expected {
+			t.Errorf("poll %d: expected delay %v, got %v", i+1, expected, got)
+		}
+	}
+
+	if w.errorCount != 0 {
+		t.Errorf("Expected error count to reset after a successful read, got %d", w.errorCount)
+	}
+	if len(*texts) != 1 || (*texts)[0] != "recovered" {
+		t.Errorf("Expected the text to be captured after recovering, got %v", *texts)
+	}
+
+	// A single failure after recovering starts counting from scratch
+	backend.failures = 1
+	if got := w.poll(); got != opts.ErrorInterval {
+		t.Errorf("Expected error interval after a fresh failure, got %v", got)
+	}
+}
+
+func TestWatcherImageChecks(t *testing.T) {
+	opts := watcherPresets["default"]
+	backend := newMemoryBackend()
+	w, _, images := newTestWatcher(opts, backend)
+
+	backend.Write([]ClipboardContent{{MimeType: "image/png", Data: []byte("image-one")}})
+
+	// Images are only checked on every ImageEvery-th poll
+	for i := 1; i < opts.ImageEvery; i++ {
+		w.poll()
+	}
+	if len(*images) != 0 {
+		t.Fatalf("Expected no image checks before poll %d, got %v", opts.ImageEvery, *images)
+	}
+	w.poll()
+	if len(*images) != 1 || (*images)[0] != "png:image-one" {
+		t.Fatalf("Expected the image to be captured, got %v", *images)
+	}
+
+	// Same image again is ignored; a change anywhere in the data is detected
+	w.check(true)
+	same := make([]byte, 2048)
+	changed := make([]byte, 2048)
+	changed[2047] = 1
+	backend.Write([]ClipboardContent{{MimeType: "image/png", Data: same}})
+	w.check(true)
+	backend.Write([]ClipboardContent{{MimeType: "image/png", Data: changed}})
+	w.check(true)
+	if len(*images) != 3 {
+		t.Errorf("Expected 3 distinct images, got %d", len(*images))
+	}
+
+	// With image monitoring off nothing is captured
+	textOnly, _, none := newTestWatcher(watcherPresets["text-only"], backend)
+	textOnly.check(true)
+	if len(*none) != 0 {
+		t.Errorf("Expected no images with monitoring disabled, got %v", *none)
+	}
+}
+
+func TestWatcherRunEventDriven(t *testing.T) {
+	backend := newMemoryBackend()
+	captured := make(chan string, 4)
+
+	w := newWatcher(watcherPresets["text-only"])
+	w.backend = func() (ClipboardBackend, error) {
+		return backend, nil
+	}
+	w.onText = func(text string, formats []ClipboardContent, app SourceApp) {
+		captured <- text
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		w.Run(stop)
+		close(done)
+	}()
+
+	// Wait until the watcher has subscribed before copying
+	deadline := time.Now().Add(time.Second)
+	for {
+		backend.mu.Lock()
+		watching := len(backend.watchers) > 0
+		backend.mu.Unlock()
+		if watching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Watcher never subscribed to change notifications")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	backend.Write([]ClipboardContent{{MimeType: mimeText, Data: []byte("event copy")}})
+
+	select {
+	case text := <-captured:
+		if text != "event copy" {
+			t.Errorf("Expected \"event copy\", got %q", text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the change notification to trigger a capture")
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return after stop was closed")
+	}
+}
+
+func TestWatcherSettingsOptions(t *testing.T) {
+	fast := Duration{500 * time.Millisecond}
+	off := false
+	zero := 0
+
+	tests := []struct {
+		name     string
+		settings WatcherSettings
+		preset   string
+		check    func(WatcherOptions) bool
+		wantErr  bool
+	}{
+		{
+			name:  "Defaults",
+			check: func(o WatcherOptions) bool { return o.PollInterval == 6*time.Second && o.MonitorImages },
+		},
+		{
+			name:     "Preset from config",
+			settings: WatcherSettings{Preset: "minimal"},
+			check:    func(o WatcherOptions) bool { return o.PollInterval == 10*time.Second && !o.MonitorImages },
+		},
+		{
+			name:     "Subcommand preset wins over config preset",
+			settings: WatcherSettings{Preset: "minimal"},
+			preset:   "text-only",
+			check:    func(o WatcherOptions) bool { return o.PollInterval == 3*time.Second },
+		},
+		{
+			name:     "Overrides apply on top of preset",
+			settings: WatcherSettings{PollInterval: &fast, MonitorImages: &off},
+			check:    func(o WatcherOptions) bool { return o.PollInterval == fast.Duration && !o.MonitorImages && o.MaxErrors == 5 },
+		},
+		{
+			name:   "Passive preset",
+			preset: "passive",
+			check:  func(o WatcherOptions) bool { return o.Passive },
+		},
+		{
+			name:     "Unknown preset",
+			settings: WatcherSettings{Preset: "turbo"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid max errors",
+			settings: WatcherSettings{MaxErrors: &zero},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := tt.settings.options(tt.preset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("options() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !tt.check(opts) {
+				t.Errorf("options() returned unexpected settings %+v", opts)
+			}
+		})
+	}
+}
+
+func TestParseConfigWatcherSection(t *testing.T) {
+	cfg, err := parseConfig(`
+[watcher]
+preset = "text-only"
+poll_interval = "2s"
+max_errors = 8
+`)
+	if err != nil {
+		t.Fatalf("parseConfig() failed: %v", err)
+	}
+
+	opts, err := cfg.Watcher.options("")
+	if err != nil {
+		t.Fatalf("options() failed: %v", err)
+	}
+	if opts.PollInterval != 2*time.Second || opts.MaxErrors != 8 || opts.MonitorImages {
+		t.Errorf("Unexpected watcher settings %+v", opts)
+	}
+
+	if _, err := parseConfig("[watcher]\npreset = \"turbo\"\n"); err == nil {
+		t.Error("Expected an unknown preset to be rejected")
+	}
+}
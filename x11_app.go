@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// X11 protocol constants used to identify the selection owner
+const (
+	x11OpGetProperty = 20
+
+	x11AtomWMName  = 39
+	x11AtomWMClass = 67
+
+	xresOpQueryVersion   = 0
+	xresOpQueryClientIds = 4
+	xresLocalClientPID   = 1 << 1
+
+	// maxPropertyLength is the most of a property read, in 4-byte units
+	maxPropertyLength = 256
+)
+
+// getSelectionOwner returns the window owning a selection, or 0 if it has none
+func (c *x11SelectionConn) getSelectionOwner(selection uint32) (uint32, error) {
+	req := make([]byte, 8)
+	req[0] = x11OpGetSelectionOwner
+	binary.LittleEndian.PutUint16(req[2:], 2)
+	binary.LittleEndian.PutUint32(req[4:], selection)
+	reply, err := c.roundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(reply[8:]), nil
+}
+
+// getProperty reads a window property of any type. A missing property gives
+// no data and no error.
+func (c *x11SelectionConn) getProperty(window, property uint32) ([]byte, error) {
+	req := make([]byte, 24)
+	req[0] = x11OpGetProperty
+	binary.LittleEndian.PutUint16(req[2:], 6)
+	binary.LittleEndian.PutUint32(req[4:], window)
+	binary.LittleEndian.PutUint32(req[8:], property)
+	binary.LittleEndian.PutUint32(req[20:], maxPropertyLength)
+	reply, err := c.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	format := int(reply[1])
+	length := int(binary.LittleEndian.Uint32(reply[16:])) * format / 8
+	if length > len(reply)-32 {
+		return nil, fmt.Errorf("X11 property reply too short")
+	}
+	return reply[32 : 32+length], nil
+}
+
+// getCardinal reads a 32-bit property such as _NET_WM_PID or WM_CLIENT_LEADER
+func (c *x11SelectionConn) getCardinal(window uint32, name string) (uint32, error) {
+	atom, err := c.internAtom(name)
+	if err != nil {
+		return 0, err
+	}
+	data, err := c.getProperty(window, atom)
+	if err != nil || len(data) < 4 {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
+
+// getWMClass returns the instance and class names in a window's WM_CLASS
+func (c *x11SelectionConn) getWMClass(window uint32) (string, string, error) {
+	data, err := c.getProperty(window, x11AtomWMClass)
+	if err != nil {
+		return "", "", err
+	}
+	parts := bytes.Split(bytes.TrimRight(data, "\x00"), []byte{0})
+	if len(parts) < 2 {
+		return string(parts[0]), "", nil
+	}
+	return string(parts[0]), string(parts[1]), nil
+}
+
+// getTitle returns a window's title, preferring the UTF-8 _NET_WM_NAME over WM_NAME
+func (c *x11SelectionConn) getTitle(window uint32) (string, error) {
+	atom, err := c.internAtom("_NET_WM_NAME")
+	if err != nil {
+		return "", err
+	}
+	for _, property := range []uint32{atom, x11AtomWMName} {
+		data, err := c.getProperty(window, property)
+		if err != nil {
+			return "", err
+		}
+		// Long titles are cut at maxPropertyLength, possibly mid-character
+		if title := strings.ToValidUTF8(string(data), ""); title != "" {
+			return title, nil
+		}
+	}
+	return "", nil
+}
+
+// activeWindowTitle returns the title of the focused window if it belongs to
+// app. Selection owners are rarely the visible window, but the copy was most
+// likely made from whichever window has focus.
+func (c *x11SelectionConn) activeWindowTitle(app SourceApp) (string, error) {
+	active, err := c.getCardinal(c.root, "_NET_ACTIVE_WINDOW")
+	if err != nil || active == 0 {
+		return "", err
+	}
+
+	_, class, err := c.getWMClass(active)
+	if err != nil {
+		return "", err
+	}
+	pid, err := c.getCardinal(active, "_NET_WM_PID")
+	if err != nil {
+		return "", err
+	}
+	if (app.PID == 0 || int(pid) != app.PID) && (app.Class == "" || class != app.Class) {
+		return "", nil
+	}
+	return c.getTitle(active)
+}
+
+// clientPID asks the X-Resource extension for the process that created a
+// window. It works for any local client, even ones that don't set _NET_WM_PID.
+func (c *x11SelectionConn) clientPID(window uint32) (int, error) {
+	present, opcode, _, err := c.queryExtension("X-Resource")
+	if err != nil || !present {
+		return 0, err
+	}
+
+	req := make([]byte, 8)
+	req[0] = opcode
+	req[1] = xresOpQueryVersion
+	binary.LittleEndian.PutUint16(req[2:], 2)
+	req[4], req[5] = 1, 2
+	if _, err := c.roundTrip(req); err != nil {
+		return 0, err
+	}
+
+	req = make([]byte, 16)
+	req[0] = opcode
+	req[1] = xresOpQueryClientIds
+	binary.LittleEndian.PutUint16(req[2:], 4)
+	binary.LittleEndian.PutUint32(req[4:], 1)
+	binary.LittleEndian.PutUint32(req[8:], window)
+	binary.LittleEndian.PutUint32(req[12:], xresLocalClientPID)
+	reply, err := c.roundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+
+	// Each ID is the client and mask it answers, a byte length, then the value
+	ids := reply[32:]
+	for n := binary.LittleEndian.Uint32(reply[8:]); n > 0 && len(ids) >= 12; n-- {
+		mask := binary.LittleEndian.Uint32(ids[4:])
+		length := int(binary.LittleEndian.Uint32(ids[8:]))
+		if len(ids) < 12+length {
+			break
+		}
+		if mask == xresLocalClientPID && length >= 4 {
+			return int(binary.LittleEndian.Uint32(ids[12:])), nil
+		}
+		ids = ids[12+length:]
+	}
+	return 0, nil
+}
+
+// selectionOwnerApp identifies the application owning a selection. Toolkits
+// often own selections with a hidden window, so WM_CLASS and _NET_WM_PID are
+// also looked up on its client leader.
+func (c *x11SelectionConn) selectionOwnerApp(selection string) (SourceApp, error) {
+	atom, err := c.internAtom(selection)
+	if err != nil {
+		return SourceApp{}, err
+	}
+	owner, err := c.getSelectionOwner(atom)
+	if err != nil || owner == 0 {
+		return SourceApp{}, err
+	}
+
+	windows := []uint32{owner}
+	if leader, err := c.getCardinal(owner, "WM_CLIENT_LEADER"); err == nil && leader != 0 && leader != owner {
+		windows = append(windows, leader)
+	}
+
+	var app SourceApp
+	for _, window := range windows {
+		if app.Class == "" {
+			if app.Instance, app.Class, err = c.getWMClass(window); err != nil {
+				return SourceApp{}, err
+			}
+		}
+		if app.PID == 0 {
+			pid, err := c.getCardinal(window, "_NET_WM_PID")
+			if err != nil {
+				return SourceApp{}, err
+			}
+			app.PID = int(pid)
+		}
+		if app.Title == "" {
+			if app.Title, err = c.getTitle(window); err != nil {
+				return SourceApp{}, err
+			}
+		}
+	}
+	if app.PID == 0 {
+		// Not every server has X-Resource; the window class is still useful
+		app.PID, _ = c.clientPID(owner)
+	}
+
+	if app.PID != 0 {
+		app.Process = processName(app.PID)
+	}
+	if app.Title == "" {
+		// The title is a nicety; the copy is identified without it
+		app.Title, _ = c.activeWindowTitle(app)
+	}
+	app.Name = app.Class
+	if app.Name == "" {
+		app.Name = app.Process
+	}
+	return app, nil
+}
+
+// processName returns the executable name of a local process, or "" if it is gone
+func processName(pid int) string {
+	comm, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+// lookupSelectionOwner connects to display and identifies the application
+// owning selection
+func lookupSelectionOwner(display, selection string) (SourceApp, error) {
+	c, err := openX11SelectionConn(display)
+	if err != nil {
+		return SourceApp{}, err
+	}
+	defer c.Close()
+
+	return c.selectionOwnerApp(selection)
+}
+
+// Owner identifies the application owning the selection
+func (x *x11Backend) Owner() (SourceApp, error) {
+	return lookupSelectionOwner(x.display, strings.ToUpper(x.selection))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"testing"
+)
+
+// replyWithData sends a reply whose bytes 8 onwards are body, followed by extra data
+func (s *fakeXServer) replyWithData(format byte, body []byte, data []byte) {
+	packet := make([]byte, 32+pad4(len(data)))
+	packet[0] = 1
+	packet[1] = format
+	binary.LittleEndian.PutUint32(packet[4:], uint32(pad4(len(data))/4))
+	copy(packet[8:], body)
+	copy(packet[32:], data)
+	s.conn.Write(packet)
+}
+
+// fakeWindowServer answers the requests made to identify a selection owner:
+// properties come from props, keyed by window and then property name, with
+// the root window as 0, and X-Resource reports pid for every window
+func fakeWindowServer(t *testing.T, conn net.Conn, owner uint32, props map[uint32]map[string][]byte, pid uint32) {
+	const xresOpcode = 140
+	atoms := map[string]uint32{"WM_CLASS": x11AtomWMClass, "WM_NAME": x11AtomWMName}
+	names := map[uint32]string{x11AtomWMClass: "WM_CLASS", x11AtomWMName: "WM_NAME"}
+
+	s := &fakeXServer{t: t, conn: conn}
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		length := int(binary.LittleEndian.Uint16(header[2:])) * 4
+		req := append(header, s.read(length-4)...)
+
+		switch req[0] {
+		case x11OpInternAtom:
+			name := string(req[8 : 8+binary.LittleEndian.Uint16(req[4:])])
+			if _, ok := atoms[name]; !ok {
+				atoms[name] = uint32(100 + len(atoms))
+				names[atoms[name]] = name
+			}
+			reply := make([]byte, 4)
+			binary.LittleEndian.PutUint32(reply, atoms[name])
+			s.reply(reply...)
+		case x11OpGetSelectionOwner:
+			reply := make([]byte, 4)
+			binary.LittleEndian.PutUint32(reply, owner)
+			s.reply(reply...)
+		case x11OpGetProperty:
+			window := binary.LittleEndian.Uint32(req[4:])
+			value := props[window][names[binary.LittleEndian.Uint32(req[8:])]]
+			format := byte(8)
+			if len(value) == 4 {
+				format = 32
+			}
+			body := make([]byte, 12)
+			binary.LittleEndian.PutUint32(body[8:], uint32(len(value)*8/int(format)))
+			s.replyWithData(format, body, value)
+		case x11OpQueryExtension:
+			s.reply(1, xresOpcode, 0)
+		case xresOpcode:
+			if req[1] == xresOpQueryVersion {
+				s.reply(1, 0, 2, 0)
+				continue
+			}
+			id := make([]byte, 16)
+			binary.LittleEndian.PutUint32(id[0:], binary.LittleEndian.Uint32(req[8:]))
+			binary.LittleEndian.PutUint32(id[4:], xresLocalClientPID)
+			binary.LittleEndian.PutUint32(id[8:], 4)
+			binary.LittleEndian.PutUint32(id[12:], pid)
+			s.replyWithData(0, []byte{1, 0, 0, 0}, id)
+		default:
+			t.Errorf("Unexpected X11 request %v", req)
+			return
+		}
+	}
+}
+
+// cardinal encodes a 32-bit property value
+func cardinal(v uint32) []byte {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, v)
+	return data
+}
+
+func TestSelectionOwnerApp(t *testing.T) {
+	const (
+		hidden = 0x500001
+		leader = 0x500000
+		active = 0x600000
+	)
+
+	tests := []struct {
+		name  string
+		props map[uint32]map[string][]byte
+		want  SourceApp
+	}{
+		{
+			name: "Class on the client leader, PID from X-Resource",
+			props: map[uint32]map[string][]byte{
+				hidden: {"WM_CLIENT_LEADER": cardinal(leader)},
+				leader: {"WM_CLASS": []byte("keepassxc\x00KeePassXC\x00"), "_NET_WM_NAME": []byte("Passwords.kdbx - KeePassXC")},
+			},
+			want: SourceApp{Name: "KeePassXC", Instance: "keepassxc", Class: "KeePassXC", PID: os.Getpid(), Process: processName(os.Getpid()), Title: "Passwords.kdbx - KeePassXC"},
+		},
+		{
+			name: "No class, named after the process",
+			props: map[uint32]map[string][]byte{
+				hidden: {"_NET_WM_PID": cardinal(uint32(os.Getpid()))},
+			},
+			want: SourceApp{Name: processName(os.Getpid()), PID: os.Getpid(), Process: processName(os.Getpid())},
+		},
+		{
+			name: "Title from the focused window of the same process",
+			props: map[uint32]map[string][]byte{
+				0:      {"_NET_ACTIVE_WINDOW": cardinal(active)},
+				hidden: {"WM_CLASS": []byte("gedit\x00Gedit\x00"), "_NET_WM_PID": cardinal(uint32(os.Getpid()))},
+				active: {"_NET_WM_PID": cardinal(uint32(os.Getpid())), "WM_NAME": []byte("notes.txt - gedit")},
+			},
+			want: SourceApp{Name: "Gedit", Instance: "gedit", Class: "Gedit", PID: os.Getpid(), Process: processName(os.Getpid()), Title: "notes.txt - gedit"},
+		},
+		{
+			name: "Focused window of another application",
+			props: map[uint32]map[string][]byte{
+				0:      {"_NET_ACTIVE_WINDOW": cardinal(active)},
+				hidden: {"WM_CLASS": []byte("gedit\x00Gedit\x00"), "_NET_WM_PID": cardinal(uint32(os.Getpid()))},
+				active: {"WM_CLASS": []byte("xterm\x00XTerm\x00"), "_NET_WM_PID": cardinal(1), "WM_NAME": []byte("bash in xterm")},
+			},
+			want: SourceApp{Name: "Gedit", Instance: "gedit", Class: "Gedit", PID: os.Getpid(), Process: processName(os.Getpid())},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			go fakeWindowServer(t, server, hidden, tt.props, uint32(os.Getpid()))
+
+			c := &x11SelectionConn{conn: client, reader: bufio.NewReader(client)}
+			app, err := c.selectionOwnerApp("CLIPBOARD")
+			if err != nil {
+				t.Fatalf("selectionOwnerApp() failed: %v", err)
+			}
+			if app != tt.want {
+				t.Errorf("selectionOwnerApp() = %+v, want %+v", app, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// X11 protocol constants used by the selection owner
+const (
+	x11OpCreateWindow           = 1
+	x11OpChangeWindowAttributes = 2
+	x11OpChangeProperty         = 18
+	x11OpSetSelectionOwner      = 22
+	x11OpGetSelectionOwner      = 23
+	x11OpSendEvent              = 25
+
+	x11PropertyNotifyEvent   = 28
+	x11SelectionClearEvent   = 29
+	x11SelectionRequestEvent = 30
+	x11SelectionNotifyEvent  = 31
+
+	x11AtomAtom           = 4
+	x11WindowClassInput   = 2 // InputOnly
+	x11CWEventMask        = 1 << 11
+	x11PropertyChangeMask = 1 << 22
+	x11PropertyDeleted    = 1
+)
+
+// textTargets are the X11 targets that all mean plain UTF-8 text
+var textTargets = []string{"UTF8_STRING", "text/plain;charset=utf-8", "text/plain", "STRING", "TEXT"}
+
+// x11Transfer is an INCR transfer in progress: data too large for a single
+// property is handed over in chunks each time the requestor deletes the property
+type x11Transfer struct {
+	requestor uint32
+	property  uint32
+	target    uint32
+	data      []byte
+	offset    int
+}
+
+// x11SelectionOwner owns a selection and answers requests for the
+// representations it holds until another client takes the selection over.
+// xclip and wl-copy offer a single target, which would lose e.g. the HTML of
+// a copied table; this serves all of them.
+type x11SelectionOwner struct {
+	c         *x11SelectionConn
+	window    uint32
+	selection uint32
+	targets   uint32
+	incr      uint32
+	data      map[uint32][]byte // Target atom to content
+	offered   []uint32          // Target atoms in preference order, for TARGETS
+	transfers []*x11Transfer
+}
+
+// ownX11Selection connects to display and takes ownership of the named
+// selection (CLIPBOARD or PRIMARY), offering contents in the given order
+func ownX11Selection(display, selection string, contents []ClipboardContent) (*x11SelectionOwner, error) {
+	c, err := openX11SelectionConn(display)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := newX11SelectionOwner(c, selection, contents)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// newX11SelectionOwner creates the owner window over an established connection and claims the selection
+func newX11SelectionOwner(c *x11SelectionConn, selection string, contents []ClipboardContent) (*x11SelectionOwner, error) {
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("nothing to write")
+	}
+
+	o := &x11SelectionOwner{c: c, window: c.idBase, data: make(map[uint32][]byte)}
+
+	var err error
+	if o.selection, err = c.internAtom(selection); err != nil {
+		return nil, err
+	}
+	if o.targets, err = c.internAtom("TARGETS"); err != nil {
+		return nil, err
+	}
+	if o.incr, err = c.internAtom("INCR"); err != nil {
+		return nil, err
+	}
+
+	o.offered = append(o.offered, o.targets)
+	for _, content := range contents {
+		names := []string{content.MimeType}
+		if content.MimeType == mimeText {
+			names = textTargets
+		}
+		for _, name := range names {
+			atom, err := c.internAtom(name)
+			if err != nil {
+				return nil, err
+			}
+			if _, seen := o.data[atom]; !seen {
+				o.data[atom] = content.Data
+				o.offered = append(o.offered, atom)
+			}
+		}
+	}
+
+	// An unmapped 1x1 InputOnly window is enough to own a selection
+	req := make([]byte, 32)
+	req[0] = x11OpCreateWindow
+	binary.LittleEndian.PutUint16(req[2:], 8)
+	binary.LittleEndian.PutUint32(req[4:], o.window)
+	binary.LittleEndian.PutUint32(req[8:], c.root)
+	binary.LittleEndian.PutUint16(req[16:], 1)
+	binary.LittleEndian.PutUint16(req[18:], 1)
+	binary.LittleEndian.PutUint16(req[22:], x11WindowClassInput)
+	if err := c.send(req); err != nil {
+		return nil, err
+	}
+
+	req = make([]byte, 16)
+	req[0] = x11OpSetSelectionOwner
+	binary.LittleEndian.PutUint16(req[2:], 4)
+	binary.LittleEndian.PutUint32(req[4:], o.window)
+	binary.LittleEndian.PutUint32(req[8:], o.selection)
+	if err := c.send(req); err != nil {
+		return nil, err
+	}
+
+	// SetSelectionOwner has no reply; ask who owns the selection to confirm it took
+	req = make([]byte, 8)
+	req[0] = x11OpGetSelectionOwner
+	binary.LittleEndian.PutUint16(req[2:], 2)
+	binary.LittleEndian.PutUint32(req[4:], o.selection)
+	reply, err := c.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if owner := binary.LittleEndian.Uint32(reply[8:]); owner != o.window {
+		return nil, fmt.Errorf("failed to take ownership of %s", selection)
+	}
+
+	return o, nil
+}
+
+// send writes a request that has no reply
+func (c *x11SelectionConn) send(req []byte) error {
+	if _, err := c.conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send X11 request: %v", err)
+	}
+	return nil
+}
+
+// Serve answers selection requests until another client takes the selection
+// (returning nil) or the connection fails
+func (o *x11SelectionOwner) Serve() error {
+	for {
+		packet, err := o.c.readPacket()
+		if err != nil {
+			return err
+		}
+
+		switch packet[0] & 0x7f {
+		case 0:
+			// Usually BadWindow from a requestor that went away mid-transfer
+		case x11SelectionClearEvent:
+			if binary.LittleEndian.Uint32(packet[12:]) == o.selection {
+				return nil
+			}
+		case x11SelectionRequestEvent:
+			if err := o.handleRequest(packet); err != nil {
+				return err
+			}
+		case x11PropertyNotifyEvent:
+			if err := o.continueTransfer(packet); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Close releases the selection by closing the connection
+func (o *x11SelectionOwner) Close() error {
+	return o.c.Close()
+}
+
+// maxChunk is the most data a single ChangeProperty request can carry
+func (o *x11SelectionOwner) maxChunk() int {
+	limit := (o.c.maxRequest - 24) &^ 3
+	if limit <= 0 || limit > 256*1024 {
+		limit = 256 * 1024
+	}
+	return limit
+}
+
+// handleRequest converts the selection to the requested target by writing it
+// to a property on the requestor window, then notifies the requestor
+func (o *x11SelectionOwner) handleRequest(event []byte) error {
+	timestamp := binary.LittleEndian.Uint32(event[4:])
+	requestor := binary.LittleEndian.Uint32(event[12:])
+	selection := binary.LittleEndian.Uint32(event[16:])
+	target := binary.LittleEndian.Uint32(event[20:])
+	property := binary.LittleEndian.Uint32(event[24:])
+	if property == 0 {
+		// Obsolete clients pass None and expect the target to be used
+		property = target
+	}
+
+	var err error
+	switch data, ok := o.data[target]; {
+	case selection != o.selection:
+		property = 0
+	case target == o.targets:
+		atoms := make([]byte, 4*len(o.offered))
+		for i, atom := range o.offered {
+			binary.LittleEndian.PutUint32(atoms[4*i:], atom)
+		}
+		err = o.changeProperty(requestor, property, x11AtomAtom, 32, atoms)
+	case !ok:
+		property = 0 // Refuse targets we don't hold, including MULTIPLE
+	case len(data) > o.maxChunk():
+		err = o.startTransfer(requestor, property, target, data)
+	default:
+		err = o.changeProperty(requestor, property, target, 8, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	notify := make([]byte, 32)
+	notify[0] = x11SelectionNotifyEvent
+	binary.LittleEndian.PutUint32(notify[4:], timestamp)
+	binary.LittleEndian.PutUint32(notify[8:], requestor)
+	binary.LittleEndian.PutUint32(notify[12:], selection)
+	binary.LittleEndian.PutUint32(notify[16:], target)
+	binary.LittleEndian.PutUint32(notify[20:], property)
+
+	req := make([]byte, 12, 44)
+	req[0] = x11OpSendEvent
+	binary.LittleEndian.PutUint16(req[2:], 11)
+	binary.LittleEndian.PutUint32(req[4:], requestor)
+	return o.c.send(append(req, notify...))
+}
+
+// changeProperty replaces a property on window with data in the given format (8 or 32 bits)
+func (o *x11SelectionOwner) changeProperty(window, property, propType uint32, format int, data []byte) error {
+	req := make([]byte, 24+pad4(len(data)))
+	req[0] = x11OpChangeProperty
+	binary.LittleEndian.PutUint16(req[2:], uint16(len(req)/4))
+	binary.LittleEndian.PutUint32(req[4:], window)
+	binary.LittleEndian.PutUint32(req[8:], property)
+	binary.LittleEndian.PutUint32(req[12:], propType)
+	req[16] = byte(format)
+	binary.LittleEndian.PutUint32(req[20:], uint32(len(data)/(format/8)))
+	copy(req[24:], data)
+	return o.c.send(req)
+}
+
+// startTransfer begins an INCR transfer: it announces the size and waits for
+// the requestor to delete the property before sending each chunk
+func (o *x11SelectionOwner) startTransfer(requestor, property, target uint32, data []byte) error {
+	req := make([]byte, 16)
+	req[0] = x11OpChangeWindowAttributes
+	binary.LittleEndian.PutUint16(req[2:], 4)
+	binary.LittleEndian.PutUint32(req[4:], requestor)
+	binary.LittleEndian.PutUint32(req[8:], x11CWEventMask)
+	binary.LittleEndian.PutUint32(req[12:], x11PropertyChangeMask)
+	if err := o.c.send(req); err != nil {
+		return err
+	}
+
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(data)))
+	if err := o.changeProperty(requestor, property, o.incr, 32, size); err != nil {
+		return err
+	}
+
+	o.transfers = append(o.transfers, &x11Transfer{requestor: requestor, property: property, target: target, data: data})
+	return nil
+}
+
+// continueTransfer sends the next chunk of an INCR transfer once the requestor
+// has read the previous one. An empty chunk marks the end.
+func (o *x11SelectionOwner) continueTransfer(event []byte) error {
+	window := binary.LittleEndian.Uint32(event[4:])
+	property := binary.LittleEndian.Uint32(event[8:])
+	if event[16] != x11PropertyDeleted {
+		return nil
+	}
+
+	for i, t := range o.transfers {
+		if t.requestor != window || t.property != property {
+			continue
+		}
+
+		end := t.offset + o.maxChunk()
+		if end > len(t.data) {
+			end = len(t.data)
+		}
+		chunk := t.data[t.offset:end]
+		t.offset = end
+
+		if len(chunk) == 0 {
+			o.transfers = append(o.transfers[:i], o.transfers[i+1:]...)
+		}
+		return o.changeProperty(window, property, t.target, 8, chunk)
+	}
+	return nil
+}
+
+// serveX11Selection hands contents to a detached "serve-selection" helper,
+// which owns the selection until another client takes it. This is the same
+// model as xclip, so the selection outlives short-lived processes like the popup.
+func serveX11Selection(display, selection string, contents []ClipboardContent) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %v", err)
+	}
+
+	payload, err := json.Marshal(contents)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, "serve-selection", selection)
+	cmd.Env = append(os.Environ(), "DISPLAY="+display)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start selection helper: %v", err)
+	}
+
+	// The helper reports "ok" once it owns the selection, or the reason it couldn't
+	status, _ := bufio.NewReader(stdout).ReadString('\n')
+	status = strings.TrimSpace(status)
+	if status != "ok" {
+		cmd.Wait()
+		if status == "" {
+			status = "no response"
+		}
+		return fmt.Errorf("selection helper failed: %s", status)
+	}
+
+	// The helper keeps running on its own; reap it in the background
+	go cmd.Wait()
+	return nil
+}
+
+// runServeSelection is the "serve-selection <clipboard|primary>" helper. It
+// reads JSON-encoded contents from stdin and serves them until replaced.
+func runServeSelection(args []string) error {
+	selection := "CLIPBOARD"
+	if len(args) > 0 {
+		selection = strings.ToUpper(args[0])
+	}
+
+	var contents []ClipboardContent
+	data, err := io.ReadAll(os.Stdin)
+	if err == nil {
+		err = json.Unmarshal(data, &contents)
+	}
+	if err != nil {
+		fmt.Printf("invalid contents: %v\n", err)
+		return err
+	}
+
+	owner, err := ownX11Selection(os.Getenv("DISPLAY"), selection, contents)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return err
+	}
+	defer owner.Close()
+
+	fmt.Println("ok")
+	os.Stdout.Close()
+
+	return owner.Serve()
+}
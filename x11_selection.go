@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// X11 protocol constants used by the selection watcher
+const (
+	x11OpInternAtom     = 16
+	x11OpQueryExtension = 98
+
+	xfixesOpQueryVersion         = 0
+	xfixesOpSelectSelectionInput = 2
+	xfixesSelectionNotifyEvent   = 0 // Offset from the extension's first event code
+	xfixesSetSelectionOwnerMask  = 1 << 0
+	xfixesSelectionWindowDestroy = 1 << 1
+	xfixesSelectionClientClose   = 1 << 2
+
+	xauthFamilyLocal = 256
+	xauthFamilyWild  = 65535
+)
+
+// x11SelectionConn is a minimal X11 client that speaks just enough of the core
+// protocol and the XFIXES extension to be told when a selection changes owner.
+// It is pure Go so event-driven monitoring needs no extra C libraries.
+type x11SelectionConn struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	root        uint32
+	idBase      uint32 // First resource ID this client may allocate
+	maxRequest  int    // Largest request the server accepts, in bytes
+	xfixesOp    uint8
+	xfixesEvent uint8
+}
+
+// parseDisplay splits a DISPLAY value like ":0", ":1.0" or "host:0" into host and display number
+func parseDisplay(display string) (string, int, error) {
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return "", 0, fmt.Errorf("invalid DISPLAY %q", display)
+	}
+
+	host := display[:colon]
+	number := display[colon+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		number = number[:dot]
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid DISPLAY %q", display)
+	}
+
+	return host, n, nil
+}
+
+// dialX11 connects to the X server named by display
+func dialX11(display string) (net.Conn, int, error) {
+	host, number, err := parseDisplay(display)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if host == "" || host == "unix" {
+		socket := fmt.Sprintf("/tmp/.X11-unix/X%d", number)
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			// Some servers only listen on the abstract socket
+			conn, err = net.Dial("unix", "@"+socket)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to connect to X server: %v", err)
+		}
+		return conn, number, nil
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+number)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to connect to X server: %v", err)
+	}
+	return conn, number, nil
+}
+
+// xauthEntry is a single record from an Xauthority file
+type xauthEntry struct {
+	family uint16
+	addr   string
+	number string
+	name   string
+	data   []byte
+}
+
+// readXauthority parses the binary Xauthority format
+func readXauthority(r io.Reader) ([]xauthEntry, error) {
+	var entries []xauthEntry
+	br := bufio.NewReader(r)
+
+	readField := func() ([]byte, error) {
+		var n uint16
+		if err := binary.Read(br, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		field := make([]byte, n)
+		_, err := io.ReadFull(br, field)
+		return field, err
+	}
+
+	for {
+		var entry xauthEntry
+		if err := binary.Read(br, binary.BigEndian, &entry.family); err != nil {
+			if err == io.EOF {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("failed to read Xauthority: %v", err)
+		}
+
+		var fields [4][]byte
+		for i := range fields {
+			field, err := readField()
+			if err != nil {
+				return nil, fmt.Errorf("truncated Xauthority entry: %v", err)
+			}
+			fields[i] = field
+		}
+
+		entry.addr = string(fields[0])
+		entry.number = string(fields[1])
+		entry.name = string(fields[2])
+		entry.data = fields[3]
+		entries = append(entries, entry)
+	}
+}
+
+// findXauthCookie picks the MIT-MAGIC-COOKIE-1 entry for a local display
+func findXauthCookie(entries []xauthEntry, hostname string, number int) (string, []byte) {
+	for _, e := range entries {
+		if e.name != "MIT-MAGIC-COOKIE-1" {
+			continue
+		}
+		if e.number != "" && e.number != strconv.Itoa(number) {
+			continue
+		}
+		if e.family == xauthFamilyWild || (e.family == xauthFamilyLocal && e.addr == hostname) {
+			return e.name, e.data
+		}
+	}
+	return "", nil
+}
+
+// loadXauthCookie reads the cookie for a local display, if one exists
+func loadXauthCookie(number int) (string, []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer file.Close()
+
+	entries, err := readXauthority(file)
+	if err != nil {
+		return "", nil
+	}
+
+	hostname, _ := os.Hostname()
+	return findXauthCookie(entries, hostname, number)
+}
+
+// openX11SelectionConn connects to DISPLAY and enables XFIXES selection events
+func openX11SelectionConn(display string) (*x11SelectionConn, error) {
+	conn, number, err := dialX11(display)
+	if err != nil {
+		return nil, err
+	}
+
+	authName, authData := loadXauthCookie(number)
+	c, err := newX11SelectionConn(conn, authName, authData)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newX11SelectionConn performs the connection handshake and XFIXES setup over conn
+func newX11SelectionConn(conn net.Conn, authName string, authData []byte) (*x11SelectionConn, error) {
+	c := &x11SelectionConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	if err := c.handshake(authName, authData); err != nil {
+		return nil, err
+	}
+
+	present, opcode, firstEvent, err := c.queryExtension("XFIXES")
+	if err != nil {
+		return nil, err
+	}
+	if !present {
+		return nil, fmt.Errorf("X server does not support the XFIXES extension")
+	}
+	c.xfixesOp = opcode
+	c.xfixesEvent = firstEvent
+
+	// XFIXES requires the client to announce its version before any other request
+	if err := c.xfixesQueryVersion(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// pad4 returns n rounded up to a multiple of four
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// handshake sends the connection setup and reads the resource ID base, the
+// maximum request length and the root window of the first screen
+func (c *x11SelectionConn) handshake(authName string, authData []byte) error {
+	req := make([]byte, 12+pad4(len(authName))+pad4(len(authData)))
+	req[0] = 'l' // Little-endian
+	binary.LittleEndian.PutUint16(req[2:], 11)
+	binary.LittleEndian.PutUint16(req[6:], uint16(len(authName)))
+	binary.LittleEndian.PutUint16(req[8:], uint16(len(authData)))
+	copy(req[12:], authName)
+	copy(req[12+pad4(len(authName)):], authData)
+
+	if _, err := c.conn.Write(req); err != nil {
+		return fmt.Errorf("failed to send X11 setup: %v", err)
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return fmt.Errorf("failed to read X11 setup reply: %v", err)
+	}
+	body := make([]byte, int(binary.LittleEndian.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return fmt.Errorf("failed to read X11 setup reply: %v", err)
+	}
+
+	if header[0] != 1 {
+		reason := strings.TrimRight(string(body), "\x00")
+		if header[0] == 0 && int(header[1]) <= len(body) {
+			reason = string(body[:header[1]])
+		}
+		return fmt.Errorf("X server refused connection: %s", reason)
+	}
+
+	if len(body) < 32 {
+		return fmt.Errorf("X11 setup reply too short")
+	}
+	vendorLen := int(binary.LittleEndian.Uint16(body[16:]))
+	numScreens := int(body[20])
+	numFormats := int(body[21])
+	screenOffset := 32 + pad4(vendorLen) + numFormats*8
+	if numScreens == 0 || len(body) < screenOffset+4 {
+		return fmt.Errorf("X11 setup reply has no screens")
+	}
+
+	c.idBase = binary.LittleEndian.Uint32(body[4:])
+	c.maxRequest = int(binary.LittleEndian.Uint16(body[18:])) * 4
+	c.root = binary.LittleEndian.Uint32(body[screenOffset:])
+	return nil
+}
+
+// readPacket reads one reply, event or error. Replies include their extra data.
+func (c *x11SelectionConn) readPacket() ([]byte, error) {
+	packet := make([]byte, 32)
+	if _, err := io.ReadFull(c.reader, packet); err != nil {
+		return nil, err
+	}
+
+	if packet[0] == 1 {
+		extra := int(binary.LittleEndian.Uint32(packet[4:])) * 4
+		if extra > 0 {
+			more := make([]byte, extra)
+			if _, err := io.ReadFull(c.reader, more); err != nil {
+				return nil, err
+			}
+			packet = append(packet, more...)
+		}
+	}
+
+	return packet, nil
+}
+
+// roundTrip sends a request and waits for its reply, skipping any events
+func (c *x11SelectionConn) roundTrip(req []byte) ([]byte, error) {
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, fmt.Errorf("failed to send X11 request: %v", err)
+	}
+
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read X11 reply: %v", err)
+		}
+		switch packet[0] {
+		case 0:
+			return nil, fmt.Errorf("X11 request failed with error code %d", packet[1])
+		case 1:
+			return packet, nil
+		}
+	}
+}
+
+// namedRequest encodes the shared layout of InternAtom and QueryExtension
+func namedRequest(opcode, data byte, name string) []byte {
+	req := make([]byte, 8+pad4(len(name)))
+	req[0] = opcode
+	req[1] = data
+	binary.LittleEndian.PutUint16(req[2:], uint16(len(req)/4))
+	binary.LittleEndian.PutUint16(req[4:], uint16(len(name)))
+	copy(req[8:], name)
+	return req
+}
+
+// queryExtension asks whether an extension exists and returns its opcode and first event code
+func (c *x11SelectionConn) queryExtension(name string) (bool, uint8, uint8, error) {
+	reply, err := c.roundTrip(namedRequest(x11OpQueryExtension, 0, name))
+	if err != nil {
+		return false, 0, 0, err
+	}
+	return reply[8] != 0, reply[9], reply[10], nil
+}
+
+// internAtom returns the atom for name, creating it if needed
+func (c *x11SelectionConn) internAtom(name string) (uint32, error) {
+	reply, err := c.roundTrip(namedRequest(x11OpInternAtom, 0, name))
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(reply[8:]), nil
+}
+
+// xfixesQueryVersion negotiates XFIXES version 5
+func (c *x11SelectionConn) xfixesQueryVersion() error {
+	req := make([]byte, 12)
+	req[0] = c.xfixesOp
+	req[1] = xfixesOpQueryVersion
+	binary.LittleEndian.PutUint16(req[2:], 3)
+	binary.LittleEndian.PutUint32(req[4:], 5)
+	binary.LittleEndian.PutUint32(req[8:], 0)
+
+	_, err := c.roundTrip(req)
+	return err
+}
+
+// watchSelection asks for an event whenever the named selection changes owner
+func (c *x11SelectionConn) watchSelection(name string) (uint32, error) {
+	atom, err := c.internAtom(name)
+	if err != nil {
+		return 0, err
+	}
+
+	req := make([]byte, 16)
+	req[0] = c.xfixesOp
+	req[1] = xfixesOpSelectSelectionInput
+	binary.LittleEndian.PutUint16(req[2:], 4)
+	binary.LittleEndian.PutUint32(req[4:], c.root)
+	binary.LittleEndian.PutUint32(req[8:], atom)
+	binary.LittleEndian.PutUint32(req[12:], xfixesSetSelectionOwnerMask|xfixesSelectionWindowDestroy|xfixesSelectionClientClose)
+
+	if _, err := c.conn.Write(req); err != nil {
+		return 0, fmt.Errorf("failed to select selection input: %v", err)
+	}
+
+	return atom, nil
+}
+
+// nextSelectionChange blocks until a selection changes owner and returns its atom
+func (c *x11SelectionConn) nextSelectionChange() (uint32, error) {
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return 0, err
+		}
+		if packet[0] == 0 {
+			return 0, fmt.Errorf("X11 error code %d", packet[1])
+		}
+		if packet[0]&0x7f == c.xfixesEvent+xfixesSelectionNotifyEvent {
+			return binary.LittleEndian.Uint32(packet[12:]), nil
+		}
+	}
+}
+
+// Close closes the connection to the X server
+func (c *x11SelectionConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestParseDisplay(t *testing.T) {
+	tests := []struct {
+		display string
+		host    string
+		number  int
+		wantErr bool
+	}{
+		{display: ":0", number: 0},
+		{display: ":1.0", number: 1},
+		{display: "unix:2", host: "unix", number: 2},
+		{display: "remote:10.0", host: "remote", number: 10},
+		{display: "nodisplay", wantErr: true},
+		{display: ":x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		host, number, err := parseDisplay(tt.display)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDisplay(%q) error = %v, wantErr %v", tt.display, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (host != tt.host || number != tt.number) {
+			t.Errorf("parseDisplay(%q) = %q, %d; want %q, %d", tt.display, host, number, tt.host, tt.number)
+		}
+	}
+}
+
+// xauthRecord encodes one Xauthority entry
+func xauthRecord(family uint16, addr, number, name string, data []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, family)
+	for _, field := range [][]byte{[]byte(addr), []byte(number), []byte(name), data} {
+		binary.Write(&buf, binary.BigEndian, uint16(len(field)))
+		buf.Write(field)
+	}
+	return buf.Bytes()
+}
+
+func TestFindXauthCookie(t *testing.T) {
+	var file bytes.Buffer
+	file.Write(xauthRecord(xauthFamilyLocal, "otherhost", "0", "MIT-MAGIC-COOKIE-1", []byte("wrong-host")))
+	file.Write(xauthRecord(xauthFamilyLocal, "myhost", "1", "MIT-MAGIC-COOKIE-1", []byte("wrong-display")))
+	file.Write(xauthRecord(xauthFamilyLocal, "myhost", "0", "MIT-MAGIC-COOKIE-1", []byte("cookie")))
+
+	entries, err := readXauthority(&file)
+	if err != nil {
+		t.Fatalf("readXauthority() failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	name, data := findXauthCookie(entries, "myhost", 0)
+	if name != "MIT-MAGIC-COOKIE-1" || string(data) != "cookie" {
+		t.Errorf("Expected cookie for myhost:0, got %q %q", name, data)
+	}
+
+	if name, _ := findXauthCookie(entries, "myhost", 5); name != "" {
+		t.Errorf("Expected no cookie for display 5, got %q", name)
+	}
+
+	if _, err := readXauthority(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x05, 'a'})); err == nil {
+		t.Error("Expected error for a truncated entry")
+	}
+}
+
+// fakeXServer plays the server side of the protocol over a pipe
+type fakeXServer struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func (s *fakeXServer) read(n int) []byte {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(s.conn, buf); err != nil {
+		s.t.Errorf("fake X server read failed: %v", err)
+	}
+	return buf
+}
+
+// readRequest reads a whole request using its length field
+func (s *fakeXServer) readRequest() []byte {
+	header := s.read(4)
+	length := int(binary.LittleEndian.Uint16(header[2:])) * 4
+	return append(header, s.read(length-4)...)
+}
+
+// reply sends a 32-byte reply whose bytes 8 onwards are body
+func (s *fakeXServer) reply(body ...byte) {
+	packet := make([]byte, 32)
+	packet[0] = 1
+	copy(packet[8:], body)
+	s.conn.Write(packet)
+}
+
+func (s *fakeXServer) event(code byte, selection uint32) {
+	packet := make([]byte, 32)
+	packet[0] = code
+	binary.LittleEndian.PutUint32(packet[12:], selection)
+	s.conn.Write(packet)
+}
+
+func TestX11SelectionConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	const (
+		rootWindow    = 0x1a2
+		xfixesOpcode  = 138
+		xfixesEvent   = 87
+		clipboardAtom = 301
+	)
+
+	requests := make(chan []byte, 8)
+	go func() {
+		s := &fakeXServer{t: t, conn: server}
+
+		// Connection setup: 12 bytes plus padded auth name and data
+		setup := s.read(12)
+		nameLen := pad4(int(binary.LittleEndian.Uint16(setup[6:])))
+		dataLen := pad4(int(binary.LittleEndian.Uint16(setup[8:])))
+		requests <- append(setup, s.read(nameLen+dataLen)...)
+
+		vendor := "fake"
+		body := make([]byte, 32+pad4(len(vendor))+8+40)
+		binary.LittleEndian.PutUint16(body[16:], uint16(len(vendor)))
+		body[20] = 1 // screens
+		body[21] = 1 // formats
+		copy(body[32:], vendor)
+		binary.LittleEndian.PutUint32(body[32+pad4(len(vendor))+8:], rootWindow)
+		header := make([]byte, 8)
+		header[0] = 1
+		binary.LittleEndian.PutUint16(header[6:], uint16(len(body)/4))
+		s.conn.Write(append(header, body...))
+
+		requests <- s.readRequest() // QueryExtension
+		s.reply(1, xfixesOpcode, xfixesEvent)
+		requests <- s.readRequest() // XFixesQueryVersion
+		s.reply(5)
+		requests <- s.readRequest() // InternAtom
+		s.reply(byte(clipboardAtom&0xff), byte(clipboardAtom>>8))
+		requests <- s.readRequest() // SelectSelectionInput
+
+		// An unrelated core event must be skipped
+		s.event(28, 0)
+		s.event(xfixesEvent, clipboardAtom)
+	}()
+
+	conn, err := newX11SelectionConn(client, "MIT-MAGIC-COOKIE-1", []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("newX11SelectionConn() failed: %v", err)
+	}
+	if conn.root != rootWindow {
+		t.Errorf("Expected root window %#x, got %#x", rootWindow, conn.root)
+	}
+
+	atom, err := conn.watchSelection("CLIPBOARD")
+	if err != nil {
+		t.Fatalf("watchSelection() failed: %v", err)
+	}
+	if atom != clipboardAtom {
+		t.Errorf("Expected CLIPBOARD atom %d, got %d", clipboardAtom, atom)
+	}
+
+	changed, err := conn.nextSelectionChange()
+	if err != nil {
+		t.Fatalf("nextSelectionChange() failed: %v", err)
+	}
+	if changed != clipboardAtom {
+		t.Errorf("Expected change on atom %d, got %d", clipboardAtom, changed)
+	}
+
+	setup := <-requests
+	if setup[0] != 'l' || !bytes.Contains(setup, []byte("MIT-MAGIC-COOKIE-1")) {
+		t.Errorf("Unexpected setup request %v", setup)
+	}
+	if query := <-requests; query[0] != x11OpQueryExtension || !bytes.Contains(query, []byte("XFIXES")) {
+		t.Errorf("Expected QueryExtension(XFIXES), got %v", query)
+	}
+	if version := <-requests; version[0] != xfixesOpcode || version[1] != xfixesOpQueryVersion {
+		t.Errorf("Expected XFixesQueryVersion, got %v", version)
+	}
+	if intern := <-requests; intern[0] != x11OpInternAtom || !bytes.Contains(intern, []byte("CLIPBOARD")) {
+		t.Errorf("Expected InternAtom(CLIPBOARD), got %v", intern)
+	}
+	selectInput := <-requests
+	if selectInput[0] != xfixesOpcode || selectInput[1] != xfixesOpSelectSelectionInput {
+		t.Errorf("Expected XFixesSelectSelectionInput, got %v", selectInput)
+	}
+	if window := binary.LittleEndian.Uint32(selectInput[4:]); window != rootWindow {
+		t.Errorf("Expected selection input on root window, got %#x", window)
+	}
+}
+
+func TestX11SelectionConnRefused(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		s := &fakeXServer{t: t, conn: server}
+		s.read(12)
+
+		reason := "No protocol specified"
+		body := make([]byte, pad4(len(reason)))
+		copy(body, reason)
+		header := make([]byte, 8)
+		header[1] = byte(len(reason))
+		binary.LittleEndian.PutUint16(header[6:], uint16(len(body)/4))
+		s.conn.Write(append(header, body...))
+	}()
+
+	_, err := newX11SelectionConn(client, "", nil)
+	if err == nil || !bytes.Contains([]byte(err.Error()), []byte("No protocol specified")) {
+		t.Errorf("Expected refusal reason in error, got %v", err)
+	}
+}
+
+// fakeSelectionServer answers InternAtom and GetSelectionOwner for a selection
+// owner and forwards every other request to requests
+func fakeSelectionServer(t *testing.T, conn net.Conn, owner uint32) (map[string]uint32, chan []byte) {
+	atoms := map[string]uint32{}
+	requests := make(chan []byte, 64)
+	go func() {
+		s := &fakeXServer{t: t, conn: conn}
+		for {
+			header := make([]byte, 4)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				close(requests)
+				return
+			}
+			length := int(binary.LittleEndian.Uint16(header[2:])) * 4
+			req := append(header, s.read(length-4)...)
+
+			switch req[0] {
+			case x11OpInternAtom:
+				name := string(req[8 : 8+binary.LittleEndian.Uint16(req[4:])])
+				if _, ok := atoms[name]; !ok {
+					atoms[name] = uint32(100 + len(atoms))
+				}
+				reply := make([]byte, 4)
+				binary.LittleEndian.PutUint32(reply, atoms[name])
+				s.reply(reply...)
+			case x11OpGetSelectionOwner:
+				reply := make([]byte, 4)
+				binary.LittleEndian.PutUint32(reply, owner)
+				s.reply(reply...)
+			default:
+				requests <- req
+			}
+		}
+	}()
+	return atoms, requests
+}
+
+// selectionRequest encodes a SelectionRequest event
+func selectionRequest(requestor, selection, target, property uint32) []byte {
+	event := make([]byte, 32)
+	event[0] = x11SelectionRequestEvent
+	binary.LittleEndian.PutUint32(event[12:], requestor)
+	binary.LittleEndian.PutUint32(event[16:], selection)
+	binary.LittleEndian.PutUint32(event[20:], target)
+	binary.LittleEndian.PutUint32(event[24:], property)
+	return event
+}
+
+func TestX11SelectionOwner(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	const (
+		window    = 0x400000
+		requestor = 0x600001
+		property  = 0x333
+	)
+
+	atoms, requests := fakeSelectionServer(t, server, window)
+	c := &x11SelectionConn{conn: client, reader: bufio.NewReader(client), root: 0x1a2, idBase: window, maxRequest: 24 + 16}
+
+	html := []byte("<b>bold</b>")
+	big := []byte("0123456789abcdefXYZ")
+	owner, err := newX11SelectionOwner(c, "CLIPBOARD", []ClipboardContent{
+		{MimeType: mimeText, Data: []byte("bold")},
+		{MimeType: "text/html", Data: html},
+		{MimeType: "image/png", Data: big},
+	})
+	if err != nil {
+		t.Fatalf("newX11SelectionOwner() failed: %v", err)
+	}
+
+	if req := <-requests; req[0] != x11OpCreateWindow || binary.LittleEndian.Uint32(req[4:]) != window {
+		t.Fatalf("Expected CreateWindow for %#x, got %v", window, req)
+	}
+	if req := <-requests; req[0] != x11OpSetSelectionOwner || binary.LittleEndian.Uint32(req[8:]) != atoms["CLIPBOARD"] {
+		t.Fatalf("Expected SetSelectionOwner(CLIPBOARD), got %v", req)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- owner.Serve()
+	}()
+
+	// expectReply reads the ChangeProperty and SendEvent answering a request
+	expectReply := func(target uint32) []byte {
+		t.Helper()
+		change := <-requests
+		if change[0] != x11OpChangeProperty || binary.LittleEndian.Uint32(change[4:]) != requestor {
+			t.Fatalf("Expected ChangeProperty on the requestor, got %v", change)
+		}
+		data := change[24 : 24+int(binary.LittleEndian.Uint32(change[20:]))*int(change[16]/8)]
+		notify := <-requests
+		if notify[0] != x11OpSendEvent || notify[12] != x11SelectionNotifyEvent || binary.LittleEndian.Uint32(notify[12+20:]) != property {
+			t.Fatalf("Expected SelectionNotify for property %#x, got %v", property, notify)
+		}
+		if got := binary.LittleEndian.Uint32(notify[12+16:]); got != target {
+			t.Errorf("Expected notify for target %d, got %d", target, got)
+		}
+		return data
+	}
+
+	// TARGETS lists every representation, text under all of its usual names
+	server.Write(selectionRequest(requestor, atoms["CLIPBOARD"], atoms["TARGETS"], property))
+	offered := map[uint32]bool{}
+	data := expectReply(atoms["TARGETS"])
+	for i := 0; i+4 <= len(data); i += 4 {
+		offered[binary.LittleEndian.Uint32(data[i:])] = true
+	}
+	for _, name := range []string{"TARGETS", "UTF8_STRING", "STRING", "text/plain", "text/html", "image/png"} {
+		if !offered[atoms[name]] {
+			t.Errorf("Expected %s in TARGETS", name)
+		}
+	}
+
+	server.Write(selectionRequest(requestor, atoms["CLIPBOARD"], atoms["text/html"], property))
+	if data := expectReply(atoms["text/html"]); !bytes.Equal(data, html) {
+		t.Errorf("Expected HTML %q, got %q", html, data)
+	}
+
+	// Unknown targets are refused with property None
+	server.Write(selectionRequest(requestor, atoms["CLIPBOARD"], 999, property))
+	if refused := <-requests; refused[0] != x11OpSendEvent || binary.LittleEndian.Uint32(refused[12+20:]) != 0 {
+		t.Errorf("Expected a refusal, got %v", refused)
+	}
+
+	// Data larger than a request is sent with INCR in chunks
+	server.Write(selectionRequest(requestor, atoms["CLIPBOARD"], atoms["image/png"], property))
+	if req := <-requests; req[0] != x11OpChangeWindowAttributes {
+		t.Fatalf("Expected PropertyChangeMask on the requestor, got %v", req)
+	}
+	if incr := <-requests; binary.LittleEndian.Uint32(incr[12:]) != atoms["INCR"] {
+		t.Fatalf("Expected an INCR property, got %v", incr)
+	}
+	<-requests // SelectionNotify
+
+	var received []byte
+	for {
+		deleted := make([]byte, 32)
+		deleted[0] = x11PropertyNotifyEvent
+		binary.LittleEndian.PutUint32(deleted[4:], requestor)
+		binary.LittleEndian.PutUint32(deleted[8:], property)
+		deleted[16] = x11PropertyDeleted
+		server.Write(deleted)
+
+		chunk := <-requests
+		n := int(binary.LittleEndian.Uint32(chunk[20:]))
+		if n == 0 {
+			break
+		}
+		received = append(received, chunk[24:24+n]...)
+	}
+	if !bytes.Equal(received, big) {
+		t.Errorf("Expected INCR data %q, got %q", big, received)
+	}
+
+	// Losing the selection ends Serve
+	clear := make([]byte, 32)
+	clear[0] = x11SelectionClearEvent
+	binary.LittleEndian.PutUint32(clear[12:], atoms["CLIPBOARD"])
+	server.Write(clear)
+	if err := <-done; err != nil {
+		t.Errorf("Expected Serve to return nil after SelectionClear, got %v", err)
+	}
+}